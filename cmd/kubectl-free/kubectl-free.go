@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/thirdeyenick/kubectl-free/pkg/cmd"
@@ -36,6 +37,11 @@ func main() {
 		date,
 	)
 	if err := root.Execute(); err != nil {
+		// exit status of commands like check
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,470 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	// checkLong defines long description
+	checkLong = templates.LongDesc(`
+		Check rules of resource usage in percent and exit non-zero if a rule is violated.
+
+		A rule is <scope>[/<name>]:<column><operator><percent>, e.g. "node:mem.req>90".
+		Scopes are node, namespace and cluster. Columns are cpu.use, cpu.req, cpu.lim,
+		mem.use, mem.req and mem.lim. Percentages of namespaces are of the allocatable
+		resources of the cluster. Operators are >, >=, < and <=.
+
+		The exit status is 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN) like monitoring plugins.
+	`)
+
+	// checkExample defines command examples
+	checkExample = templates.Examples(`
+		# Fail if the memory requests of any node are above 90%.
+		kubectl free check --rule "node:mem.req>90"
+
+		# Warn if the CPU requests of the cluster are above 80%, fail if a namespace requests more than 30% of the memory.
+		kubectl free check --warn-rule "cluster:cpu.req>80" --rule "namespace/team-a:mem.req>30"
+
+		# Read rules from a file and print the result as a Nagios/monitoring plugin.
+		kubectl free check --rules-file rules.yaml --nagios
+	`)
+)
+
+// checkStatus is the exit status of check (monitoring plugin compatible)
+type checkStatus int
+
+const (
+	checkOK       checkStatus = 0
+	checkWarning  checkStatus = 1
+	checkCritical checkStatus = 2
+	checkUnknown  checkStatus = 3
+)
+
+// String returns the name of the status
+func (s checkStatus) String() string {
+	switch s {
+	case checkOK:
+		return "OK"
+	case checkWarning:
+		return "WARNING"
+	case checkCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+const (
+	// scopes of rules
+	nodeCheckScope      = "node"
+	namespaceCheckScope = "namespace"
+	clusterCheckScope   = "cluster"
+)
+
+// ruleRegexp matches <scope>[/<name>]:<column><operator><percent>
+var ruleRegexp = regexp.MustCompile(`^(node|namespace|cluster)(?:/([^:]+))?:([a-z]+\.[a-z]+)\s*(>=|<=|>|<)\s*(\d+)%?$`)
+
+// ExitError is an error carrying the exit status of a command, e.g. the status of check
+// Err is nil if the command succeeded with a non-zero status.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error returns the message of the error
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// checkRule is a rule of check
+type checkRule struct {
+	text     string
+	severity checkStatus
+	scope    string
+	name     string
+	column   string
+	operator string
+	value    int64
+}
+
+// checkRulesFile is the file of rules (--rules-file)
+type checkRulesFile struct {
+	Critical []string `json:"critical"`
+	Warning  []string `json:"warning"`
+}
+
+// checkViolation is a violated rule of a node, namespace or the cluster
+type checkViolation struct {
+	rule  checkRule
+	name  string
+	value int64
+}
+
+// NewCmdCheck is a cobra command wrapping check
+func NewCmdCheck(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "check [NODE...]",
+		Short:   "Check rules of resource usage and exit non-zero if a rule is violated.",
+		Long:    checkLong,
+		Example: checkExample,
+		// errors are printed by RunE, the exit status is returned as ExitError
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(c *cobra.Command, args []string) error {
+			return o.runCheckCommand(f, c, args)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&o.criticalRules, "rule", "", o.criticalRules, `Critical rule, e.g. "node:mem.req>90" (can be repeated).`)
+	cmd.Flags().StringArrayVarP(&o.warningRules, "warn-rule", "", o.warningRules, `Warning rule, e.g. "cluster:cpu.req>80" (can be repeated).`)
+	cmd.Flags().StringVarP(&o.rulesFile, "rules-file", "", o.rulesFile, `Yaml file with lists of "critical" and "warning" rules.`)
+	cmd.Flags().BoolVarP(&o.nagios, "nagios", "", o.nagios, `Print one status line with performance data like a Nagios/monitoring plugin.`)
+
+	return cmd
+}
+
+// runCheckCommand runs check and returns its status as ExitError
+// Every error (options, rules, api server) is UNKNOWN.
+func (o *FreeOptions) runCheckCommand(f cmdutil.Factory, c *cobra.Command, args []string) error {
+
+	err := o.Complete(f, c, args)
+	if err == nil {
+		err = o.Validate()
+	}
+
+	status := checkUnknown
+	if err == nil {
+		status, err = o.RunCheck(args)
+	}
+
+	if err != nil {
+		if o.nagios {
+			fmt.Fprintf(o.table.Output, "FREE %s - %v\n", checkUnknown, err)
+		} else {
+			fmt.Fprintf(o.ErrOut, "error: %v\n", err)
+		}
+		return &ExitError{Code: int(checkUnknown), Err: err}
+	}
+
+	if status != checkOK {
+		return &ExitError{Code: int(status)}
+	}
+
+	return nil
+}
+
+// RunCheck evaluates the rules and prints violations
+func (o *FreeOptions) RunCheck(args []string) (checkStatus, error) {
+
+	if o.multiCluster() {
+		return checkUnknown, fmt.Errorf("--contexts and --all-contexts can't be used with check")
+	}
+
+	rules, err := o.getCheckRules()
+	if err != nil {
+		return checkUnknown, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	// rules of usage can't be evaluated against missing usage
+	if err := o.checkUsage(ctx, rules, args); err != nil {
+		return checkUnknown, err
+	}
+
+	s, err := o.takeSnapshot(ctx, args)
+	if err != nil {
+		return checkUnknown, err
+	}
+
+	violations := evaluateCheckRules(rules, s)
+	status := getCheckStatus(violations)
+
+	if o.nagios {
+		o.printNagios(status, rules, violations, s)
+		return status, nil
+	}
+
+	o.showViolations(rules, violations)
+
+	return status, nil
+}
+
+// checkUsage returns an error if a rule refers to usage and usage of nodes or pods is not available
+// The snapshot counts missing usage as 0, which would never violate rules like "node:mem.use>90".
+func (o *FreeOptions) checkUsage(ctx context.Context, rules []checkRule, args []string) error {
+
+	i := slices.IndexFunc(rules, func(r checkRule) bool {
+		return r.column == cpuUseThreshold || r.column == memUseThreshold
+	})
+	if i < 0 {
+		return nil
+	}
+
+	if o.noMetrics {
+		return fmt.Errorf("usage of rule %q is not available with --no-metrics", rules[i].text)
+	}
+
+	nodes, err := o.source.GetNodes(ctx, args, o.labelSelector)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if _, err := o.source.GetNodeMetrics(ctx, node.ObjectMeta.Name); err != nil {
+			return fmt.Errorf("failed to get usage of rule %q: %v", rules[i].text, err)
+		}
+	}
+
+	if _, err := o.source.GetPodMetrics(ctx); err != nil {
+		return fmt.Errorf("failed to get usage of rule %q: %v", rules[i].text, err)
+	}
+
+	return nil
+}
+
+// getCheckRules returns critical and warning rules of flags and the rules file
+func (o *FreeOptions) getCheckRules() ([]checkRule, error) {
+
+	critical := slices.Clone(o.criticalRules)
+	warning := slices.Clone(o.warningRules)
+
+	if o.rulesFile != "" {
+		f, err := os.Open(o.rulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open rules file: %v", err)
+		}
+		defer f.Close()
+
+		rf := checkRulesFile{}
+		if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&rf); err != nil {
+			return nil, fmt.Errorf("failed to read rules file %s: %v", o.rulesFile, err)
+		}
+		critical = append(critical, rf.Critical...)
+		warning = append(warning, rf.Warning...)
+	}
+
+	rules := []checkRule{}
+	for _, text := range critical {
+		r, err := parseCheckRule(text, checkCritical)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	for _, text := range warning {
+		r, err := parseCheckRule(text, checkWarning)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules given, use --rule, --warn-rule or --rules-file")
+	}
+
+	return rules, nil
+}
+
+// parseCheckRule parses a rule like "node:mem.req>90"
+func parseCheckRule(text string, severity checkStatus) (checkRule, error) {
+
+	m := ruleRegexp.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return checkRule{}, fmt.Errorf("rule must be <scope>[/<name>]:<column><operator><percent>, not given %q", text)
+	}
+	if !slices.Contains(thresholdColumns, m[3]) {
+		return checkRule{}, fmt.Errorf("unknown column %q of rule %q, columns are %s", m[3], text, strings.Join(thresholdColumns, ", "))
+	}
+	if m[1] == clusterCheckScope && m[2] != "" {
+		return checkRule{}, fmt.Errorf("rule %q of the cluster can't have a name", text)
+	}
+
+	value, err := strconv.ParseInt(m[5], 10, 64)
+	if err != nil {
+		return checkRule{}, fmt.Errorf("invalid percent of rule %q: %v", text, err)
+	}
+
+	return checkRule{
+		text:     strings.TrimSpace(text),
+		severity: severity,
+		scope:    m[1],
+		name:     m[2],
+		column:   m[3],
+		operator: m[4],
+		value:    value,
+	}, nil
+}
+
+// violated reports whether a percentage violates the rule
+func (r checkRule) violated(percent int64) bool {
+	switch r.operator {
+	case ">":
+		return percent > r.value
+	case ">=":
+		return percent >= r.value
+	case "<":
+		return percent < r.value
+	default:
+		return percent <= r.value
+	}
+}
+
+// checkPercentages returns the percentages of the columns of rules
+func checkPercentages(cpuUsed, cpuRequested, cpuLimited, cpuAllocatable, memUsed, memRequested, memLimited, memAllocatable int64) map[string]int64 {
+	return map[string]int64{
+		cpuUseThreshold: util.GetPercentage(cpuUsed, cpuAllocatable),
+		cpuReqThreshold: util.GetPercentage(cpuRequested, cpuAllocatable),
+		cpuLimThreshold: util.GetPercentage(cpuLimited, cpuAllocatable),
+		memUseThreshold: util.GetPercentage(memUsed, memAllocatable),
+		memReqThreshold: util.GetPercentage(memRequested, memAllocatable),
+		memLimThreshold: util.GetPercentage(memLimited, memAllocatable),
+	}
+}
+
+// checkTargets returns the percentages of nodes, namespaces and the cluster by scope and name
+// Percentages of namespaces are of the allocatable resources of the cluster.
+func checkTargets(s *snapshot) map[string]map[string]map[string]int64 {
+
+	var cluster snapshotNode
+	nodes := map[string]map[string]int64{}
+	for _, n := range s.Nodes {
+		nodes[n.Name] = checkPercentages(n.CPUUsed, n.CPURequested, n.CPULimited, n.CPUAllocatable, n.MemUsed, n.MemRequested, n.MemLimited, n.MemAllocatable)
+
+		cluster.CPUUsed += n.CPUUsed
+		cluster.CPURequested += n.CPURequested
+		cluster.CPULimited += n.CPULimited
+		cluster.CPUAllocatable += n.CPUAllocatable
+		cluster.MemUsed += n.MemUsed
+		cluster.MemRequested += n.MemRequested
+		cluster.MemLimited += n.MemLimited
+		cluster.MemAllocatable += n.MemAllocatable
+	}
+
+	namespaces := map[string]map[string]int64{}
+	for name, nf := range aggregateNamespaces(s.Containers) {
+		namespaces[name] = checkPercentages(nf.cpuUsed, nf.cpuRequested, nf.cpuLimited, cluster.CPUAllocatable, nf.memUsed, nf.memRequested, nf.memLimited, cluster.MemAllocatable)
+	}
+
+	return map[string]map[string]map[string]int64{
+		nodeCheckScope:      nodes,
+		namespaceCheckScope: namespaces,
+		clusterCheckScope: {
+			clusterCheckScope: checkPercentages(cluster.CPUUsed, cluster.CPURequested, cluster.CPULimited, cluster.CPUAllocatable, cluster.MemUsed, cluster.MemRequested, cluster.MemLimited, cluster.MemAllocatable),
+		},
+	}
+}
+
+// evaluateCheckRules returns the violations of rules in the order of the rules and names
+// Rules with a name only apply to that node or namespace.
+func evaluateCheckRules(rules []checkRule, s *snapshot) []checkViolation {
+
+	targets := checkTargets(s)
+
+	violations := []checkViolation{}
+	for _, r := range rules {
+		scope := targets[r.scope]
+		names := maps.Keys(scope)
+		slices.Sort(names)
+
+		for _, name := range names {
+			if r.name != "" && r.name != name {
+				continue
+			}
+			if p := scope[name][r.column]; r.violated(p) {
+				violations = append(violations, checkViolation{rule: r, name: name, value: p})
+			}
+		}
+	}
+
+	return violations
+}
+
+// getCheckStatus returns the most severe status of violations
+func getCheckStatus(violations []checkViolation) checkStatus {
+	status := checkOK
+	for _, v := range violations {
+		if v.rule.severity > status {
+			status = v.rule.severity
+		}
+	}
+	return status
+}
+
+// showViolations prints violated rules as a table
+func (o *FreeOptions) showViolations(rules []checkRule, violations []checkViolation) {
+
+	if len(violations) == 0 {
+		fmt.Fprintf(o.table.Output, "No rules violated (%d rules).\n", len(rules))
+		return
+	}
+
+	if !o.noHeaders {
+		o.table.Header = []string{"SEVERITY", "RULE", "NAME", "VALUE"}
+	}
+
+	for _, v := range violations {
+		severity := v.rule.severity.String()
+		if !o.nocolor {
+			if v.rule.severity == checkCritical {
+				util.Red(&severity)
+			} else {
+				util.Yellow(&severity)
+			}
+		}
+		o.table.AddRow([]string{
+			severity,                             // severity of the rule
+			v.rule.text,                          // rule
+			v.name,                               // node, namespace or cluster
+			strconv.FormatInt(v.value, 10) + "%", // percent
+		})
+	}
+
+	o.table.Print()
+}
+
+// printNagios prints one status line with the violations and the percentages of the cluster as performance data
+//
+//	FREE CRITICAL - node node1 mem.req 95% (node:mem.req>90) | cpu.use=10%;; ...
+func (o *FreeOptions) printNagios(status checkStatus, rules []checkRule, violations []checkViolation, s *snapshot) {
+
+	summary := fmt.Sprintf("%d rules", len(rules))
+	if len(violations) > 0 {
+		messages := []string{}
+		for _, v := range violations {
+			messages = append(messages, fmt.Sprintf("%s %s %s %d%% (%s)", v.rule.scope, v.name, v.rule.column, v.value, v.rule.text))
+		}
+		summary = strings.Join(messages, ", ")
+	}
+
+	cluster := checkTargets(s)[clusterCheckScope][clusterCheckScope]
+	perfData := []string{}
+	for _, column := range thresholdColumns {
+		perfData = append(perfData, fmt.Sprintf("%s=%d%%", column, cluster[column]))
+	}
+
+	fmt.Fprintf(o.table.Output, "FREE %s - %s | %s\n", status, summary, strings.Join(perfData, " "))
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestParseCheckRule(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		text        string
+		expected    checkRule
+		expectedErr string
+	}{
+		{
+			"node rule",
+			"node:mem.req>90",
+			checkRule{text: "node:mem.req>90", severity: checkCritical, scope: "node", column: "mem.req", operator: ">", value: 90},
+			"",
+		},
+		{
+			"namespace rule with name",
+			" namespace/team-a:cpu.lim >= 30% ",
+			checkRule{text: "namespace/team-a:cpu.lim >= 30%", severity: checkCritical, scope: "namespace", name: "team-a", column: "cpu.lim", operator: ">=", value: 30},
+			"",
+		},
+		{
+			"invalid scope",
+			"pod:mem.req>90",
+			checkRule{},
+			`rule must be <scope>[/<name>]:<column><operator><percent>, not given "pod:mem.req>90"`,
+		},
+		{
+			"invalid column",
+			"node:disk.use>90",
+			checkRule{},
+			`unknown column "disk.use" of rule "node:disk.use>90", columns are cpu.use, cpu.req, cpu.lim, mem.use, mem.req, mem.lim`,
+		},
+		{
+			"cluster with name",
+			"cluster/prod:cpu.req>80",
+			checkRule{},
+			`rule "cluster/prod:cpu.req>80" of the cluster can't have a name`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			actual, err := parseCheckRule(test.text, checkCritical)
+			if test.expectedErr != "" {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%+v) differ (got: %+v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+
+	rulesFile := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(rulesFile, []byte("warning:\n- cluster:cpu.req>10\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var tests = []struct {
+		description    string
+		criticalRules  []string
+		warningRules   []string
+		rulesFile      string
+		nagios         bool
+		expectedStatus checkStatus
+		expected       []string
+	}{
+		{
+			"ok",
+			[]string{"node:mem.req>90"},
+			nil,
+			"",
+			false,
+			checkOK,
+			[]string{
+				"No rules violated (1 rules).",
+				"",
+			},
+		},
+		{
+			"critical and warning",
+			[]string{"node:mem.req>50", "namespace/default:cpu.req>=10"},
+			nil,
+			rulesFile,
+			false,
+			checkCritical,
+			[]string{
+				"CRITICAL node:mem.req>50               node1   57%",
+				"CRITICAL namespace/default:cpu.req>=10 default 12%",
+				"WARNING  cluster:cpu.req>10            cluster 14%",
+				"",
+			},
+		},
+		{
+			"warning nagios",
+			nil,
+			nil,
+			rulesFile,
+			true,
+			checkWarning,
+			[]string{
+				"FREE WARNING - cluster cluster cpu.req 14% (cluster:cpu.req>10) | cpu.use=0% cpu.req=14% cpu.lim=22% mem.use=0% mem.req=19% mem.lim=27%",
+				"",
+			},
+		},
+		{
+			"ok nagios",
+			[]string{"namespace:mem.lim>50"},
+			nil,
+			"",
+			true,
+			checkOK,
+			[]string{
+				"FREE OK - 1 rules | cpu.use=0% cpu.req=14% cpu.lim=22% mem.use=0% mem.req=19% mem.lim=27%",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:       true,
+				noHeaders:     true,
+				noMetrics:     true,
+				criticalRules: test.criticalRules,
+				warningRules:  test.warningRules,
+				rulesFile:     test.rulesFile,
+				nagios:        test.nagios,
+				table:         table.NewOutputTable(buffer),
+				source:        newTestSource("", testNodes, testPods),
+			}
+
+			status, err := o.RunCheck([]string{})
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if status != test.expectedStatus {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expectedStatus, status)
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+
+	t.Run("usage not available", func(t *testing.T) {
+
+		for _, o := range []*FreeOptions{
+			{noMetrics: true, source: newTestSource("", testNodes, testPods)},
+			{source: source.NewStaticSource("", testNodes, testPods, nil, nil)},
+		} {
+			o.criticalRules = []string{"node:mem.use>90"}
+			o.table = table.NewOutputTable(&bytes.Buffer{})
+
+			status, err := o.RunCheck([]string{})
+			if err == nil || status != checkUnknown {
+				t.Errorf("unexpected error: should return err and %s (got: %s)", checkUnknown, status)
+			}
+		}
+	})
+
+	t.Run("no rules", func(t *testing.T) {
+
+		o := &FreeOptions{table: table.NewOutputTable(&bytes.Buffer{})}
+
+		status, err := o.RunCheck([]string{})
+		if err == nil || status != checkUnknown {
+			t.Errorf("unexpected error: should return err and %s (got: %s)", checkUnknown, status)
+		}
+	})
+}
+
+func TestCheckCommandExitStatus(t *testing.T) {
+
+	cmdtesting.InitTestErrorHandler(t)
+	tf := cmdtesting.NewTestFactory()
+	defer tf.Cleanup()
+	tf.ClientConfigVal = cmdtesting.DefaultClientConfig()
+
+	missingFile := filepath.Join(t.TempDir(), "missing.yaml")
+
+	var tests = []struct {
+		description string
+		args        []string
+		expectedOut string
+		expectedErr string
+	}{
+		{
+			"error",
+			[]string{"check", "--from-file", missingFile, "--rule", "node:mem.req>90"},
+			"",
+			"error: ",
+		},
+		{
+			"error nagios",
+			[]string{"check", "--from-file", missingFile, "--rule", "node:mem.req>90", "--nagios"},
+			"FREE UNKNOWN - ",
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			out := &bytes.Buffer{}
+			errOut := &bytes.Buffer{}
+			rootCmd := NewCmdFree(
+				tf,
+				genericclioptions.IOStreams{In: os.Stdin, Out: out, ErrOut: errOut},
+				"v0.0.1",
+				"abcd123",
+				"1234567890",
+			)
+
+			_, _, err := executeCommandC(rootCmd, test.args...)
+
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) || exitErr.Code != int(checkUnknown) {
+				t.Errorf("[%s] expected exit status %d (got: %v)", test.description, checkUnknown, err)
+			}
+			if !strings.HasPrefix(out.String(), test.expectedOut) || (test.expectedOut == "" && out.Len() > 0) {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expectedOut, out.String())
+			}
+			if !strings.Contains(errOut.String()+out.String(), missingFile) {
+				t.Errorf("[%s] expected error about %s (got: %q, %q)", test.description, missingFile, out.String(), errOut.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// cluster is the data source of a kubeconfig context (--contexts, --all-contexts)
+// err is set if the clients of the context couldn't be created.
+type cluster struct {
+	name   string
+	source source.Source
+	err    error
+}
+
+// clusterFree is the calculated resource usage of the nodes of a cluster
+type clusterFree struct {
+	nodes []nodeFree
+	err   error
+}
+
+// multiCluster reports whether the node summary is shown for several kubeconfig contexts
+func (o *FreeOptions) multiCluster() bool {
+	return len(o.contexts) > 0 || o.allContexts
+}
+
+// newClusters returns the data sources of the selected kubeconfig contexts
+func (o *FreeOptions) newClusters() ([]cluster, error) {
+
+	raw, err := o.configFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+
+	names := o.contexts
+	if o.allContexts {
+		names = maps.Keys(raw.Contexts)
+		slices.Sort(names)
+	}
+
+	clusters := []cluster{}
+	for _, name := range names {
+		c := cluster{name: name}
+		c.source, c.err = o.newClusterSource(raw, name)
+		clusters = append(clusters, c)
+	}
+
+	return clusters, nil
+}
+
+// newClusterSource returns the data source of a kubeconfig context
+func (o *FreeOptions) newClusterSource(raw clientcmdapi.Config, name string) (source.Source, error) {
+
+	if _, ok := raw.Contexts[name]; !ok {
+		return nil, fmt.Errorf("context %q does not exist", name)
+	}
+
+	config, err := clientcmd.NewNonInteractiveClientConfig(raw, name, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config of context %q: %v", name, err)
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client of context %q: %v", name, err)
+	}
+
+	mclient, err := o.setMetricsClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics client of context %q: %v", name, err)
+	}
+
+	return o.withPodFilter(source.NewClientSource(
+		client.CoreV1().Nodes(),
+		client.CoreV1().Pods(o.namespace),
+		mclient.MetricsV1beta1().NodeMetricses(),
+		mclient.MetricsV1beta1().PodMetricses(o.namespace),
+	)), nil
+}
+
+// showClusters prints the node summary of all clusters in one table with totals per cluster
+// Errors of clusters are printed to ErrOut and an error is returned if any cluster failed,
+// so scripts notice a partial result.
+func (o *FreeOptions) showClusters(ctx context.Context, args []string) error {
+
+	// set table header
+	if !o.noHeaders {
+		o.table.Header = append([]string{"CLUSTER"}, o.freeTableHeaders...)
+	}
+
+	// query clusters in parallel
+	results := make([]clusterFree, len(o.clusters))
+	var wg sync.WaitGroup
+	for i, c := range o.clusters {
+		wg.Add(1)
+		go func(i int, c cluster) {
+			defer wg.Done()
+			results[i] = o.getClusterFree(ctx, c, args)
+		}(i, c)
+	}
+	wg.Wait()
+
+	failed := []string{}
+	for i, c := range o.clusters {
+		if results[i].err != nil {
+			failed = append(failed, c.name)
+			continue
+		}
+
+		for _, nf := range results[i].nodes {
+			o.table.AddRow(append([]string{c.name}, o.nodeFreeToRow(nf, nil)...))
+		}
+		o.table.AddRow(append([]string{c.name}, o.clusterTotalToRow(results[i].nodes)...))
+	}
+
+	o.table.Print()
+
+	for i, c := range o.clusters {
+		if results[i].err != nil {
+			fmt.Fprintf(o.ErrOut, "error: context %s: %v\n", c.name, results[i].err)
+		}
+	}
+
+	if len(failed) == len(o.clusters) {
+		return fmt.Errorf("failed to get nodes of all contexts")
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to get nodes of contexts: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// getClusterFree calculates the resource usage of the nodes of a cluster
+func (o *FreeOptions) getClusterFree(ctx context.Context, c cluster, args []string) clusterFree {
+
+	if c.err != nil {
+		return clusterFree{err: c.err}
+	}
+
+	// options of the cluster, only the data source differs
+	co := *o
+	co.source = c.source
+
+	nodes, err := co.source.GetNodes(ctx, args, co.labelSelector)
+	if err != nil {
+		return clusterFree{err: err}
+	}
+
+	cf := clusterFree{nodes: []nodeFree{}}
+	for _, node := range nodes {
+		nf, err := co.getNodeFree(ctx, node)
+		if err != nil {
+			return clusterFree{err: err}
+		}
+		cf.nodes = append(cf.nodes, nf)
+	}
+
+	return cf
+}
+
+// clusterTotalToRow creates a table row of the sum of the nodes of a cluster
+// The status column shows the count of ready nodes.
+func (o *FreeOptions) clusterTotalToRow(nodes []nodeFree) []string {
+
+	total := nodeFree{name: "total"}
+	ready := 0
+	for _, nf := range nodes {
+		if nf.status == "Ready" {
+			ready++
+		}
+		total.cpuUsed += nf.cpuUsed
+		total.cpuRequested += nf.cpuRequested
+		total.cpuLimited += nf.cpuLimited
+		total.cpuAllocatable += nf.cpuAllocatable
+		total.memUsed += nf.memUsed
+		total.memRequested += nf.memRequested
+		total.memLimited += nf.memLimited
+		total.memAllocatable += nf.memAllocatable
+		total.podCount += nf.podCount
+		total.podAllocatable += nf.podAllocatable
+		total.containerCount += nf.containerCount
+		total.oomCount += nf.oomCount
+		total.fsUsed += nf.fsUsed
+		total.fsCapacity += nf.fsCapacity
+		total.ephemeralUsed += nf.ephemeralUsed
+		total.netRx += nf.netRx
+		total.netTx += nf.netTx
+	}
+
+	row := o.nodeFreeToRow(total, nil)
+
+	// hack: avoid breaking column by escape char of the colored node status
+	status := fmt.Sprintf("%d/%d", ready, len(nodes))
+	if !o.nocolor {
+		util.DefaultColor(&status)
+	}
+	row[1] = status
+
+	return row
+}
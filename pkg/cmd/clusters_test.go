@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// testKubeconfig has two contexts of two clusters
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster1
+  cluster:
+    server: https://127.0.0.1:6443
+- name: cluster2
+  cluster:
+    server: https://127.0.0.2:6443
+contexts:
+- name: prod
+  context:
+    cluster: cluster1
+    user: user1
+- name: staging
+  context:
+    cluster: cluster2
+    user: user1
+current-context: prod
+users:
+- name: user1
+  user:
+    token: token
+`
+
+func TestNewClusters(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var tests = []struct {
+		description string
+		contexts    []string
+		allContexts bool
+		expected    []string
+	}{
+		{
+			"contexts",
+			[]string{"staging", "unknown"},
+			false,
+			[]string{"staging:<nil>", `unknown:context "unknown" does not exist`},
+		},
+		{
+			"all contexts",
+			nil,
+			true,
+			[]string{"prod:<nil>", "staging:<nil>"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			o := &FreeOptions{
+				configFlags: genericclioptions.NewConfigFlags(true),
+				contexts:    test.contexts,
+				allContexts: test.allContexts,
+			}
+			*o.configFlags.KubeConfig = path
+
+			clusters, err := o.newClusters()
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := []string{}
+			for _, c := range clusters {
+				actual = append(actual, fmt.Sprintf("%s:%v", c.name, c.err))
+			}
+			if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestShowClusters(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("healthy and failed clusters", func(t *testing.T) {
+
+		buffer := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		o := &FreeOptions{
+			nocolor:   true,
+			noMetrics: true,
+			kByte:     true,
+			table:     table.NewOutputTable(buffer),
+			clusters: []cluster{
+				{name: "prod", source: newTestSource("", testNodes, testPods)},
+				{name: "broken", err: fmt.Errorf("context %q does not exist", "broken")},
+				{name: "staging", source: newTestSource("", testNodes[:1], testPods[:1])},
+			},
+		}
+		o.ErrOut = errOut
+		o.prepareFreeTableHeader()
+
+		// a partial result is an error
+		err := o.showClusters(ctx, []string{})
+		if err == nil || err.Error() != "failed to get nodes of contexts: broken" {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		expected := strings.Join([]string{
+			"CLUSTER NAME  STATUS   CPU/req CPU/lim CPU/alloc CPU/req% CPU/lim% MEM/req MEM/lim MEM/alloc MEM/req% MEM/lim%",
+			"prod    node1 Ready    1700m   2700m   4         42%      67%      2K      3K      4K        57%      82%",
+			"prod    node2 NotReady -       -       8         0%       0%       -       -       8K        0%       0%",
+			"prod    total 1/2      1700m   2700m   12        14%      22%      2K      3K      12K       19%      27%",
+			"staging node1 Ready    1       2       4         25%      50%      1K      2K      4K        25%      50%",
+			"staging total 1/1      1       2       4         25%      50%      1K      2K      4K        25%      50%",
+			"",
+		}, "\n")
+		if buffer.String() != expected {
+			t.Errorf("expected(\n%s) differ (got: \n%s)", expected, buffer.String())
+		}
+
+		expectedErr := "error: context broken: context \"broken\" does not exist\n"
+		if errOut.String() != expectedErr {
+			t.Errorf("expected(%q) differ (got: %q)", expectedErr, errOut.String())
+		}
+	})
+
+	t.Run("all clusters failed", func(t *testing.T) {
+
+		o := &FreeOptions{
+			table:    table.NewOutputTable(&bytes.Buffer{}),
+			clusters: []cluster{{name: "broken", err: fmt.Errorf("failed")}},
+		}
+		o.ErrOut = &bytes.Buffer{}
+
+		if err := o.showClusters(ctx, []string{}); err == nil {
+			t.Errorf("unexpected error: should return err")
+		}
+	})
+}
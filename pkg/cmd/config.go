@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// envPrefix is the prefix of environment variables overriding flag defaults
+// e.g. KUBECTL_FREE_WARN_THRESHOLD=70 for --warn-threshold
+const envPrefix = "KUBECTL_FREE_"
+
+// freeConfig is the config file (--config) with defaults and named profiles (--profile)
+// Keys are flag names and values are flag values, e.g.
+//
+//	defaults:
+//	  warn-threshold: 70
+//	  sort-by-resource: cpu
+//	profiles:
+//	  capacity-review:
+//	    list: true
+//	    no-metrics: true
+type freeConfig struct {
+	Defaults map[string]interface{}            `json:"defaults"`
+	Profiles map[string]map[string]interface{} `json:"profiles"`
+}
+
+// loadConfig reads the config file
+// A missing file is an empty config unless the file or a profile is explicitly given.
+func loadConfig(path string, required bool) (*freeConfig, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return &freeConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to open config file: %v", err)
+	}
+	defer f.Close()
+
+	config := &freeConfig{}
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(config); err != nil {
+		if errors.Is(err, io.EOF) {
+			return config, nil
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+// applyConfig sets flags which are not given on the command line
+// Precedence is flags > environment variables (KUBECTL_FREE_*) > profile > defaults of the config file.
+func (o *FreeOptions) applyConfig(cmd *cobra.Command) error {
+
+	flags := cmd.Flags()
+
+	// environment variables, also selects the config file and the profile
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			if e := flags.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("failed to set %s: %v", envName(f.Name), e)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if o.configFile == "" {
+		return nil
+	}
+
+	config, err := loadConfig(o.configFile, flags.Changed("config") || o.profile != "")
+	if err != nil {
+		return err
+	}
+
+	if o.profile != "" {
+		profile, ok := config.Profiles[o.profile]
+		if !ok {
+			return fmt.Errorf("profile %q does not exist in %s", o.profile, o.configFile)
+		}
+		if err := setConfigFlags(cmd, profile); err != nil {
+			return fmt.Errorf("failed to apply profile %q: %v", o.profile, err)
+		}
+	}
+
+	if err := setConfigFlags(cmd, config.Defaults); err != nil {
+		return fmt.Errorf("failed to apply defaults of %s: %v", o.configFile, err)
+	}
+
+	return nil
+}
+
+// setConfigFlags sets flags which are not set yet to the values of the config file
+// Options of other commands (e.g. --list for a sub command) are ignored.
+func setConfigFlags(cmd *cobra.Command, values map[string]interface{}) error {
+
+	flags := cmd.Flags()
+	for name, value := range values {
+
+		f := flags.Lookup(name)
+		if f == nil {
+			if !hasFlag(cmd.Root(), name) {
+				return fmt.Errorf("unknown option %q", name)
+			}
+			continue
+		}
+		if f.Changed {
+			continue
+		}
+
+		v, err := configValueToString(value)
+		if err != nil {
+			return fmt.Errorf("invalid value of %q: %v", name, err)
+		}
+		if err := flags.Set(name, v); err != nil {
+			return fmt.Errorf("invalid value of %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// hasFlag reports whether a command or one of its sub commands has a flag
+func hasFlag(cmd *cobra.Command, name string) bool {
+	if cmd.Flags().Lookup(name) != nil {
+		return true
+	}
+	for _, c := range cmd.Commands() {
+		if hasFlag(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// configValueToString converts a value of the config file to a flag value
+// Lists are values of slice flags, e.g. contexts: [prod, staging]
+func configValueToString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case []interface{}:
+		values := []string{}
+		for _, e := range v {
+			s, err := configValueToString(e)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, s)
+		}
+		return strings.Join(values, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// envName returns the environment variable of a flag, e.g. KUBECTL_FREE_NO_COLOR for --no-color
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+const testConfig = `defaults:
+  warn-threshold: 70
+  crit-threshold: 80
+  sort-by-resource: cpu
+profiles:
+  capacity-review:
+    warn-threshold: 50
+    list: true
+    contexts: [prod, staging]
+  broken:
+    unknown-option: true
+`
+
+func TestApplyConfig(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var tests = []struct {
+		description string
+		args        []string
+		env         map[string]string
+		expected    string
+		expectedErr string
+	}{
+		{
+			"defaults",
+			[]string{"--config", path},
+			nil,
+			"warn:70 crit:80 sort:cpu list:false contexts:[]",
+			"",
+		},
+		{
+			"profile",
+			[]string{"--config", path, "--profile", "capacity-review"},
+			nil,
+			"warn:50 crit:80 sort:cpu list:true contexts:[prod staging]",
+			"",
+		},
+		{
+			"env overrides profile",
+			[]string{"--config", path},
+			map[string]string{"KUBECTL_FREE_PROFILE": "capacity-review", "KUBECTL_FREE_WARN_THRESHOLD": "40"},
+			"warn:40 crit:80 sort:cpu list:true contexts:[prod staging]",
+			"",
+		},
+		{
+			"flags override env",
+			[]string{"--config", path, "--warn-threshold", "30", "--sort-by-resource", "memory"},
+			map[string]string{"KUBECTL_FREE_WARN_THRESHOLD": "40"},
+			"warn:30 crit:80 sort:memory list:false contexts:[]",
+			"",
+		},
+		{
+			"missing default config file",
+			[]string{},
+			nil,
+			"warn:60 crit:90 sort:memory list:false contexts:[]",
+			"",
+		},
+		{
+			"unknown profile",
+			[]string{"--config", path, "--profile", "unknown"},
+			nil,
+			"",
+			`profile "unknown" does not exist in ` + path,
+		},
+		{
+			"unknown option",
+			[]string{"--config", path, "--profile", "broken"},
+			nil,
+			"",
+			`failed to apply profile "broken": unknown option "unknown-option"`,
+		},
+		{
+			"invalid env",
+			[]string{"--config", path},
+			map[string]string{"KUBECTL_FREE_SORT_BY_RESOURCE": "disk"},
+			"",
+			`failed to set KUBECTL_FREE_SORT_BY_RESOURCE: invalid argument "disk" for "--sort-by-resource" flag: can only sort by "memory" and "cpu", not by given "disk"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			o := NewFreeOptions(genericclioptions.NewTestIOStreamsDiscard())
+			o.configFile = filepath.Join(t.TempDir(), "missing.yaml")
+
+			cmd := &cobra.Command{}
+			cmd.Flags().Int64Var(&o.warnThreshold, "warn-threshold", o.warnThreshold, "")
+			cmd.Flags().Int64Var(&o.critThreshold, "crit-threshold", o.critThreshold, "")
+			cmd.Flags().Var(&o.sortByResource, "sort-by-resource", "")
+			cmd.Flags().BoolVar(&o.list, "list", o.list, "")
+			cmd.Flags().StringSliceVar(&o.contexts, "contexts", o.contexts, "")
+			cmd.Flags().StringVar(&o.configFile, "config", o.configFile, "")
+			cmd.Flags().StringVar(&o.profile, "profile", o.profile, "")
+
+			if err := cmd.ParseFlags(test.args); err != nil {
+				t.Fatalf("[%s] unexpected error: %v", test.description, err)
+			}
+
+			err := o.applyConfig(cmd)
+			if test.expectedErr != "" {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := strings.Join([]string{
+				"warn:" + strconv.FormatInt(o.warnThreshold, 10),
+				"crit:" + strconv.FormatInt(o.critThreshold, 10),
+				"sort:" + o.sortByResource.String(),
+				"list:" + strconv.FormatBool(o.list),
+				"contexts:[" + strings.Join(o.contexts, " ") + "]",
+			}, " ")
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/constants"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// liveSnapshot is the argument of diff to compare with the current state of the cluster
+const liveSnapshot = "live"
+
+var (
+	// diffLong defines long description
+	diffLong = templates.LongDesc(`
+		Show changes of resources between two snapshots taken by "kubectl free snapshot".
+
+		If the second snapshot is omitted or "live", the snapshot is compared with the current state of the cluster.
+		Changes are listed per node (including added and removed nodes) and per namespace.
+	`)
+
+	// diffExample defines command examples
+	diffExample = templates.Examples(`
+		# Compare a snapshot with the current state of the cluster.
+		kubectl free snapshot > before.json
+		kubectl free diff before.json
+
+		# Compare two snapshots.
+		kubectl free diff before.json after.json
+	`)
+)
+
+// namespaceFree is the aggregated resource usage of containers in a namespace
+type namespaceFree struct {
+	cpuUsed      int64
+	cpuRequested int64
+	cpuLimited   int64
+
+	memUsed      int64
+	memRequested int64
+	memLimited   int64
+
+	podCount       int
+	containerCount int
+
+	// usage of a container is available
+	hasUsage bool
+}
+
+// NewCmdDiff is a cobra command wrapping diff
+func NewCmdDiff(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "diff BEFORE [AFTER|live]",
+		Short:   "Show changes of resources between two snapshots.",
+		Long:    diffLong,
+		Example: diffExample,
+		Args:    cobra.RangeArgs(1, 2),
+		Run: func(c *cobra.Command, args []string) {
+			// snapshot files don't require access to the cluster
+			if len(args) == 1 || args[1] == liveSnapshot {
+				cmdutil.CheckErr(o.Complete(f, c, []string{}))
+			} else {
+				cmdutil.CheckErr(o.applyConfig(c))
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunDiff(args))
+		},
+	}
+
+	return cmd
+}
+
+// RunDiff prints changes between two snapshots
+func (o *FreeOptions) RunDiff(args []string) error {
+
+	before, err := readSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+
+	var after *snapshot
+	if len(args) == 1 || args[1] == liveSnapshot {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		after, err = o.takeSnapshot(ctx, []string{})
+	} else {
+		after, err = readSnapshot(args[1])
+	}
+	if err != nil {
+		return err
+	}
+
+	o.showDiff(before, after)
+
+	return nil
+}
+
+// showDiff prints changed nodes and namespaces
+func (o *FreeOptions) showDiff(before, after *snapshot) {
+
+	out := o.table.Output
+
+	if !o.noHeaders {
+		fmt.Fprintf(out, "before: %s\tafter: %s\n\n", before.Time.Format(time.RFC3339), after.Time.Format(time.RFC3339))
+	}
+
+	nodeTable := table.NewOutputTable(out)
+	if !o.noHeaders {
+		nodeTable.Header = o.diffNodeTableHeader()
+	}
+	for _, row := range o.diffNodeRows(before.Nodes, after.Nodes) {
+		nodeTable.AddRow(row)
+	}
+
+	namespaceTable := table.NewOutputTable(out)
+	if !o.noHeaders {
+		namespaceTable.Header = o.diffNamespaceTableHeader()
+	}
+	for _, row := range o.diffNamespaceRows(before.Containers, after.Containers) {
+		namespaceTable.AddRow(row)
+	}
+
+	if len(nodeTable.Rows) == 0 && len(namespaceTable.Rows) == 0 {
+		fmt.Fprintln(out, "No changes.")
+		return
+	}
+
+	if len(nodeTable.Rows) > 0 {
+		nodeTable.Print()
+	}
+	if len(nodeTable.Rows) > 0 && len(namespaceTable.Rows) > 0 {
+		fmt.Fprintln(out)
+	}
+	if len(namespaceTable.Rows) > 0 {
+		namespaceTable.Print()
+	}
+}
+
+// diffNodeRows creates table rows of added, removed and changed nodes
+func (o *FreeOptions) diffNodeRows(before, after []snapshotNode) [][]string {
+
+	beforeNodes := map[string]snapshotNode{}
+	for _, n := range before {
+		beforeNodes[n.Name] = n
+	}
+	afterNodes := map[string]snapshotNode{}
+	for _, n := range after {
+		afterNodes[n.Name] = n
+	}
+
+	names := maps.Keys(beforeNodes)
+	for name := range afterNodes {
+		if _, ok := beforeNodes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	rows := [][]string{}
+	for _, name := range names {
+		b, inBefore := beforeNodes[name]
+		a, inAfter := afterNodes[name]
+
+		change := "changed"
+		status := a.Status
+		switch {
+		case !inBefore:
+			change = "added"
+		case !inAfter:
+			change = "removed"
+			status = b.Status
+		case a == b:
+			// unchanged
+			continue
+		case a.Status != b.Status:
+			status = b.Status + "->" + a.Status
+		}
+
+		row := []string{name, change, status}
+		if !o.noMetrics {
+			row = append(row, o.toMilliDelta(a.CPUUsed-b.CPUUsed))
+		}
+		row = append(
+			row,
+			o.toMilliDelta(a.CPURequested-b.CPURequested),
+			o.toMilliDelta(a.CPULimited-b.CPULimited),
+			o.toMilliDelta(a.CPUAllocatable-b.CPUAllocatable),
+		)
+		if !o.noMetrics {
+			row = append(row, o.toUnitDelta(a.MemUsed-b.MemUsed))
+		}
+		row = append(
+			row,
+			o.toUnitDelta(a.MemRequested-b.MemRequested),
+			o.toUnitDelta(a.MemLimited-b.MemLimited),
+			o.toUnitDelta(a.MemAllocatable-b.MemAllocatable),
+			toCountDelta(int64(a.PodCount-b.PodCount)),
+			toCountDelta(int64(a.ContainerCount-b.ContainerCount)),
+		)
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// diffNamespaceRows creates table rows of added, removed and changed namespaces
+func (o *FreeOptions) diffNamespaceRows(before, after []snapshotContainer) [][]string {
+
+	beforeNamespaces := aggregateNamespaces(before)
+	afterNamespaces := aggregateNamespaces(after)
+
+	names := maps.Keys(beforeNamespaces)
+	for name := range afterNamespaces {
+		if _, ok := beforeNamespaces[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	rows := [][]string{}
+	for _, name := range names {
+		b, inBefore := beforeNamespaces[name]
+		a, inAfter := afterNamespaces[name]
+
+		change := "changed"
+		switch {
+		case !inBefore:
+			change = "added"
+		case !inAfter:
+			change = "removed"
+		case a == b:
+			// unchanged
+			continue
+		}
+
+		row := []string{name, change}
+		if !o.noMetrics {
+			row = append(row, o.toMilliDelta(a.cpuUsed-b.cpuUsed))
+		}
+		row = append(
+			row,
+			o.toMilliDelta(a.cpuRequested-b.cpuRequested),
+			o.toMilliDelta(a.cpuLimited-b.cpuLimited),
+		)
+		if !o.noMetrics {
+			row = append(row, o.toUnitDelta(a.memUsed-b.memUsed))
+		}
+		row = append(
+			row,
+			o.toUnitDelta(a.memRequested-b.memRequested),
+			o.toUnitDelta(a.memLimited-b.memLimited),
+			toCountDelta(int64(a.podCount-b.podCount)),
+			toCountDelta(int64(a.containerCount-b.containerCount)),
+		)
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// aggregateNamespaces sums up resources of containers of running pods per namespace
+func aggregateNamespaces(containers []snapshotContainer) map[string]namespaceFree {
+
+	namespaces := map[string]namespaceFree{}
+	pods := map[string]bool{}
+
+	for _, c := range containers {
+		// only running pods like the totals of nodes
+		if c.PodPhase != string(v1.PodRunning) {
+			continue
+		}
+
+		nf := namespaces[c.Namespace]
+
+		if c.CPUUsed != nil {
+			nf.cpuUsed += *c.CPUUsed
+			nf.hasUsage = true
+		}
+		nf.cpuRequested += c.CPURequested
+		nf.cpuLimited += c.CPULimit
+
+		if c.MemoryUsed != nil {
+			nf.memUsed += *c.MemoryUsed
+			nf.hasUsage = true
+		}
+		nf.memRequested += c.MemoryRequested
+		nf.memLimited += c.MemoryLimit
+
+		podKey := c.Namespace + "/" + c.PodName
+		if !pods[podKey] {
+			pods[podKey] = true
+			nf.podCount++
+		}
+		nf.containerCount++
+
+		namespaces[c.Namespace] = nf
+	}
+
+	return namespaces
+}
+
+// diffNodeTableHeader defines table headers for node changes
+func (o *FreeOptions) diffNodeTableHeader() []string {
+
+	hStatus := "STATUS"
+	if !o.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hStatus)
+	}
+
+	header := []string{"NAME", "CHANGE", hStatus}
+	if !o.noMetrics {
+		header = append(header, "CPU/use")
+	}
+	header = append(header, "CPU/req", "CPU/lim", "CPU/alloc")
+	if !o.noMetrics {
+		header = append(header, "MEM/use")
+	}
+	header = append(header, "MEM/req", "MEM/lim", "MEM/alloc", "PODS", "CONTAINERS")
+
+	return header
+}
+
+// diffNamespaceTableHeader defines table headers for namespace changes
+func (o *FreeOptions) diffNamespaceTableHeader() []string {
+
+	header := []string{"NAMESPACE", "CHANGE"}
+	if !o.noMetrics {
+		header = append(header, "CPU/use")
+	}
+	header = append(header, "CPU/req", "CPU/lim")
+	if !o.noMetrics {
+		header = append(header, "MEM/use")
+	}
+	header = append(header, "MEM/req", "MEM/lim", "PODS", "CONTAINERS")
+
+	return header
+}
+
+// toMilliDelta returns a signed toMilliUnitOrDash, "-" if "i" is 0
+func (o *FreeOptions) toMilliDelta(i int64) string {
+	switch {
+	case i > 0:
+		return "+" + o.toMilliUnitOrDash(i)
+	case i < 0:
+		return "-" + o.toMilliUnitOrDash(-i)
+	default:
+		return "-"
+	}
+}
+
+// toUnitDelta returns a signed toUnitOrDash, "-" if "i" is 0
+// Deltas smaller than the unit are shown in bytes, e.g. "+512B" instead of "+0M".
+func (o *FreeOptions) toUnitDelta(i int64) string {
+
+	sign := "+"
+	if i < 0 {
+		sign = "-"
+		i = -i
+	}
+	if i == 0 {
+		return "-"
+	}
+
+	unitbytes, _ := util.GetSiUnit(o.bytes, o.kByte, o.mByte, o.gByte)
+	if o.binPrefix {
+		unitbytes, _ = util.GetBinUnit(o.bytes, o.kByte, o.mByte, o.gByte)
+	}
+	if i < unitbytes {
+		return sign + strconv.FormatInt(i, 10) + constants.UnitBytesStr
+	}
+
+	return sign + o.toUnit(i)
+}
+
+// toCountDelta returns a signed count, "-" if "i" is 0
+func toCountDelta(i int64) string {
+	switch {
+	case i > 0:
+		return "+" + strconv.FormatInt(i, 10)
+	case i < 0:
+		return strconv.FormatInt(i, 10)
+	default:
+		return "-"
+	}
+}
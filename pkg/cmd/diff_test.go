@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+)
+
+func TestShowDiff(t *testing.T) {
+
+	used := int64(100)
+
+	node1 := snapshotNode{Name: "node1", Status: "Ready", CPURequested: 1000, CPUAllocatable: 4000, MemRequested: 1000, MemAllocatable: 4000, PodCount: 1, ContainerCount: 1}
+	node1Scaled := node1
+	node1Scaled.CPURequested = 1500
+	node1Scaled.MemRequested = 3000
+	node1Scaled.PodCount = 2
+	node1Scaled.ContainerCount = 2
+	node2 := snapshotNode{Name: "node2", Status: "Ready", CPUAllocatable: 2000, MemAllocatable: 2000}
+
+	container1 := snapshotContainer{NodeName: "node1", Namespace: "default", PodName: "pod1", PodPhase: "Running", Name: "c1", CPURequested: 1000, MemoryRequested: 1000, CPUUsed: &used}
+	container2 := snapshotContainer{NodeName: "node1", Namespace: "default", PodName: "pod2", PodPhase: "Running", Name: "c1", CPURequested: 500, MemoryRequested: 2000}
+	container3 := snapshotContainer{NodeName: "node2", Namespace: "awesome-ns", PodName: "pod3", PodPhase: "Running", Name: "c1"}
+	completed := snapshotContainer{NodeName: "node1", Namespace: "default", PodName: "job", PodPhase: "Succeeded", Name: "c1", CPURequested: 1000}
+	container1Grown := container1
+	container1Grown.MemoryRequested += 512
+
+	var tests = []struct {
+		description string
+		noMetrics   bool
+		before      *snapshot
+		after       *snapshot
+		expected    []string
+	}{
+		{
+			"no changes",
+			true,
+			&snapshot{Nodes: []snapshotNode{node1}, Containers: []snapshotContainer{container1}},
+			&snapshot{Nodes: []snapshotNode{node1}, Containers: []snapshotContainer{container1}},
+			[]string{
+				"No changes.",
+				"",
+			},
+		},
+		{
+			"scaled up",
+			true,
+			&snapshot{Nodes: []snapshotNode{node1}, Containers: []snapshotContainer{container1}},
+			&snapshot{Nodes: []snapshotNode{node1Scaled}, Containers: []snapshotContainer{container1, container2}},
+			[]string{
+				"node1 changed Ready +500m - - +2K - - +1 +1",
+				"",
+				"default changed +500m - +2K - +1 +1",
+				"",
+			},
+		},
+		{
+			"completed pod",
+			true,
+			&snapshot{Nodes: []snapshotNode{node1}, Containers: []snapshotContainer{container1}},
+			&snapshot{Nodes: []snapshotNode{node1}, Containers: []snapshotContainer{container1, completed}},
+			[]string{
+				"No changes.",
+				"",
+			},
+		},
+		{
+			"change smaller than the unit",
+			true,
+			&snapshot{Nodes: []snapshotNode{node1}, Containers: []snapshotContainer{container1}},
+			&snapshot{Nodes: []snapshotNode{node1}, Containers: []snapshotContainer{container1Grown}},
+			[]string{
+				"default changed - - +512B - - -",
+				"",
+			},
+		},
+		{
+			"node and namespace added and removed",
+			false,
+			&snapshot{Nodes: []snapshotNode{node1}, Containers: []snapshotContainer{container1}},
+			&snapshot{Nodes: []snapshotNode{node2}, Containers: []snapshotContainer{container3}},
+			[]string{
+				"node1 removed Ready - -1 - -4 - -1K - -4K -1 -1",
+				"node2 added   Ready - -  - +2 - -   - +2K -  -",
+				"",
+				"awesome-ns added   -     -  - - -   - +1 +1",
+				"default    removed -100m -1 - - -1K - -1 -1",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:   true,
+				noHeaders: true,
+				noMetrics: test.noMetrics,
+				kByte:     true,
+				table:     table.NewOutputTable(buffer),
+			}
+
+			o.showDiff(test.before, test.after)
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
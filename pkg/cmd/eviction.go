@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+)
+
+// podEviction is the memory usage of a pod ranked for eviction
+// Usage is 0 and hasUsage false if no metrics are available for the pod.
+type podEviction struct {
+	nodeName      string
+	namespace     string
+	name          string
+	qosClass      string
+	priorityClass string
+	priority      int32
+	memUsed       int64
+	memRequested  int64
+	memLimited    int64
+	hasUsage      bool
+}
+
+// exceedsRequests reports whether the memory usage of the pod exceeds its requests
+func (p podEviction) exceedsRequests() bool {
+	return p.hasUsage && p.memUsed > p.memRequested
+}
+
+// showEvictionRisk prints pods of nodes under memory pressure in the order the kubelet would evict them
+// A node is under memory pressure if it reports the MemoryPressure condition or its memory usage reaches the crit threshold.
+func (o *FreeOptions) showEvictionRisk(ctx context.Context, nodes []v1.Node) error {
+
+	// include containers without requests/limits, they are evicted first
+	listAll := o.listAll
+	o.listAll = true
+	defer func() { o.listAll = listAll }()
+
+	// get pod metrics
+	podMetrics := o.getPodMetrics(ctx)
+
+	t := o.table
+	if !o.noHeaders {
+		t.Header = o.evictionTableHeader()
+	}
+
+	for _, node := range nodes {
+
+		nf, err := o.getNodeFree(ctx, node)
+		if err != nil {
+			return err
+		}
+		if !o.underMemoryPressure(node, nf) {
+			continue
+		}
+
+		infos, err := o.getPodInfos(ctx, node, podMetrics)
+		if err != nil {
+			return err
+		}
+
+		for i, p := range rankEviction(getPodEvictions(infos)) {
+			t.AddRow(o.podEvictionToRow(i+1, p))
+		}
+	}
+
+	if len(t.Rows) == 0 {
+		fmt.Fprintf(t.Output, "No nodes under memory pressure (crit-threshold:%d%%).\n", o.getThreshold(memUseThreshold).crit)
+		return nil
+	}
+
+	t.Print()
+
+	return nil
+}
+
+// underMemoryPressure reports whether the kubelet of a node is about to evict pods
+func (o *FreeOptions) underMemoryPressure(node v1.Node, nf nodeFree) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeMemoryPressure && condition.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return nf.memUsed > 0 && util.GetPercentage(nf.memUsed, nf.memAllocatable) >= o.getThreshold(memUseThreshold).crit
+}
+
+// getPodEvictions sums memory of containers per pod
+// Terminated pods are skipped, they don't use memory of the node anymore.
+func getPodEvictions(infos []podInfo) []podEviction {
+
+	pods := []podEviction{}
+	index := map[string]int{}
+
+	for _, info := range infos {
+		if info.podPhase == string(v1.PodSucceeded) || info.podPhase == string(v1.PodFailed) {
+			continue
+		}
+
+		key := info.podNamespace + "/" + info.podName
+		i, ok := index[key]
+		if !ok {
+			i = len(pods)
+			index[key] = i
+			pods = append(pods, podEviction{
+				nodeName:      info.nodeName,
+				namespace:     info.podNamespace,
+				name:          info.podName,
+				qosClass:      info.podQOSClass,
+				priorityClass: info.podPriorityClass,
+				priority:      info.podPriority,
+			})
+		}
+
+		p := &pods[i]
+		p.memRequested += info.containerMemoryRequested
+		p.memLimited += info.containerMemoryLimit
+		if info.containerMemoryUsed != nil {
+			p.memUsed += info.containerMemoryUsed.Value()
+			p.hasUsage = true
+		}
+	}
+
+	return pods
+}
+
+// rankEviction sorts pods in the order the kubelet evicts them under memory pressure
+// Pods using more memory than requested come first, then pods of lower priority,
+// then pods using more memory above their requests.
+func rankEviction(pods []podEviction) []podEviction {
+	slices.SortStableFunc(pods, func(a, b podEviction) bool {
+		if a.exceedsRequests() != b.exceedsRequests() {
+			return a.exceedsRequests()
+		}
+		if a.priority != b.priority {
+			return a.priority < b.priority
+		}
+		return a.memUsed-a.memRequested > b.memUsed-b.memRequested
+	})
+	return pods
+}
+
+// podEvictionToRow creates a table row of a pod ranked for eviction
+func (o *FreeOptions) podEvictionToRow(rank int, p podEviction) []string {
+
+	priorityClass := p.priorityClass
+	if priorityClass == "" {
+		priorityClass = "-"
+	}
+
+	memUsed := "-"
+	if p.hasUsage {
+		memUsed = o.toUnit(p.memUsed)
+	}
+
+	memOverRequests := "-"
+	if p.exceedsRequests() {
+		memOverRequests = o.toUnit(p.memUsed - p.memRequested)
+	}
+
+	return []string{
+		p.nodeName,                     // node name
+		fmt.Sprintf("%d", rank),        // eviction rank
+		p.namespace,                    // namespace
+		p.name,                         // pod name
+		p.qosClass,                     // qos class
+		priorityClass,                  // priority class
+		fmt.Sprintf("%d", p.priority),  // priority
+		memUsed,                        // mem used (from metrics)
+		o.toUnitOrDash(p.memRequested), // mem requested
+		o.toUnitOrDash(p.memLimited),   // mem limited
+		memOverRequests,                // mem used above requests
+	}
+}
+
+// evictionTableHeader defines table headers for --eviction-risk
+func (o *FreeOptions) evictionTableHeader() []string {
+	return []string{
+		"NODE NAME",
+		"RANK",
+		"NAMESPACE",
+		"POD NAME",
+		"QOS",
+		"PRIORITY CLASS",
+		"PRIORITY",
+		"MEM/use",
+		"MEM/req",
+		"MEM/lim",
+		"MEM/use-req",
+	}
+}
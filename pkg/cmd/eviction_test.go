@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+)
+
+func TestRankEviction(t *testing.T) {
+
+	pods := []podEviction{
+		{name: "high-priority", priority: 1000, memUsed: 2000, memRequested: 1000, hasUsage: true},
+		{name: "within-requests", priority: 0, memUsed: 500, memRequested: 1000, hasUsage: true},
+		{name: "over-10", priority: 0, memUsed: 1010, memRequested: 1000, hasUsage: true},
+		{name: "over-100", priority: 0, memUsed: 1100, memRequested: 1000, hasUsage: true},
+		{name: "no-metrics", priority: 0, memRequested: 0},
+	}
+
+	expected := []string{"over-100", "over-10", "high-priority", "no-metrics", "within-requests"}
+
+	actual := []string{}
+	for _, p := range rankEviction(pods) {
+		actual = append(actual, p.name)
+	}
+
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected(%v) differ (got: %v)", expected, actual)
+	}
+}
+
+func TestShowEvictionRisk(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		description   string
+		critThreshold int64
+		expected      []string
+	}{
+		{
+			"no memory pressure",
+			90,
+			[]string{
+				"No nodes under memory pressure (crit-threshold:90%).",
+				"",
+			},
+		},
+		{
+			"above crit threshold",
+			20,
+			[]string{
+				"node1 1 awesome-ns pod3 Burstable - 0 -  0K 0K -",
+				"node1 2 default    pod1 Burstable - 0 0K 1K 2K -",
+				"node1 3 default    pod2 Burstable - 0 -  1K 1K -",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:       true,
+				noHeaders:     true,
+				kByte:         true,
+				critThreshold: test.critThreshold,
+				table:         table.NewOutputTable(buffer),
+				source:        newTestSource("", testNodes, testPods),
+			}
+
+			if err := o.showEvictionRisk(ctx, testNodes); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// history groups (--by)
+	historyByNode      = "node"
+	historyByNamespace = "namespace"
+
+	// history values (--value)
+	historyValueUse = "use"
+	historyValueReq = "req"
+	historyValueLim = "lim"
+
+	// sparklineWidth is the maximum number of bars of a trend
+	sparklineWidth = 20
+)
+
+var (
+	// historyLong defines long description
+	historyLong = templates.LongDesc(`
+		Show min, avg, max and p95 of resources recorded by "kubectl free record" per node or namespace.
+
+		The trend columns are sparklines of the recorded samples from old to new.
+	`)
+
+	// historyExample defines command examples
+	historyExample = templates.Examples(`
+		# Show usage of nodes during the last 24 hours.
+		kubectl free history
+
+		# Show requests of namespaces during the last 7 days.
+		kubectl free history --by namespace --value req --since 168h
+
+		# Show usage of specific nodes.
+		kubectl free history node1 node2
+	`)
+)
+
+// historySeries are recorded cpu and memory values of a node or namespace
+type historySeries struct {
+	cpu []int64
+	mem []int64
+}
+
+// NewCmdHistory is a cobra command wrapping history
+func NewCmdHistory(o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "history [NAME...]",
+		Short:   "Show trends of resources recorded by \"kubectl free record\".",
+		Long:    historyLong,
+		Example: historyExample,
+		Run: func(c *cobra.Command, args []string) {
+			// the history store doesn't require access to the cluster, only the config (e.g. store)
+			cmdutil.CheckErr(o.applyConfig(c))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunHistory(args))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.historyStore, "store", "", o.historyStore, `Path of the history store.`)
+	cmd.Flags().DurationVarP(&o.historySince, "since", "", o.historySince, `Only show samples recorded within this duration.`)
+	cmd.Flags().StringVarP(&o.historyBy, "by", "", o.historyBy, `Group samples by "node" or "namespace".`)
+	cmd.Flags().StringVarP(&o.historyValue, "value", "", o.historyValue, `Show "use", "req" or "lim" of cpu and memory.`)
+
+	return cmd
+}
+
+// RunHistory prints statistics of recorded samples
+func (o *FreeOptions) RunHistory(args []string) error {
+
+	if o.historyBy != historyByNode && o.historyBy != historyByNamespace {
+		return fmt.Errorf("can only group by %q and %q, not by given %q", historyByNode, historyByNamespace, o.historyBy)
+	}
+	if o.historyValue != historyValueUse && o.historyValue != historyValueReq && o.historyValue != historyValueLim {
+		return fmt.Errorf("can only show %q, %q and %q, not given %q", historyValueUse, historyValueReq, historyValueLim, o.historyValue)
+	}
+
+	samples, err := readHistorySamples(o.historyStore, time.Now().Add(-o.historySince))
+	if err != nil {
+		return err
+	}
+
+	o.showHistory(samples, args)
+
+	return nil
+}
+
+// showHistory prints min, avg, max, p95 and trend of cpu and memory per node or namespace
+func (o *FreeOptions) showHistory(samples []historySample, names []string) {
+
+	series := o.historySeries(samples)
+
+	if len(names) == 0 {
+		names = maps.Keys(series)
+		slices.Sort(names)
+	}
+
+	if !o.noHeaders && len(samples) > 0 {
+		fmt.Fprintf(
+			o.table.Output,
+			"%d samples from %s to %s\n\n",
+			len(samples),
+			samples[0].Time.Format(time.RFC3339),
+			samples[len(samples)-1].Time.Format(time.RFC3339),
+		)
+	}
+
+	t := table.NewOutputTable(o.table.Output)
+	if !o.noHeaders {
+		t.Header = o.historyTableHeader()
+	}
+
+	for _, name := range names {
+		s, ok := series[name]
+		if !ok {
+			continue
+		}
+
+		cpuMin, cpuAvg, cpuMax, cpuP95 := util.GetStats(s.cpu)
+		memMin, memAvg, memMax, memP95 := util.GetStats(s.mem)
+
+		t.AddRow([]string{
+			name,
+			fmt.Sprintf("%d", len(s.cpu)),
+			o.toMilliUnitOrDash(cpuMin),
+			o.toMilliUnitOrDash(cpuAvg),
+			o.toMilliUnitOrDash(cpuMax),
+			o.toMilliUnitOrDash(cpuP95),
+			util.GetSparkline(s.cpu, sparklineWidth),
+			o.toUnitOrDash(memMin),
+			o.toUnitOrDash(memAvg),
+			o.toUnitOrDash(memMax),
+			o.toUnitOrDash(memP95),
+			util.GetSparkline(s.mem, sparklineWidth),
+		})
+	}
+
+	if len(t.Rows) == 0 {
+		fmt.Fprintf(o.table.Output, "No samples in %s within %s.\n", o.historyStore, o.historySince)
+		return
+	}
+
+	t.Print()
+}
+
+// historySeries collects the selected values of samples per node or namespace
+// Samples without usage (e.g. metrics were not available) are skipped for usage.
+func (o *FreeOptions) historySeries(samples []historySample) map[string]*historySeries {
+
+	series := map[string]*historySeries{}
+	add := func(name string, cpu, mem *int64) {
+		s, ok := series[name]
+		if !ok {
+			s = &historySeries{}
+			series[name] = s
+		}
+		if cpu != nil {
+			s.cpu = append(s.cpu, *cpu)
+		}
+		if mem != nil {
+			s.mem = append(s.mem, *mem)
+		}
+	}
+
+	for _, sample := range samples {
+		if o.historyBy == historyByNamespace {
+			for _, n := range sample.Namespaces {
+				switch o.historyValue {
+				case historyValueReq:
+					add(n.Name, &n.CPURequested, &n.MemRequested)
+				case historyValueLim:
+					add(n.Name, &n.CPULimited, &n.MemLimited)
+				default:
+					add(n.Name, n.CPUUsed, n.MemUsed)
+				}
+			}
+			continue
+		}
+
+		for _, n := range sample.Nodes {
+			switch o.historyValue {
+			case historyValueReq:
+				add(n.Name, &n.CPURequested, &n.MemRequested)
+			case historyValueLim:
+				add(n.Name, &n.CPULimited, &n.MemLimited)
+			default:
+				add(n.Name, n.CPUUsed, n.MemUsed)
+			}
+		}
+	}
+
+	return series
+}
+
+// historyTableHeader defines table headers for history
+func (o *FreeOptions) historyTableHeader() []string {
+
+	hName := "NAME"
+	if o.historyBy == historyByNamespace {
+		hName = "NAMESPACE"
+	}
+
+	return []string{
+		hName,
+		"SAMPLES",
+		"CPU/" + o.historyValue + ":min",
+		"avg",
+		"max",
+		"p95",
+		"trend",
+		"MEM/" + o.historyValue + ":min",
+		"avg",
+		"max",
+		"p95",
+		"trend",
+	}
+}
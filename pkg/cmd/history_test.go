@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestShowHistory(t *testing.T) {
+
+	usage := func(v int64) *int64 { return &v }
+
+	now := time.Now()
+	samples := []historySample{}
+	for i := int64(1); i <= 4; i++ {
+		samples = append(samples, historySample{
+			Time: now.Add(time.Duration(i) * time.Minute),
+			Nodes: []historyNode{
+				{Name: "node1", CPUUsed: usage(i * 100), CPURequested: 1000, MemUsed: usage(i * 1000), MemRequested: 4000},
+				{Name: "node2", CPUUsed: usage(500), CPURequested: 2000, MemUsed: usage(1000), MemRequested: 1000},
+			},
+			Namespaces: []historyNamespace{
+				{Name: "default", CPUUsed: usage(i * 10), CPURequested: 100, MemUsed: usage(1000), MemRequested: 2000},
+			},
+		})
+	}
+
+	// metrics were not available, usage is skipped but requests count
+	samples = append(samples, historySample{
+		Time: now.Add(5 * time.Minute),
+		Nodes: []historyNode{
+			{Name: "node1", CPURequested: 1000, MemRequested: 4000},
+			{Name: "node2", CPURequested: 2000, MemRequested: 1000},
+		},
+		Namespaces: []historyNamespace{
+			{Name: "default", CPURequested: 100, MemRequested: 2000},
+		},
+	})
+
+	var tests = []struct {
+		description string
+		by          string
+		value       string
+		names       []string
+		expected    []string
+	}{
+		{
+			"usage of nodes",
+			historyByNode,
+			historyValueUse,
+			[]string{},
+			[]string{
+				"node1 4 100m 250m 400m 400m ▁▃▅█ 1K 2K 4K 4K ▁▃▅█",
+				"node2 4 500m 500m 500m 500m ▁▁▁▁ 1K 1K 1K 1K ▁▁▁▁",
+				"",
+			},
+		},
+		{
+			"requests of a node",
+			historyByNode,
+			historyValueReq,
+			[]string{"node2"},
+			[]string{
+				"node2 5 2 2 2 2 ▁▁▁▁▁ 1K 1K 1K 1K ▁▁▁▁▁",
+				"",
+			},
+		},
+		{
+			"usage of namespaces",
+			historyByNamespace,
+			historyValueUse,
+			[]string{},
+			[]string{
+				"default 4 10m 25m 40m 40m ▁▃▅█ 1K 1K 1K 1K ▁▁▁▁",
+				"",
+			},
+		},
+		{
+			"unknown name",
+			historyByNode,
+			historyValueUse,
+			[]string{"node3"},
+			[]string{
+				"No samples in history.jsonl within 1h0m0s.",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:      true,
+				noHeaders:    true,
+				kByte:        true,
+				table:        table.NewOutputTable(buffer),
+				historyStore: "history.jsonl",
+				historySince: time.Hour,
+				historyBy:    test.by,
+				historyValue: test.value,
+			}
+
+			o.showHistory(samples, test.names)
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestRunHistoryValidation(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		by          string
+		value       string
+	}{
+		{"invalid group", "pod", historyValueUse},
+		{"invalid value", historyByNode, "alloc"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{historyBy: test.by, historyValue: test.value}
+			if err := o.RunHistory([]string{}); err == nil {
+				t.Errorf("[%s] unexpected error: should return err", test.description)
+			}
+		})
+	}
+}
+
+func TestHistoryCommandStoreEnv(t *testing.T) {
+
+	store := filepath.Join(t.TempDir(), "history.jsonl")
+	sample := historySample{
+		Time:  time.Now(),
+		Nodes: []historyNode{{Name: "node1", CPURequested: 100, MemRequested: 1000}},
+	}
+	if err := appendHistorySample(store, sample); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Setenv("KUBECTL_FREE_STORE", store)
+
+	tf := cmdtesting.NewTestFactory()
+	defer tf.Cleanup()
+
+	out := &bytes.Buffer{}
+	rootCmd := NewCmdFree(
+		tf,
+		genericclioptions.IOStreams{In: os.Stdin, Out: out, ErrOut: &bytes.Buffer{}},
+		"v0.0.1",
+		"abcd123",
+		"1234567890",
+	)
+
+	if _, err := executeCommand(rootCmd, "history", "--no-headers", "--value", "req"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if !strings.HasPrefix(out.String(), "node1 ") {
+		t.Errorf("expected(node1) differ (got: %q)", out.String())
+	}
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// findings of containers (--list --oom)
+	oomKilledFinding     = "OOMKilled"
+	limitPressureFinding = "LimitPressure"
+)
+
+// showOOMContainers prints containers which were killed for running out of memory
+// or whose memory usage reaches limitThreshold percent of their memory limit
+func (o *FreeOptions) showOOMContainers(ctx context.Context, nodes []v1.Node) error {
+
+	// include containers without limits, the node might have killed them
+	listAll := o.listAll
+	o.listAll = true
+	defer func() { o.listAll = listAll }()
+
+	// get pod metrics
+	podMetrics := o.getPodMetrics(ctx)
+
+	t := o.table
+	if !o.noHeaders {
+		t.Header = o.oomTableHeader()
+	}
+
+	for _, node := range nodes {
+
+		infos, err := o.getPodInfos(ctx, node, podMetrics)
+		if err != nil {
+			return err
+		}
+
+		for _, info := range o.sortEntries(infos) {
+			findings := o.getOOMFindings(info)
+			if len(findings) == 0 {
+				continue
+			}
+			t.AddRow(o.oomInfoToRow(info, findings))
+		}
+	}
+
+	if len(t.Rows) == 0 {
+		fmt.Fprintf(t.Output, "No containers killed for running out of memory or above %d%% of their memory limit.\n", o.limitThreshold)
+		return nil
+	}
+
+	t.Print()
+
+	return nil
+}
+
+// getOOMFindings returns why a container is shown by --list --oom
+func (o *FreeOptions) getOOMFindings(info podInfo) []string {
+
+	findings := []string{}
+
+	if info.containerOOMKilled {
+		findings = append(findings, oomKilledFinding)
+	}
+
+	if info.containerMemoryUsed != nil && info.containerMemoryLimit > 0 {
+		if util.GetPercentage(info.containerMemoryUsed.Value(), info.containerMemoryLimit) >= o.limitThreshold {
+			findings = append(findings, limitPressureFinding)
+		}
+	}
+
+	return findings
+}
+
+// oomInfoToRow creates a table row of a container killed or about to be killed for running out of memory
+func (o *FreeOptions) oomInfoToRow(info podInfo, findings []string) []string {
+
+	memUsed := "-"
+	memLimitPercent := "-"
+	if info.containerMemoryUsed != nil {
+		memUsed = o.toUnitOrDash(info.containerMemoryUsed.Value())
+		if info.containerMemoryLimit > 0 {
+			memLimitPercent = o.toColorPercentWithThreshold(util.GetPercentage(info.containerMemoryUsed.Value(), info.containerMemoryLimit), o.oomThreshold())
+		}
+	}
+
+	restarts := fmt.Sprintf("%d", info.containerRestarts)
+	memLimit := o.toUnitOrDash(info.containerMemoryLimit)
+	finding := strings.Join(findings, ",")
+
+	return []string{
+		info.nodeName,            // node name
+		info.podNamespace,        // namespace
+		info.podName,             // pod name
+		info.containerName,       // container name
+		restarts,                 // container restarts
+		info.containerLastReason, // last termination reason
+		memUsed,                  // mem used (from metrics)
+		memLimit,                 // mem limit
+		memLimitPercent,          // mem used % of limit
+		finding,                  // findings
+	}
+}
+
+// oomThreshold returns the thresholds of MEM/lim%, critical from --limit-threshold
+func (o *FreeOptions) oomThreshold() threshold {
+	t := threshold{warn: o.warnThreshold, crit: o.limitThreshold}
+	if t.warn > t.crit {
+		t.warn = t.crit
+	}
+	return t
+}
+
+// oomTableHeader defines table headers for --list --oom
+func (o *FreeOptions) oomTableHeader() []string {
+
+	hMEMLimP := "MEM/lim%"
+
+	if !o.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hMEMLimP) // MEM/lim%
+	}
+
+	return []string{
+		"NODE NAME",
+		"NAMESPACE",
+		"POD NAME",
+		"CONTAINER",
+		"RESTARTS",
+		"LAST REASON",
+		"MEM/use",
+		"MEM/lim",
+		hMEMLimP,
+		"FINDING",
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	color "github.com/gookit/color"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// newOOMTestSource returns pods killed for running out of memory and close to their memory limit
+func newOOMTestSource() *source.StaticSource {
+
+	container := func(name string, limit int64) v1.Container {
+		c := v1.Container{Name: name}
+		if limit > 0 {
+			c.Resources.Limits = v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(limit, resource.DecimalSI)}
+		}
+		return c
+	}
+
+	pod := func(name string, c v1.Container, status v1.ContainerStatus) v1.Pod {
+		status.Name = c.Name
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       v1.PodSpec{NodeName: "node1", Containers: []v1.Container{c}},
+			Status:     v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: []v1.ContainerStatus{status}},
+		}
+	}
+
+	usage := func(name, container string, mem int64) metricsapiv1beta1.PodMetrics {
+		return metricsapiv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{
+				{Name: container, Usage: v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(mem, resource.DecimalSI)}},
+			},
+		}
+	}
+
+	oomKilled := v1.ContainerStatus{
+		RestartCount:         3,
+		State:                v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+		LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+	}
+
+	pods := []v1.Pod{
+		pod("pressure", container("a", 10000), v1.ContainerStatus{}),
+		pod("killed", container("b", 10000), oomKilled),
+		pod("unlimited", container("c", 0), v1.ContainerStatus{}),
+	}
+	podMetrics := []metricsapiv1beta1.PodMetrics{
+		usage("pressure", "a", 9500),
+		usage("killed", "b", 1000),
+		usage("unlimited", "c", 50000),
+	}
+
+	return source.NewStaticSource("", testNodes, pods, testNodeMetrics.Items, podMetrics)
+}
+
+func TestShowOOMContainers(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		description    string
+		limitThreshold int64
+		expected       []string
+	}{
+		{
+			"default threshold",
+			90,
+			[]string{
+				"node1 default killed   b 3 OOMKilled 1K 10K 10% OOMKilled",
+				"node1 default pressure a 0 -         9K 10K 95% LimitPressure",
+				"",
+			},
+		},
+		{
+			"low threshold",
+			10,
+			[]string{
+				"node1 default killed   b 3 OOMKilled 1K 10K 10% OOMKilled,LimitPressure",
+				"node1 default pressure a 0 -         9K 10K 95% LimitPressure",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:        true,
+				noHeaders:      true,
+				kByte:          true,
+				limitThreshold: test.limitThreshold,
+				sortByResource: memorySortResource,
+				table:          table.NewOutputTable(buffer),
+				source:         newOOMTestSource(),
+			}
+
+			if err := o.showOOMContainers(ctx, []v1.Node{testNodes[0]}); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestShowFreeOOM(t *testing.T) {
+	ctx := context.Background()
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:   true,
+		noHeaders: true,
+		noMetrics: true,
+		kByte:     true,
+		oom:       true,
+		table:     table.NewOutputTable(buffer),
+		source:    newOOMTestSource(),
+	}
+
+	if err := o.showFree(ctx, testNodes); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		"node1 Ready    - - 4 0% 0% - 20K 4K 0% 500% 1",
+		"node2 NotReady - - 8 0% 0% - -   8K 0% 0%   0",
+		"",
+	}, "\n")
+	if buffer.String() != expected {
+		t.Errorf("expected(%q) differ (got: %q)", expected, buffer.String())
+	}
+}
+
+func TestOOMInfoToRowColor(t *testing.T) {
+
+	// critical threshold of MEM/lim% is --limit-threshold, not --crit-threshold
+	var tests = []struct {
+		description    string
+		warnThreshold  int64
+		limitThreshold int64
+		expected       string
+	}{
+		{"below warn", 75, 95, color.Green.Sprint("70%")},
+		{"below limit threshold", 60, 80, color.Yellow.Sprint("70%")},
+		{"above limit threshold", 75, 60, color.Red.Sprint("70%")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{
+				warnThreshold:  test.warnThreshold,
+				critThreshold:  50,
+				limitThreshold: test.limitThreshold,
+			}
+
+			info := podInfo{containerMemoryUsed: resource.NewQuantity(700, resource.DecimalSI), containerMemoryLimit: 1000}
+			actual := o.oomInfoToRow(info, nil)[8]
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/constants"
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	resourcehelper "k8s.io/kubectl/pkg/util/resource"
+)
+
+// podClasses are the classes of pods of the overhead columns
+var podClasses = []string{constants.PodClassStatic, constants.PodClassDaemonSet, constants.PodClassSystem, constants.PodClassOther}
+
+// nodeOverhead is the capacity of a node split into reserved resources and requests by class of pods
+type nodeOverhead struct {
+	name string
+
+	cpuCapacity    int64
+	cpuAllocatable int64
+	cpuRequested   map[string]int64
+
+	memCapacity    int64
+	memAllocatable int64
+	memRequested   map[string]int64
+}
+
+// cpuOverhead returns cpu not available to workloads: reserved and requested by static, DaemonSet and kube-system pods
+func (no nodeOverhead) cpuOverhead() int64 {
+	return no.cpuCapacity - no.cpuAllocatable + no.cpuRequested[constants.PodClassStatic] + no.cpuRequested[constants.PodClassDaemonSet] + no.cpuRequested[constants.PodClassSystem]
+}
+
+// memOverhead returns memory not available to workloads: reserved and requested by static, DaemonSet and kube-system pods
+func (no nodeOverhead) memOverhead() int64 {
+	return no.memCapacity - no.memAllocatable + no.memRequested[constants.PodClassStatic] + no.memRequested[constants.PodClassDaemonSet] + no.memRequested[constants.PodClassSystem]
+}
+
+// showOverhead prints capacity, reserved resources (kube-reserved, system-reserved and eviction threshold)
+// and requests of static, DaemonSet, kube-system and other pods of nodes (--overhead)
+func (o *FreeOptions) showOverhead(ctx context.Context, nodes []v1.Node) error {
+
+	if !o.noHeaders {
+		o.table.Header = o.overheadTableHeader()
+	}
+
+	for _, node := range nodes {
+		no, err := o.getNodeOverhead(ctx, node)
+		if err != nil {
+			return err
+		}
+		o.table.AddRow(o.nodeOverheadToRow(no))
+	}
+
+	o.table.Print()
+
+	return nil
+}
+
+// getNodeOverhead calculates the overhead of a node
+// Like the requests of the node table, only running pods are counted.
+func (o *FreeOptions) getNodeOverhead(ctx context.Context, node v1.Node) (nodeOverhead, error) {
+
+	no := nodeOverhead{
+		name:           node.ObjectMeta.Name,
+		cpuCapacity:    node.Status.Capacity.Cpu().MilliValue(),
+		cpuAllocatable: node.Status.Allocatable.Cpu().MilliValue(),
+		cpuRequested:   map[string]int64{},
+		memCapacity:    node.Status.Capacity.Memory().Value(),
+		memAllocatable: node.Status.Allocatable.Memory().Value(),
+		memRequested:   map[string]int64{},
+	}
+
+	pods, err := o.source.GetPods(ctx, no.name)
+	if err != nil {
+		return no, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+
+		// effective requests as seen by the scheduler, including init containers and pod overhead
+		class := util.GetPodOverheadClass(pod)
+		requests, _ := resourcehelper.PodRequestsAndLimits(&pod)
+		no.cpuRequested[class] += requests.Cpu().MilliValue()
+		no.memRequested[class] += requests.Memory().Value()
+	}
+
+	return no, nil
+}
+
+// nodeOverheadToRow creates a table row of the overhead of a node
+func (o *FreeOptions) nodeOverheadToRow(no nodeOverhead) []string {
+
+	cpuReserved := no.cpuCapacity - no.cpuAllocatable
+	memReserved := no.memCapacity - no.memAllocatable
+
+	row := []string{no.name} // node name
+
+	// cpu
+	row = append(
+		row,
+		o.toMilliUnitOrDash(no.cpuCapacity), // cpu capacity
+		o.toMilliUnitOrDash(cpuReserved),    // cpu reserved
+	)
+	for _, class := range podClasses {
+		row = append(row, o.toMilliUnitOrDash(no.cpuRequested[class])) // cpu requested by class of pods
+	}
+	row = append(row, o.toColorPercent(util.GetPercentage(no.cpuOverhead(), no.cpuCapacity))) // cpu overhead %
+
+	// mem
+	row = append(
+		row,
+		o.toUnitOrDash(no.memCapacity), // mem capacity
+		o.toUnitOrDash(memReserved),    // mem reserved
+	)
+	for _, class := range podClasses {
+		row = append(row, o.toUnitOrDash(no.memRequested[class])) // mem requested by class of pods
+	}
+	row = append(row, o.toColorPercent(util.GetPercentage(no.memOverhead(), no.memCapacity))) // mem overhead %
+
+	return row
+}
+
+// overheadTableHeader defines table headers for --overhead
+func (o *FreeOptions) overheadTableHeader() []string {
+
+	hCPUOverheadP := "CPU/overhead%"
+	hMEMOverheadP := "MEM/overhead%"
+
+	if !o.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hCPUOverheadP) // CPU/overhead%
+		util.DefaultColor(&hMEMOverheadP) // MEM/overhead%
+	}
+
+	return []string{
+		"NODE NAME",
+		"CPU/cap",
+		"CPU/rsv",
+		"CPU/static",
+		"CPU/ds",
+		"CPU/sys",
+		"CPU/other",
+		hCPUOverheadP,
+		"MEM/cap",
+		"MEM/rsv",
+		"MEM/static",
+		"MEM/ds",
+		"MEM/sys",
+		"MEM/other",
+		hMEMOverheadP,
+	}
+}
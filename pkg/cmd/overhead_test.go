@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestShowOverhead(t *testing.T) {
+	ctx := context.Background()
+
+	resources := func(cpu, mem int64) v1.ResourceList {
+		return v1.ResourceList{
+			v1.ResourceCPU:    *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+			v1.ResourceMemory: *resource.NewQuantity(mem, resource.DecimalSI),
+		}
+	}
+
+	node := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{
+			Capacity:    resources(2000, 10000),
+			Allocatable: resources(1800, 8000),
+		},
+	}
+
+	pod := func(name, namespace, ownerKind string, cpu, mem int64, phase v1.PodPhase) v1.Pod {
+		p := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1.PodSpec{
+				NodeName:   "node1",
+				Containers: []v1.Container{{Name: name, Resources: v1.ResourceRequirements{Requests: resources(cpu, mem)}}},
+			},
+			Status: v1.PodStatus{Phase: phase},
+		}
+		if ownerKind != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner"}}
+		}
+		return p
+	}
+
+	pods := []v1.Pod{
+		pod("kube-apiserver", "kube-system", "Node", 250, 1000, v1.PodRunning),
+		pod("kube-proxy", "kube-system", "DaemonSet", 100, 500, v1.PodRunning),
+		pod("node-exporter", "monitoring", "DaemonSet", 50, 500, v1.PodRunning),
+		pod("coredns", "kube-system", "ReplicaSet", 100, 1000, v1.PodRunning),
+		pod("app", "default", "ReplicaSet", 500, 2000, v1.PodRunning),
+		pod("job", "default", "Job", 500, 2000, v1.PodSucceeded),
+	}
+
+	// effective requests: the larger init container and the pod overhead count
+	app := &pods[4]
+	app.Spec.InitContainers = []v1.Container{{Name: "init", Resources: v1.ResourceRequirements{Requests: resources(800, 0)}}}
+	app.Spec.Overhead = resources(100, 1000)
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor: true,
+		kByte:   true,
+		table:   table.NewOutputTable(buffer),
+		source:  source.NewStaticSource("", []v1.Node{node}, pods, nil, nil),
+	}
+
+	if err := o.showOverhead(ctx, []v1.Node{node}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		"NODE NAME CPU/cap CPU/rsv CPU/static CPU/ds CPU/sys CPU/other CPU/overhead% MEM/cap MEM/rsv MEM/static MEM/ds MEM/sys MEM/other MEM/overhead%",
+		"node1     2       200m    250m       150m   100m    900m      35%           10K     2K      1K         1K     1K      3K        50%",
+		"",
+	}, "\n")
+	if buffer.String() != expected {
+		t.Errorf("expected(\n%s) differ (got: \n%s)", expected, buffer.String())
+	}
+}
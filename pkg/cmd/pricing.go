@@ -0,0 +1,425 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+const (
+	// cost allocation views (--cost-by)
+	costByNode      = "node"
+	costByNamespace = "namespace"
+	costByWorkload  = "workload"
+
+	// weights of the cost allocation (--cost-weight)
+	costWeightRequests = "requests"
+	costWeightMax      = "max"
+
+	// idleCostName is the row of the unallocated cost of nodes
+	idleCostName = "(idle)"
+)
+
+// priceSheet is the hourly cost of nodes (--pricing), e.g.
+//
+//	default: 0.10
+//	instanceTypes:
+//	  m5.large: 0.096
+//	  m5.xlarge: 0.192
+//	labels:
+//	  - selector: node.kubernetes.io/lifecycle=spot
+//	    price: 0.04
+//
+// Label selectors are matched in order before instance types, the default is the price of all other nodes.
+type priceSheet struct {
+	Default       *float64           `json:"default"`
+	InstanceTypes map[string]float64 `json:"instanceTypes"`
+	Labels        []priceSheetLabel  `json:"labels"`
+}
+
+// priceSheetLabel is the price of nodes matching a label selector
+type priceSheetLabel struct {
+	Selector string  `json:"selector"`
+	Price    float64 `json:"price"`
+
+	selector labels.Selector
+}
+
+// loadPriceSheet reads the price sheet
+func loadPriceSheet(path string) (*priceSheet, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open price sheet: %v", err)
+	}
+	defer f.Close()
+
+	prices := &priceSheet{}
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(prices); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read price sheet %s: %v", path, err)
+	}
+
+	for i, l := range prices.Labels {
+		selector, err := labels.Parse(l.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse selector %q of price sheet %s: %v", l.Selector, path, err)
+		}
+		prices.Labels[i].selector = selector
+	}
+
+	return prices, nil
+}
+
+// nodePrice returns the hourly cost of a node
+func (p *priceSheet) nodePrice(node v1.Node) (float64, error) {
+
+	for _, l := range p.Labels {
+		if l.selector.Matches(labels.Set(node.Labels)) {
+			return l.Price, nil
+		}
+	}
+
+	instanceType := getInstanceType(node)
+	if price, ok := p.InstanceTypes[instanceType]; ok {
+		return price, nil
+	}
+
+	if p.Default != nil {
+		return *p.Default, nil
+	}
+
+	return 0, fmt.Errorf("no price of node %s (instance type %q), add it or a default to the price sheet", node.Name, instanceType)
+}
+
+// getInstanceType returns the instance type label of a node
+func getInstanceType(node v1.Node) string {
+	if instanceType, ok := node.Labels[v1.LabelInstanceTypeStable]; ok {
+		return instanceType
+	}
+	return node.Labels[v1.LabelInstanceType]
+}
+
+// nodeCost is the hourly cost of a node and its allocation to pods
+type nodeCost struct {
+	name         string
+	instanceType string
+	price        float64
+	allocated    float64
+	pods         []podCost
+}
+
+// idle returns the cost of a node not allocated to pods
+func (nc nodeCost) idle() float64 {
+	// rounding of the allocation
+	if nc.allocated > nc.price {
+		return 0
+	}
+	return nc.price - nc.allocated
+}
+
+// podCost is the hourly cost allocated to a pod
+type podCost struct {
+	namespace string
+	workload  string
+	cost      float64
+}
+
+// costGroup is the cost of a namespace or workload
+type costGroup struct {
+	namespace string
+	workload  string
+	pods      int
+	cost      float64
+}
+
+// showCost prints the hourly cost of nodes, or the allocation of the cost to namespaces or workloads (--pricing)
+func (o *FreeOptions) showCost(ctx context.Context, nodes []v1.Node) error {
+
+	// usage is only a weight of --cost-weight max
+	var podMetrics *metricsapiv1beta1.PodMetricsList
+	if o.costWeight == costWeightMax {
+		podMetrics = o.getPodMetrics(ctx)
+	}
+
+	costs := []nodeCost{}
+	for _, node := range nodes {
+		nc, err := o.getNodeCost(ctx, node, podMetrics)
+		if err != nil {
+			return err
+		}
+		costs = append(costs, nc)
+	}
+
+	switch o.costBy {
+	case costByNamespace, costByWorkload:
+		if !o.noHeaders {
+			o.table.Header = o.costGroupTableHeader()
+		}
+		for _, row := range o.costGroupRows(costs) {
+			o.table.AddRow(row)
+		}
+	default:
+		if !o.noHeaders {
+			o.table.Header = o.nodeCostTableHeader()
+		}
+		for _, nc := range costs {
+			o.table.AddRow(o.nodeCostToRow(nc))
+		}
+		o.table.AddRow(o.nodeCostTotalToRow(costs))
+	}
+
+	o.table.Print()
+
+	return nil
+}
+
+// getNodeCost allocates the cost of a node to its running pods
+// The share of a pod is the mean of its shares of allocatable cpu and memory, weighted by requests
+// or by max(request, usage) of containers (--cost-weight). The allocation never exceeds the cost of the node.
+func (o *FreeOptions) getNodeCost(ctx context.Context, node v1.Node, podMetrics *metricsapiv1beta1.PodMetricsList) (nodeCost, error) {
+
+	nc := nodeCost{
+		name:         node.ObjectMeta.Name,
+		instanceType: getInstanceType(node),
+	}
+
+	price, err := o.prices.nodePrice(node)
+	if err != nil {
+		return nc, err
+	}
+	nc.price = price
+
+	pods, err := o.source.GetPods(ctx, nc.name)
+	if err != nil {
+		return nc, err
+	}
+
+	cpuAllocatable := node.Status.Allocatable.Cpu().MilliValue()
+	memAllocatable := node.Status.Allocatable.Memory().Value()
+
+	shares := []float64{}
+	var total float64
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+
+		var cpu, mem int64
+		for _, container := range pod.Spec.Containers {
+			containerCPU := container.Resources.Requests.Cpu().MilliValue()
+			containerMem := container.Resources.Requests.Memory().Value()
+
+			if podMetrics != nil {
+				cpuUsed, memUsed := util.GetPodContainerMetrics(podMetrics, pod.Namespace, pod.Name, container.Name)
+				if cpuUsed != nil && cpuUsed.MilliValue() > containerCPU {
+					containerCPU = cpuUsed.MilliValue()
+				}
+				if memUsed != nil && memUsed.Value() > containerMem {
+					containerMem = memUsed.Value()
+				}
+			}
+
+			cpu += containerCPU
+			mem += containerMem
+		}
+
+		share := (resourceShare(cpu, cpuAllocatable) + resourceShare(mem, memAllocatable)) / 2
+		shares = append(shares, share)
+		total += share
+		nc.pods = append(nc.pods, podCost{
+			namespace: pod.Namespace,
+			workload:  util.GetPodOwner(pod),
+		})
+	}
+
+	// usage above allocatable resources, split the whole node
+	if total > 1 {
+		for i := range shares {
+			shares[i] /= total
+		}
+	}
+
+	for i, share := range shares {
+		nc.pods[i].cost = nc.price * share
+		nc.allocated += nc.pods[i].cost
+	}
+
+	return nc, nil
+}
+
+// resourceShare returns the share of allocatable resources
+func resourceShare(i, allocatable int64) float64 {
+	if allocatable == 0 {
+		return 0
+	}
+	return float64(i) / float64(allocatable)
+}
+
+// costGroupRows sums the cost of pods by namespace or workload (--cost-by) sorted by cost
+// The idle cost of all nodes and the total are the last rows.
+func (o *FreeOptions) costGroupRows(costs []nodeCost) [][]string {
+
+	groups := map[string]*costGroup{}
+	var price, idle float64
+	for _, nc := range costs {
+		price += nc.price
+		idle += nc.idle()
+
+		for _, pc := range nc.pods {
+			key := pc.namespace
+			if o.costBy == costByWorkload {
+				key += "/" + pc.workload
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &costGroup{namespace: pc.namespace, workload: pc.workload}
+				groups[key] = g
+			}
+			g.pods++
+			g.cost += pc.cost
+		}
+	}
+
+	sorted := []*costGroup{}
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	slices.SortFunc(sorted, func(a, b *costGroup) bool {
+		if a.cost != b.cost {
+			return a.cost > b.cost
+		}
+		return a.namespace+"/"+a.workload < b.namespace+"/"+b.workload
+	})
+
+	rows := [][]string{}
+	for _, g := range sorted {
+		rows = append(rows, o.costGroupToRow(g.namespace, g.workload, fmt.Sprintf("%d", g.pods), g.cost, price))
+	}
+	rows = append(rows, o.costGroupToRow(idleCostName, "-", "-", idle, price))
+	rows = append(rows, o.costGroupToRow("total", "-", "-", price, price))
+
+	return rows
+}
+
+// costGroupToRow creates a table row of the cost of a namespace or workload
+func (o *FreeOptions) costGroupToRow(namespace, workload, pods string, cost, price float64) []string {
+
+	row := []string{namespace} // namespace
+	if o.costBy == costByWorkload {
+		row = append(row, workload) // workload
+	}
+
+	share := costShare(cost, price)
+
+	return append(
+		row,
+		pods,                     // pod count
+		formatCost(cost),         // cost per hour
+		formatCostPercent(share), // share of the cost of nodes
+	)
+}
+
+// nodeCostToRow creates a table row of the cost of a node
+func (o *FreeOptions) nodeCostToRow(nc nodeCost) []string {
+
+	instanceType := nc.instanceType
+	if instanceType == "" {
+		instanceType = "-"
+	}
+
+	idleShare := costShare(nc.idle(), nc.price)
+
+	return []string{
+		nc.name,                      // node name
+		instanceType,                 // instance type
+		formatCost(nc.price),         // cost per hour
+		formatCost(nc.allocated),     // allocated cost per hour
+		formatCost(nc.idle()),        // idle cost per hour
+		formatCostPercent(idleShare), // idle %
+	}
+}
+
+// nodeCostTotalToRow creates a table row of the cost of all nodes
+func (o *FreeOptions) nodeCostTotalToRow(costs []nodeCost) []string {
+
+	total := nodeCost{name: "total", instanceType: "-"}
+	for _, nc := range costs {
+		total.price += nc.price
+		total.allocated += nc.allocated
+	}
+
+	return o.nodeCostToRow(total)
+}
+
+// costShare returns a cost as percentage of the price
+func costShare(cost, price float64) float64 {
+	if price == 0 {
+		return 0
+	}
+	return cost / price * 100
+}
+
+// formatCost returns a cost with two decimals
+func formatCost(cost float64) string {
+	return fmt.Sprintf("%.2f", cost)
+}
+
+// formatCostPercent returns a percentage without decimals
+func formatCostPercent(p float64) string {
+	return fmt.Sprintf("%.0f%%", p)
+}
+
+// nodeCostTableHeader defines table headers for --pricing
+func (o *FreeOptions) nodeCostTableHeader() []string {
+	return []string{
+		"NODE NAME",
+		"INSTANCE TYPE",
+		"COST/h",
+		"ALLOC/h",
+		"IDLE/h",
+		"IDLE%",
+	}
+}
+
+// costGroupTableHeader defines table headers for --pricing --cost-by namespace/workload
+func (o *FreeOptions) costGroupTableHeader() []string {
+
+	header := []string{"NAMESPACE"}
+	if o.costBy == costByWorkload {
+		header = append(header, "WORKLOAD")
+	}
+
+	return append(header, "PODS", "COST/h", "SHARE%")
+}
+
+// validateCost validates --pricing, --cost-by and --cost-weight
+func (o *FreeOptions) validateCost() error {
+
+	if o.pricingFile == "" {
+		return nil
+	}
+
+	if o.costBy != costByNode && o.costBy != costByNamespace && o.costBy != costByWorkload {
+		return fmt.Errorf("can only allocate cost by %q, %q and %q, not by given %q", costByNode, costByNamespace, costByWorkload, o.costBy)
+	}
+	if o.costWeight != costWeightRequests && o.costWeight != costWeightMax {
+		return fmt.Errorf("can only weight cost by %q and %q, not by given %q", costWeightRequests, costWeightMax, o.costWeight)
+	}
+
+	// --pricing is another view of nodes
+	if o.list || o.evictionRisk || o.overhead || o.output == treeOutputFormat {
+		return fmt.Errorf("--pricing can't be used with --list, --eviction-risk, --overhead or -o tree")
+	}
+
+	return nil
+}
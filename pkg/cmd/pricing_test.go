@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+const testPriceSheet = `
+default: 0.10
+instanceTypes:
+  m5.large: 1.00
+labels:
+  - selector: lifecycle=spot
+    price: 0.50
+`
+
+func writeTestPriceSheet(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "prices.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write price sheet: %v", err)
+	}
+	return path
+}
+
+func TestNodePrice(t *testing.T) {
+
+	prices, err := loadPriceSheet(writeTestPriceSheet(t, testPriceSheet))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	node := func(labels map[string]string) v1.Node {
+		return v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: labels}}
+	}
+
+	var tests = []struct {
+		description string
+		node        v1.Node
+		expected    float64
+	}{
+		{"instance type", node(map[string]string{v1.LabelInstanceTypeStable: "m5.large"}), 1.00},
+		{"beta instance type", node(map[string]string{v1.LabelInstanceType: "m5.large"}), 1.00},
+		{"label before instance type", node(map[string]string{v1.LabelInstanceTypeStable: "m5.large", "lifecycle": "spot"}), 0.50},
+		{"default", node(map[string]string{v1.LabelInstanceTypeStable: "m5.xlarge"}), 0.10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual, err := prices.nodePrice(test.node)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+
+	t.Run("no price", func(t *testing.T) {
+		prices := &priceSheet{}
+		_, err := prices.nodePrice(node(map[string]string{v1.LabelInstanceTypeStable: "m5.large"}))
+		expected := `no price of node node1 (instance type "m5.large"), add it or a default to the price sheet`
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected(%s) differ (got: %v)", expected, err)
+		}
+	})
+}
+
+func TestLoadPriceSheet(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		content     string
+		expected    string
+	}{
+		{"invalid yaml", "instanceTypes: [", "failed to read price sheet"},
+		{"invalid selector", "labels:\n  - selector: 'a in ('\n    price: 1", `failed to parse selector "a in ("`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			_, err := loadPriceSheet(writeTestPriceSheet(t, test.content))
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+			}
+		})
+	}
+}
+
+func TestShowCost(t *testing.T) {
+	ctx := context.Background()
+
+	resources := func(cpu, mem int64) v1.ResourceList {
+		return v1.ResourceList{
+			v1.ResourceCPU:    *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+			v1.ResourceMemory: *resource.NewQuantity(mem, resource.DecimalSI),
+		}
+	}
+
+	nodes := []v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{v1.LabelInstanceTypeStable: "m5.large"}},
+			Status:     v1.NodeStatus{Allocatable: resources(2000, 8000)},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{v1.LabelInstanceTypeStable: "m5.large", "lifecycle": "spot"}},
+			Status:     v1.NodeStatus{Allocatable: resources(1000, 4000)},
+		},
+	}
+
+	controller := true
+	pod := func(node, namespace, name, ownerKind, ownerName string, cpu, mem int64, phase v1.PodPhase) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       namespace,
+				Labels:          map[string]string{"pod-template-hash": "abc"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}},
+			},
+			Spec: v1.PodSpec{
+				NodeName:   node,
+				Containers: []v1.Container{{Name: "c", Resources: v1.ResourceRequirements{Requests: resources(cpu, mem)}}},
+			},
+			Status: v1.PodStatus{Phase: phase},
+		}
+	}
+
+	pods := []v1.Pod{
+		pod("node1", "default", "app-abc-1", "ReplicaSet", "app-abc", 500, 2000, v1.PodRunning),
+		pod("node1", "default", "app-abc-2", "ReplicaSet", "app-abc", 500, 2000, v1.PodRunning),
+		pod("node1", "data", "db-0", "StatefulSet", "db", 1000, 0, v1.PodRunning),
+		pod("node1", "default", "job", "Job", "job", 1000, 4000, v1.PodSucceeded),
+		pod("node2", "default", "web-0", "StatefulSet", "web", 500, 2000, v1.PodRunning),
+	}
+
+	podMetrics := []metricsapiv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "data"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{{Name: "c", Usage: resources(1500, 2000)}},
+		},
+		// pod of the same name in another namespace
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "other"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{{Name: "c", Usage: resources(1000, 4000)}},
+		},
+	}
+
+	prices, err := loadPriceSheet(writeTestPriceSheet(t, testPriceSheet))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var tests = []struct {
+		description string
+		costBy      string
+		costWeight  string
+		expected    []string
+	}{
+		{
+			"by node",
+			costByNode,
+			costWeightRequests,
+			[]string{
+				"NODE NAME INSTANCE TYPE COST/h ALLOC/h IDLE/h IDLE%",
+				"node1     m5.large      1.00   0.75    0.25   25%",
+				"node2     m5.large      0.50   0.25    0.25   50%",
+				"total     -             1.50   1.00    0.50   33%",
+			},
+		},
+		{
+			"by node weighted by usage",
+			costByNode,
+			costWeightMax,
+			[]string{
+				"NODE NAME INSTANCE TYPE COST/h ALLOC/h IDLE/h IDLE%",
+				"node1     m5.large      1.00   1.00    0.00   0%",
+				"node2     m5.large      0.50   0.25    0.25   50%",
+				"total     -             1.50   1.25    0.25   17%",
+			},
+		},
+		{
+			"by namespace",
+			costByNamespace,
+			costWeightRequests,
+			[]string{
+				"NAMESPACE PODS COST/h SHARE%",
+				"default   3    0.75   50%",
+				"data      1    0.25   17%",
+				"(idle)    -    0.50   33%",
+				"total     -    1.50   100%",
+			},
+		},
+		{
+			"by workload",
+			costByWorkload,
+			costWeightRequests,
+			[]string{
+				"NAMESPACE WORKLOAD        PODS COST/h SHARE%",
+				"default   Deployment/app  2    0.50   33%",
+				"data      StatefulSet/db  1    0.25   17%",
+				"default   StatefulSet/web 1    0.25   17%",
+				"(idle)    -               -    0.50   33%",
+				"total     -               -    1.50   100%",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:    true,
+				table:      table.NewOutputTable(buffer),
+				source:     source.NewStaticSource("", nodes, pods, nil, podMetrics),
+				prices:     prices,
+				costBy:     test.costBy,
+				costWeight: test.costWeight,
+			}
+
+			if err := o.showCost(ctx, nodes); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(append(test.expected, ""), "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(\n%s) differ (got: \n%s)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestValidateCost(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		o           *FreeOptions
+		expected    string
+	}{
+		{
+			"invalid cost by",
+			&FreeOptions{pricingFile: "prices.yaml", costBy: "team", costWeight: costWeightRequests},
+			`can only allocate cost by "node", "namespace" and "workload", not by given "team"`,
+		},
+		{
+			"invalid cost weight",
+			&FreeOptions{pricingFile: "prices.yaml", costBy: costByNode, costWeight: "usage"},
+			`can only weight cost by "requests" and "max", not by given "usage"`,
+		},
+		{
+			"with list",
+			&FreeOptions{pricingFile: "prices.yaml", costBy: costByNode, costWeight: costWeightRequests, list: true},
+			"--pricing can't be used with --list, --eviction-risk, --overhead or -o tree",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			err := test.o.validateCost()
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// limitRangerAnnotation lists requests and limits of containers set by the LimitRanger admission plugin
+	// e.g. "LimitRanger plugin set: cpu, memory request for container app; cpu limit for container app"
+	limitRangerAnnotation = "kubernetes.io/limit-ranger"
+
+	// limitRangerPrefix is the prefix of limitRangerAnnotation
+	limitRangerPrefix = "LimitRanger plugin set: "
+)
+
+var (
+	// quotaLong defines long description
+	quotaLong = templates.LongDesc(`
+		Show hard limits of resource quotas of namespaces against their used values and the actual usage of containers.
+
+		Containers whose requests or limits were set by the defaults of a limit range are listed below.
+	`)
+
+	// quotaExample defines command examples
+	quotaExample = templates.Examples(`
+		# Show resource quotas and defaulted containers of all namespaces.
+		kubectl free quota
+
+		# Show resource quotas and defaulted containers of a namespace.
+		kubectl free quota -n team-a
+	`)
+)
+
+// quotaUsage is the actual usage of containers of a namespace
+type quotaUsage struct {
+	cpuUsed int64
+	memUsed int64
+}
+
+// quotaInfo is a resource of a resource quota
+type quotaInfo struct {
+	namespace string
+	name      string
+	resource  v1.ResourceName
+	hard      int64
+	used      int64
+
+	// usage is the actual usage of containers or -1 if the resource or metrics have no usage
+	usage int64
+}
+
+// defaultedContainer is a container with requests or limits set by a limit range
+type defaultedContainer struct {
+	namespace   string
+	podName     string
+	name        string
+	limitRanges []string
+	defaulted   []string
+	cpuRequest  int64
+	cpuLimit    int64
+	memRequest  int64
+	memLimit    int64
+}
+
+// NewCmdQuota is a cobra command wrapping quota
+func NewCmdQuota(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "quota",
+		Short:   "Show resource quotas and limit range defaults of namespaces against requests and usage.",
+		Long:    quotaLong,
+		Example: quotaExample,
+		Args:    cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunQuota())
+		},
+	}
+
+	return cmd
+}
+
+// RunQuota prints resource quotas and defaulted containers
+func (o *FreeOptions) RunQuota() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	nodes, err := o.source.GetNodes(ctx, nil, o.labelSelector)
+	if err != nil {
+		return err
+	}
+
+	return o.showQuota(ctx, nodes)
+}
+
+// showQuota prints resource quotas of namespaces and containers defaulted by limit ranges
+func (o *FreeOptions) showQuota(ctx context.Context, nodes []v1.Node) error {
+
+	if o.client == nil {
+		return fmt.Errorf("resource quotas require access to the cluster")
+	}
+
+	quotas, err := o.client.CoreV1().ResourceQuotas(o.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list resource quotas: %v", err)
+	}
+
+	limitRanges, err := o.client.CoreV1().LimitRanges(o.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list limit ranges: %v", err)
+	}
+
+	// several namespaces (-n team-a,team-b) and --exclude-namespace are selected from all namespaces
+	quotaItems := []v1.ResourceQuota{}
+	for _, quota := range quotas.Items {
+		if o.podFilter == nil || o.podFilter.MatchesNamespace(quota.Namespace) {
+			quotaItems = append(quotaItems, quota)
+		}
+	}
+	limitRangeItems := []v1.LimitRange{}
+	for _, limitRange := range limitRanges.Items {
+		if o.podFilter == nil || o.podFilter.MatchesNamespace(limitRange.Namespace) {
+			limitRangeItems = append(limitRangeItems, limitRange)
+		}
+	}
+
+	usages, containers, err := o.getQuotaUsages(ctx, nodes, getDefaultLimitRanges(limitRangeItems))
+	if err != nil {
+		return err
+	}
+
+	out := o.table.Output
+
+	quotaTable := table.NewOutputTable(out)
+	if !o.noHeaders {
+		quotaTable.Header = o.quotaTableHeader()
+	}
+	for _, info := range getQuotaInfos(quotaItems, usages) {
+		quotaTable.AddRow(o.quotaInfoToRow(info))
+	}
+
+	containerTable := table.NewOutputTable(out)
+	if !o.noHeaders {
+		containerTable.Header = o.defaultedContainerTableHeader()
+	}
+	for _, c := range containers {
+		containerTable.AddRow(o.defaultedContainerToRow(c))
+	}
+
+	if len(quotaTable.Rows) == 0 && len(containerTable.Rows) == 0 {
+		fmt.Fprintln(out, "No resource quotas or defaulted containers.")
+		return nil
+	}
+
+	if len(quotaTable.Rows) > 0 {
+		quotaTable.Print()
+	}
+	if len(quotaTable.Rows) > 0 && len(containerTable.Rows) > 0 {
+		fmt.Fprintln(out)
+	}
+	if len(containerTable.Rows) > 0 {
+		containerTable.Print()
+	}
+
+	return nil
+}
+
+// getQuotaUsages returns the actual usage of containers by namespace and containers defaulted by limit ranges
+// Like resource quotas, only pods which are not terminated are counted. Usages are nil without metrics.
+func (o *FreeOptions) getQuotaUsages(ctx context.Context, nodes []v1.Node, limitRanges map[string][]string) (map[string]*quotaUsage, []defaultedContainer, error) {
+
+	podMetrics := o.getPodMetrics(ctx)
+
+	var usages map[string]*quotaUsage
+	if podMetrics != nil {
+		usages = map[string]*quotaUsage{}
+	}
+	containers := []defaultedContainer{}
+	for _, node := range nodes {
+		pods, err := o.source.GetPods(ctx, node.ObjectMeta.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, pod := range pods.Items {
+			if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+				continue
+			}
+
+			u, ok := usages[pod.Namespace]
+			if !ok && usages != nil {
+				u = &quotaUsage{}
+				usages[pod.Namespace] = u
+			}
+
+			defaulted := parseLimitRangerAnnotation(pod.Annotations[limitRangerAnnotation])
+			for _, container := range pod.Spec.Containers {
+				if u != nil {
+					cpu, mem := util.GetPodContainerMetrics(podMetrics, pod.Namespace, pod.Name, container.Name)
+					if cpu != nil {
+						u.cpuUsed += cpu.MilliValue()
+					}
+					if mem != nil {
+						u.memUsed += mem.Value()
+					}
+				}
+
+				if fields, ok := defaulted[container.Name]; ok {
+					containers = append(containers, defaultedContainer{
+						namespace:   pod.Namespace,
+						podName:     pod.Name,
+						name:        container.Name,
+						limitRanges: limitRanges[pod.Namespace],
+						defaulted:   fields,
+						cpuRequest:  container.Resources.Requests.Cpu().MilliValue(),
+						cpuLimit:    container.Resources.Limits.Cpu().MilliValue(),
+						memRequest:  container.Resources.Requests.Memory().Value(),
+						memLimit:    container.Resources.Limits.Memory().Value(),
+					})
+				}
+			}
+		}
+	}
+
+	return usages, containers, nil
+}
+
+// getQuotaInfos returns the resources of resource quotas sorted by namespace, quota and resource
+func getQuotaInfos(quotas []v1.ResourceQuota, usages map[string]*quotaUsage) []quotaInfo {
+
+	infos := []quotaInfo{}
+	for _, quota := range quotas {
+		u, ok := usages[quota.Namespace]
+		if !ok {
+			u = &quotaUsage{}
+		}
+		metrics := usages != nil
+
+		for name, hard := range quota.Status.Hard {
+			used := quota.Status.Used[name]
+			info := quotaInfo{
+				namespace: quota.Namespace,
+				name:      quota.Name,
+				resource:  name,
+				hard:      hard.Value(),
+				used:      used.Value(),
+				usage:     -1,
+			}
+
+			switch {
+			case isCPUQuota(name):
+				info.hard = hard.MilliValue()
+				info.used = used.MilliValue()
+				if metrics && isUsageQuota(name) {
+					info.usage = u.cpuUsed
+				}
+			case metrics && isMemoryQuota(name) && isUsageQuota(name):
+				info.usage = u.memUsed
+			}
+
+			infos = append(infos, info)
+		}
+	}
+
+	slices.SortFunc(infos, func(a, b quotaInfo) bool {
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.resource < b.resource
+	})
+
+	return infos
+}
+
+// isCPUQuota reports whether a quota resource is cpu (cpu, requests.cpu, limits.cpu)
+func isCPUQuota(name v1.ResourceName) bool {
+	return name == v1.ResourceCPU || strings.HasSuffix(string(name), "."+string(v1.ResourceCPU))
+}
+
+// isMemoryQuota reports whether a quota resource is bytes (memory, storage and ephemeral storage)
+func isMemoryQuota(name v1.ResourceName) bool {
+	return strings.HasSuffix(string(name), string(v1.ResourceMemory)) || strings.HasSuffix(string(name), string(v1.ResourceStorage))
+}
+
+// isUsageQuota reports whether the actual usage of containers relates to a quota resource
+// Ephemeral storage and storage of claims are not covered by metrics-server.
+func isUsageQuota(name v1.ResourceName) bool {
+	switch name {
+	case v1.ResourceCPU, v1.ResourceRequestsCPU, v1.ResourceLimitsCPU, v1.ResourceMemory, v1.ResourceRequestsMemory, v1.ResourceLimitsMemory:
+		return true
+	}
+	return false
+}
+
+// getDefaultLimitRanges returns names of limit ranges with container defaults by namespace
+func getDefaultLimitRanges(limitRanges []v1.LimitRange) map[string][]string {
+
+	names := map[string][]string{}
+	for _, lr := range limitRanges {
+		for _, limit := range lr.Spec.Limits {
+			if limit.Type == v1.LimitTypeContainer && (len(limit.Default) > 0 || len(limit.DefaultRequest) > 0) {
+				names[lr.Namespace] = append(names[lr.Namespace], lr.Name)
+				break
+			}
+		}
+	}
+
+	for _, n := range names {
+		slices.Sort(n)
+	}
+
+	return names
+}
+
+// parseLimitRangerAnnotation returns requests and limits set by the LimitRanger admission plugin by container
+// e.g. {"app": ["cpu request", "memory request", "cpu limit"]}, init containers are ignored.
+func parseLimitRangerAnnotation(annotation string) map[string][]string {
+
+	defaulted := map[string][]string{}
+
+	s, ok := strings.CutPrefix(annotation, limitRangerPrefix)
+	if !ok {
+		return defaulted
+	}
+
+	for _, entry := range strings.Split(s, "; ") {
+		for _, kind := range []string{"request", "limit"} {
+			resources, container, found := strings.Cut(entry, " "+kind+" for container ")
+			if !found {
+				continue
+			}
+			for _, resource := range strings.Split(resources, ", ") {
+				defaulted[container] = append(defaulted[container], resource+" "+kind)
+			}
+		}
+	}
+
+	return defaulted
+}
+
+// quotaInfoToRow creates a table row of a resource of a resource quota
+func (o *FreeOptions) quotaInfoToRow(info quotaInfo) []string {
+
+	format := func(i int64) string {
+		switch {
+		case isCPUQuota(info.resource):
+			return o.toMilliUnitOrDash(i)
+		case isMemoryQuota(info.resource):
+			return o.toUnitOrDash(i)
+		default:
+			return fmt.Sprintf("%d", i)
+		}
+	}
+
+	usage := "-"
+	usagePercent := "-"
+	if info.usage >= 0 {
+		usage = format(info.usage)
+		usagePercent = o.toColorPercent(util.GetPercentage(info.usage, info.hard))
+	}
+
+	usedPercent := util.GetPercentage(info.used, info.hard)
+
+	return []string{
+		info.namespace,                // namespace
+		info.name,                     // resource quota name
+		string(info.resource),         // resource
+		format(info.hard),             // hard limit
+		format(info.used),             // used
+		o.toColorPercent(usedPercent), // used %
+		usage,                         // actual usage of containers
+		usagePercent,                  // actual usage %
+	}
+}
+
+// defaultedContainerToRow creates a table row of a container defaulted by a limit range
+func (o *FreeOptions) defaultedContainerToRow(c defaultedContainer) []string {
+
+	limitRanges := "-"
+	if len(c.limitRanges) > 0 {
+		limitRanges = strings.Join(c.limitRanges, ",")
+	}
+
+	return []string{
+		c.namespace,                       // namespace
+		c.podName,                         // pod name
+		c.name,                            // container name
+		limitRanges,                       // limit ranges with defaults
+		strings.Join(c.defaulted, ","),    // defaulted requests and limits
+		o.toMilliUnitOrDash(c.cpuRequest), // cpu requested
+		o.toMilliUnitOrDash(c.cpuLimit),   // cpu limit
+		o.toUnitOrDash(c.memRequest),      // memory requested
+		o.toUnitOrDash(c.memLimit),        // memory limit
+	}
+}
+
+// quotaTableHeader defines table headers for resource quotas
+func (o *FreeOptions) quotaTableHeader() []string {
+
+	hUsedP := "USED%"
+	hUsageP := "USAGE%"
+
+	if !o.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hUsedP)  // USED%
+		util.DefaultColor(&hUsageP) // USAGE%
+	}
+
+	return []string{
+		"NAMESPACE",
+		"QUOTA",
+		"RESOURCE",
+		"HARD",
+		"USED",
+		hUsedP,
+		"USAGE",
+		hUsageP,
+	}
+}
+
+// defaultedContainerTableHeader defines table headers for containers defaulted by limit ranges
+func (o *FreeOptions) defaultedContainerTableHeader() []string {
+	return []string{
+		"NAMESPACE",
+		"POD NAME",
+		"CONTAINER NAME",
+		"LIMITRANGE",
+		"DEFAULTED",
+		"CPU/req",
+		"CPU/lim",
+		"MEM/req",
+		"MEM/lim",
+	}
+}
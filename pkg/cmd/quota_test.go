@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fake "k8s.io/client-go/kubernetes/fake"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestShowQuota(t *testing.T) {
+	ctx := context.Background()
+
+	objects := []runtime.Object{
+		&v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "default"},
+			Status: v1.ResourceQuotaStatus{
+				Hard: v1.ResourceList{
+					v1.ResourceRequestsCPU:    resource.MustParse("2"),
+					v1.ResourceLimitsMemory:   resource.MustParse("10k"),
+					v1.ResourcePods:           resource.MustParse("10"),
+					v1.ResourceRequestsMemory: resource.MustParse("4k"),
+				},
+				Used: v1.ResourceList{
+					v1.ResourceRequestsCPU:    resource.MustParse("1500m"),
+					v1.ResourceLimitsMemory:   resource.MustParse("9k"),
+					v1.ResourcePods:           resource.MustParse("2"),
+					v1.ResourceRequestsMemory: resource.MustParse("2k"),
+				},
+			},
+		},
+		&v1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "default"},
+			Spec: v1.LimitRangeSpec{
+				Limits: []v1.LimitRangeItem{
+					{Type: v1.LimitTypeContainer, DefaultRequest: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}},
+				},
+			},
+		},
+	}
+
+	pods := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod1",
+				Namespace:   "default",
+				Annotations: map[string]string{limitRangerAnnotation: "LimitRanger plugin set: cpu request for container app"},
+			},
+			Spec: v1.PodSpec{
+				NodeName: "node1",
+				Containers: []v1.Container{
+					{Name: "app", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}}},
+					{Name: "sidecar"},
+				},
+			},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "default"},
+			Spec:       v1.PodSpec{NodeName: "node1", Containers: []v1.Container{{Name: "job"}}},
+			Status:     v1.PodStatus{Phase: v1.PodSucceeded},
+		},
+	}
+
+	podMetrics := []metricsapiv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{
+				{Name: "app", Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse("300m"), v1.ResourceMemory: resource.MustParse("1k")}},
+				{Name: "sidecar", Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse("200m"), v1.ResourceMemory: resource.MustParse("1k")}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "default"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{
+				{Name: "job", Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1k")}},
+			},
+		},
+		// pod of the same name in another namespace
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "other"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{
+				{Name: "app", Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1k")}},
+			},
+		},
+	}
+
+	var tests = []struct {
+		description string
+		noMetrics   bool
+		expected    []string
+	}{
+		{
+			"with metrics",
+			false,
+			[]string{
+				"default compute limits.memory   10K 9K    90% 2K   20%",
+				"default compute pods            10  2     20% -    -",
+				"default compute requests.cpu    2   1500m 75% 500m 25%",
+				"default compute requests.memory 4K  2K    50% 2K   50%",
+				"",
+				"default pod1 app defaults cpu request 100m - - -",
+				"",
+			},
+		},
+		{
+			"without metrics",
+			true,
+			[]string{
+				"default compute limits.memory   10K 9K    90% - -",
+				"default compute pods            10  2     20% - -",
+				"default compute requests.cpu    2   1500m 75% - -",
+				"default compute requests.memory 4K  2K    50% - -",
+				"",
+				"default pod1 app defaults cpu request 100m - - -",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:   true,
+				noHeaders: true,
+				noMetrics: test.noMetrics,
+				kByte:     true,
+				table:     table.NewOutputTable(buffer),
+				client:    fake.NewSimpleClientset(objects...),
+				source:    source.NewStaticSource("", testNodes, pods, nil, podMetrics),
+			}
+
+			if err := o.showQuota(ctx, testNodes); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+
+	t.Run("no quotas", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		o := &FreeOptions{
+			table:  table.NewOutputTable(buffer),
+			client: fake.NewSimpleClientset(),
+			source: source.NewStaticSource("", testNodes, nil, nil, nil),
+		}
+
+		if err := o.showQuota(ctx, testNodes); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		expected := "No resource quotas or defaulted containers.\n"
+		if buffer.String() != expected {
+			t.Errorf("expected(%q) differ (got: %q)", expected, buffer.String())
+		}
+	})
+
+	t.Run("other namespaces", func(t *testing.T) {
+		podFilter, err := source.NewPodFilter("", "", []string{"team-a", "team-b"}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		buffer := &bytes.Buffer{}
+		o := &FreeOptions{
+			table:     table.NewOutputTable(buffer),
+			client:    fake.NewSimpleClientset(objects...),
+			podFilter: podFilter,
+		}
+		o.source = o.withPodFilter(source.NewStaticSource("", testNodes, pods, nil, nil))
+
+		if err := o.showQuota(ctx, testNodes); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		expected := "No resource quotas or defaulted containers.\n"
+		if buffer.String() != expected {
+			t.Errorf("expected(%q) differ (got: %q)", expected, buffer.String())
+		}
+	})
+
+	t.Run("no cluster access", func(t *testing.T) {
+		o := &FreeOptions{table: table.NewOutputTable(&bytes.Buffer{})}
+		if err := o.showQuota(ctx, testNodes); err == nil {
+			t.Errorf("unexpected error: should return err")
+		}
+	})
+}
+
+func TestParseLimitRangerAnnotation(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		annotation  string
+		expected    map[string][]string
+	}{
+		{
+			"requests and limits",
+			"LimitRanger plugin set: cpu, memory request for container app; cpu limit for container app; memory limit for container sidecar",
+			map[string][]string{"app": {"cpu request", "memory request", "cpu limit"}, "sidecar": {"memory limit"}},
+		},
+		{
+			"init container",
+			"LimitRanger plugin set: cpu request for init container init",
+			map[string][]string{},
+		},
+		{
+			"no annotation",
+			"",
+			map[string][]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := parseLimitRangerAnnotation(test.annotation)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
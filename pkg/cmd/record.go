@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// maxHistoryLineSize is the maximum size of a sample in the history store
+const maxHistoryLineSize = 64 * 1024 * 1024
+
+var (
+	// recordLong defines long description
+	recordLong = templates.LongDesc(`
+		Append samples of node and namespace resources to a local history store.
+
+		By default a single sample is recorded, so it can be run by cron.
+		Use "kubectl free history" to show trends of the recorded samples.
+	`)
+
+	// recordExample defines command examples
+	recordExample = templates.Examples(`
+		# Record a sample every 5 minutes (crontab).
+		*/5 * * * * kubectl free record --all-namespaces
+
+		# Record a sample every minute until interrupted.
+		kubectl free record --count 0 --interval 1m
+
+		# Record samples to a custom store.
+		kubectl free record --store /var/lib/kubectl-free/history.jsonl
+	`)
+)
+
+// historySample is a sample of node and namespace resources in the history store
+type historySample struct {
+	Time       time.Time          `json:"time"`
+	Nodes      []historyNode      `json:"nodes"`
+	Namespaces []historyNamespace `json:"namespaces"`
+}
+
+// historyNode is the json representation of nodeFree in the history store
+// Usage is nil if no metrics are available for the node.
+type historyNode struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	CPUUsed        *int64 `json:"cpuUsed,omitempty"`
+	CPURequested   int64  `json:"cpuRequested"`
+	CPULimited     int64  `json:"cpuLimited"`
+	CPUAllocatable int64  `json:"cpuAllocatable"`
+	MemUsed        *int64 `json:"memUsed,omitempty"`
+	MemRequested   int64  `json:"memRequested"`
+	MemLimited     int64  `json:"memLimited"`
+	MemAllocatable int64  `json:"memAllocatable"`
+	PodCount       int    `json:"podCount"`
+	PodAllocatable int64  `json:"podAllocatable"`
+	ContainerCount int    `json:"containerCount"`
+}
+
+// historyNamespace is the json representation of namespaceFree
+// Usage is nil if no metrics are available for containers of the namespace.
+type historyNamespace struct {
+	Name           string `json:"name"`
+	CPUUsed        *int64 `json:"cpuUsed,omitempty"`
+	CPURequested   int64  `json:"cpuRequested"`
+	CPULimited     int64  `json:"cpuLimited"`
+	MemUsed        *int64 `json:"memUsed,omitempty"`
+	MemRequested   int64  `json:"memRequested"`
+	MemLimited     int64  `json:"memLimited"`
+	PodCount       int    `json:"podCount"`
+	ContainerCount int    `json:"containerCount"`
+}
+
+// newHistorySample converts a snapshot to a historySample with namespace totals
+func newHistorySample(s *snapshot) historySample {
+
+	namespaces := aggregateNamespaces(s.Containers)
+	names := maps.Keys(namespaces)
+	slices.Sort(names)
+
+	sample := historySample{
+		Time:       s.Time,
+		Nodes:      []historyNode{},
+		Namespaces: []historyNamespace{},
+	}
+	for _, n := range s.Nodes {
+		sample.Nodes = append(sample.Nodes, historyNode{
+			Name:           n.Name,
+			Status:         n.Status,
+			CPUUsed:        historyUsage(n.CPUUsed, n.hasUsage),
+			CPURequested:   n.CPURequested,
+			CPULimited:     n.CPULimited,
+			CPUAllocatable: n.CPUAllocatable,
+			MemUsed:        historyUsage(n.MemUsed, n.hasUsage),
+			MemRequested:   n.MemRequested,
+			MemLimited:     n.MemLimited,
+			MemAllocatable: n.MemAllocatable,
+			PodCount:       n.PodCount,
+			PodAllocatable: n.PodAllocatable,
+			ContainerCount: n.ContainerCount,
+		})
+	}
+	for _, name := range names {
+		nf := namespaces[name]
+		sample.Namespaces = append(sample.Namespaces, historyNamespace{
+			Name:           name,
+			CPUUsed:        historyUsage(nf.cpuUsed, nf.hasUsage),
+			CPURequested:   nf.cpuRequested,
+			CPULimited:     nf.cpuLimited,
+			MemUsed:        historyUsage(nf.memUsed, nf.hasUsage),
+			MemRequested:   nf.memRequested,
+			MemLimited:     nf.memLimited,
+			PodCount:       nf.podCount,
+			ContainerCount: nf.containerCount,
+		})
+	}
+
+	return sample
+}
+
+// historyUsage returns usage of a sample, nil if no usage is available
+// Missing usage must not count as 0 in statistics of the history.
+func historyUsage(used int64, ok bool) *int64 {
+	if !ok {
+		return nil
+	}
+	return &used
+}
+
+// NewCmdRecord is a cobra command wrapping record
+func NewCmdRecord(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "record [NODE...]",
+		Short:   "Append samples of node and namespace resources to a local history store.",
+		Long:    recordLong,
+		Example: recordExample,
+		Run: func(c *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunRecord(args))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.historyStore, "store", "", o.historyStore, `Path of the history store.`)
+	cmd.Flags().IntVarP(&o.recordCount, "count", "", o.recordCount, `Number of samples to record every --interval, 0 records until interrupted.`)
+
+	return cmd
+}
+
+// RunRecord appends recordCount samples to the history store
+func (o *FreeOptions) RunRecord(args []string) error {
+
+	if o.recordCount < 0 {
+		return fmt.Errorf("count must not be negative (count:%d)", o.recordCount)
+	}
+	if o.recordCount != 1 {
+		if err := util.ValidateInterval(o.watchInterval); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// a single sample doesn't benefit from the cache
+	if o.recordCount != 1 {
+		if err := o.startCache(ctx); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(o.watchInterval)
+	defer ticker.Stop()
+
+	for i := 0; o.recordCount == 0 || i < o.recordCount; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+
+		rctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		s, err := o.takeSnapshot(rctx, args)
+		cancel()
+		if err != nil {
+			if o.recordCount == 1 {
+				return err
+			}
+			// keep recording, the api server might be back with the next sample
+			fmt.Fprintf(o.ErrOut, "error: %v\n", err)
+			continue
+		}
+
+		if err := appendHistorySample(o.historyStore, newHistorySample(s)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendHistorySample appends a sample as a json line to the history store
+func appendHistorySample(path string, sample historySample) error {
+
+	b, err := json.Marshal(sample)
+	if err != nil {
+		return fmt.Errorf("failed to encode sample: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history store: %v", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %v", err)
+	}
+	defer f.Close()
+
+	// write the sample in one call, concurrent cron jobs don't interleave lines
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write history store: %v", err)
+	}
+
+	return nil
+}
+
+// readHistorySamples reads samples recorded at or after since from the history store
+// Broken lines (e.g. an interrupted write) are skipped.
+func readHistorySamples(path string, since time.Time) ([]historySample, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history store: %v", err)
+	}
+	defer f.Close()
+
+	samples := []historySample{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxHistoryLineSize)
+	for scanner.Scan() {
+		var sample historySample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			continue
+		}
+		if sample.Time.Before(since) {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history store: %v", err)
+	}
+
+	// samples of concurrent recorders might not be in order
+	slices.SortStableFunc(samples, func(a, b historySample) bool {
+		return a.Time.Before(b.Time)
+	})
+
+	return samples, nil
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestNewHistorySample(t *testing.T) {
+	ctx := context.Background()
+
+	o := &FreeOptions{
+		nocolor: true,
+		table:   table.NewOutputTable(os.Stdout),
+		source:  newTestSource("", testNodes, testPods),
+	}
+
+	s, err := o.takeSnapshot(ctx, []string{"node1"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	sample := newHistorySample(s)
+
+	used := int64(10)
+	expected := []historyNamespace{
+		{Name: "awesome-ns", CPURequested: 200, CPULimited: 200, MemRequested: 300, MemLimited: 300, PodCount: 1, ContainerCount: 2},
+		{Name: "default", CPUUsed: &used, CPURequested: 1500, CPULimited: 2500, MemUsed: &used, MemRequested: 2000, MemLimited: 3000, PodCount: 2, ContainerCount: 3},
+	}
+	if !reflect.DeepEqual(sample.Namespaces, expected) {
+		t.Errorf("expected(%+v) differ (got: %+v)", expected, sample.Namespaces)
+	}
+	if len(sample.Nodes) != 1 || sample.Nodes[0].Name != "node1" || sample.Nodes[0].CPUUsed == nil {
+		t.Errorf("unexpected nodes: %+v", sample.Nodes)
+	}
+
+	// no usage without metrics
+	o.source = source.NewStaticSource("", testNodes, testPods, nil, nil)
+	s, err = o.takeSnapshot(ctx, []string{"node1"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	for _, n := range newHistorySample(s).Nodes {
+		if n.CPUUsed != nil || n.MemUsed != nil {
+			t.Errorf("expected no usage of node %s (got: %v, %v)", n.Name, n.CPUUsed, n.MemUsed)
+		}
+	}
+	for _, n := range newHistorySample(s).Namespaces {
+		if n.CPUUsed != nil || n.MemUsed != nil {
+			t.Errorf("expected no usage of namespace %s (got: %v, %v)", n.Name, n.CPUUsed, n.MemUsed)
+		}
+	}
+}
+
+func TestHistoryStore(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "free", "history.jsonl")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// recorded out of order
+	samples := []historySample{
+		{Time: now.Add(-time.Hour), Nodes: []historyNode{{Name: "node1", CPURequested: 2}}},
+		{Time: now.Add(-2 * time.Hour), Nodes: []historyNode{{Name: "node1", CPURequested: 1}}},
+		{Time: now.Add(-48 * time.Hour), Nodes: []historyNode{{Name: "node1", CPURequested: 0}}},
+	}
+	for _, s := range samples {
+		if err := appendHistorySample(path, s); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	}
+
+	// interrupted write
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	_, _ = f.WriteString(`{"time":"`)
+	f.Close()
+
+	actual, err := readHistorySamples(path, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	cpu := []int64{}
+	for _, s := range actual {
+		cpu = append(cpu, s.Nodes[0].CPURequested)
+	}
+	expected := []int64{1, 2}
+	if !reflect.DeepEqual(cpu, expected) {
+		t.Errorf("expected(%v) differ (got: %v)", expected, cpu)
+	}
+
+	if _, err := readHistorySamples(filepath.Join(t.TempDir(), "missing.jsonl"), now); err == nil {
+		t.Errorf("unexpected error: should return err")
+	}
+}
+
+func TestRunRecord(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	o := &FreeOptions{
+		IOStreams:     genericclioptions.IOStreams{Out: os.Stdout, ErrOut: os.Stderr},
+		nocolor:       true,
+		table:         table.NewOutputTable(os.Stdout),
+		source:        newTestSource("", testNodes, testPods),
+		historyStore:  path,
+		recordCount:   2,
+		watchInterval: time.Millisecond,
+	}
+
+	if err := o.RunRecord([]string{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	samples, err := readHistorySamples(path, time.Time{})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(samples) != 2 {
+		t.Errorf("expected(2) differ (got: %d)", len(samples))
+	}
+
+	o.recordCount = -1
+	if err := o.RunRecord([]string{}); err == nil {
+		t.Errorf("unexpected error: should return err")
+	}
+}
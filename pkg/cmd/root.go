@@ -5,27 +5,36 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
 	"github.com/thirdeyenick/kubectl-free/pkg/table"
 	"github.com/thirdeyenick/kubectl-free/pkg/util"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/homedir"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/util/templates"
 	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
-	metricsv1beta1 "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
 
 	// Initialize all known client auth plugins.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
+// requestTimeout is the timeout of fetching all data for one output
+const requestTimeout = 15 * time.Second
+
 var (
 	memorySortResource sortResource = "memory"
 	cpuSortResource    sortResource = "cpu"
@@ -55,6 +64,164 @@ func (s *sortResource) Type() string {
 	return "sortResource"
 }
 
+var (
+	metricsServerMetricsSource metricsSource = "metrics-server"
+	prometheusMetricsSource    metricsSource = "prometheus"
+	kubeletMetricsSource       metricsSource = "kubelet"
+)
+
+// metricsSource is the backend of node and container usage
+type metricsSource string
+
+// String implements the stringer interface
+func (s *metricsSource) String() string {
+	if s == nil {
+		return "null"
+	}
+	return string(*s)
+}
+
+// Set sets the content of the metricsSource
+func (s *metricsSource) Set(v string) error {
+	if v != metricsServerMetricsSource.String() && v != prometheusMetricsSource.String() && v != kubeletMetricsSource.String() {
+		return fmt.Errorf("can only use metrics of %q, %q and %q, not of given %q", metricsServerMetricsSource.String(), prometheusMetricsSource.String(), kubeletMetricsSource.String(), v)
+	}
+	*s = metricsSource(v)
+	return nil
+}
+
+// Type returns the type
+func (s *metricsSource) Type() string {
+	return "metricsSource"
+}
+
+var (
+	tableOutputFormat outputFormat = "table"
+	treeOutputFormat  outputFormat = "tree"
+)
+
+// outputFormat is the format of the output
+type outputFormat string
+
+// String implements the stringer interface
+func (f *outputFormat) String() string {
+	if f == nil {
+		return "null"
+	}
+	return string(*f)
+}
+
+// Set sets the content of the outputFormat
+func (f *outputFormat) Set(v string) error {
+	if v != tableOutputFormat.String() && v != treeOutputFormat.String() {
+		return fmt.Errorf("can only output %q and %q, not given %q", tableOutputFormat.String(), treeOutputFormat.String(), v)
+	}
+	*f = outputFormat(v)
+	return nil
+}
+
+// Type returns the type
+func (f *outputFormat) Type() string {
+	return "outputFormat"
+}
+
+// namespacesValue is the value of --namespace, a repeated flag adds namespaces (-n team-a -n team-b)
+type namespacesValue struct {
+	namespace *string
+	changed   bool
+}
+
+// String implements the stringer interface
+func (n *namespacesValue) String() string {
+	if n.namespace == nil {
+		return ""
+	}
+	return *n.namespace
+}
+
+// Set sets the namespace, or adds it to the namespaces given before
+func (n *namespacesValue) Set(v string) error {
+	if n.changed && *n.namespace != "" {
+		v = *n.namespace + "," + v
+	}
+	*n.namespace = v
+	n.changed = true
+	return nil
+}
+
+// Type returns the type
+func (n *namespacesValue) Type() string {
+	return "string"
+}
+
+const (
+	// columns of percentages with own thresholds (--threshold)
+	cpuUseThreshold = "cpu.use"
+	cpuReqThreshold = "cpu.req"
+	cpuLimThreshold = "cpu.lim"
+	memUseThreshold = "mem.use"
+	memReqThreshold = "mem.req"
+	memLimThreshold = "mem.lim"
+)
+
+// thresholdColumns are the columns of --threshold
+var thresholdColumns = []string{cpuUseThreshold, cpuReqThreshold, cpuLimThreshold, memUseThreshold, memReqThreshold, memLimThreshold}
+
+// threshold is a pair of warn(yellow) and critical(red) percentages
+type threshold struct {
+	warn int64
+	crit int64
+}
+
+// thresholds are the thresholds of columns, e.g. cpu.lim=150:250
+type thresholds map[string]threshold
+
+// String implements the stringer interface
+func (t *thresholds) String() string {
+	if t == nil {
+		return "null"
+	}
+	values := []string{}
+	for _, column := range thresholdColumns {
+		if th, ok := (*t)[column]; ok {
+			values = append(values, fmt.Sprintf("%s=%d:%d", column, th.warn, th.crit))
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// Set adds thresholds of columns, e.g. cpu.lim=150:250,mem.lim=100:120
+func (t *thresholds) Set(v string) error {
+	if *t == nil {
+		*t = thresholds{}
+	}
+	for _, value := range strings.Split(v, ",") {
+		column, pair, ok := strings.Cut(value, "=")
+		if !ok || !slices.Contains(thresholdColumns, column) {
+			return fmt.Errorf("threshold must be <column>=<warn>:<crit> of the columns %s, not given %q", strings.Join(thresholdColumns, ", "), value)
+		}
+		warn, crit, ok := strings.Cut(pair, ":")
+		if !ok {
+			return fmt.Errorf("threshold of %s must be <warn>:<crit>, not given %q", column, pair)
+		}
+		w, err := strconv.ParseInt(warn, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid warn threshold of %s: %v", column, err)
+		}
+		c, err := strconv.ParseInt(crit, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid critical threshold of %s: %v", column, err)
+		}
+		(*t)[column] = threshold{warn: w, crit: c}
+	}
+	return nil
+}
+
+// Type returns the type
+func (t *thresholds) Type() string {
+	return "thresholds"
+}
+
 var (
 	// DfLong defines long description
 	freeLong = templates.LongDesc(`
@@ -75,6 +242,13 @@ var (
 		# Using label selector.
 		kubectl free -l key=value
 
+		# Only count pods of a team, or pods of some namespaces (comma separated).
+		kubectl free --pod-selector team=x
+		kubectl free -n team-a,team-b
+
+		# Show what's left after system pods.
+		kubectl free --exclude-namespace kube-system --pod-field-selector status.phase=Running
+
 		# Print raw(bytes) usage.
 		kubectl free --bytes --without-unit
 
@@ -90,9 +264,82 @@ var (
 		# Print container even if that has no resources/limits.
 		kubectl free --list --list-all
 
+		# Only show nodes or containers matching an expression.
+		kubectl free --where 'mem.req% > 80 && status == Ready'
+		kubectl free --list --list-all --where 'namespace =~ ^team- && (cpu.lim == 0 || mem.lim == 0)'
+		kubectl free --list --where 'age > 7d'
+
+		# List effective resources of pods (one row per pod) on nodes.
+		kubectl free --list --per-pod
+
+		# List containers with VerticalPodAutoscaler recommendations and flag requests 50% off the target.
+		kubectl free --list --vpa --vpa-divergence 50
+
+		# Show nodes, namespaces, pods and containers as a tree with subtotals.
+		kubectl free -o tree
+
+		# Show the share of capacity reserved by the kubelet and requested by static, DaemonSet and kube-system pods.
+		kubectl free --overhead
+
+		# Show hourly cost of nodes and the cost of requests by namespace from a price sheet.
+		kubectl free --pricing prices.yaml
+		kubectl free --pricing prices.yaml --cost-by namespace --cost-weight max
+
+		# Show which pods the kubelet evicts first on nodes under memory pressure.
+		kubectl free --eviction-risk
+
+		# Show containers killed for running out of memory or using 80% of their memory limit.
+		kubectl free --oom
+		kubectl free --list --oom --limit-threshold 80
+
+		# Allow overcommit of CPU limits without red colors.
+		kubectl free --threshold cpu.lim=150:250
+
 		# Do you like emoji? 😃
 		kubectl free --emoji
 		kubectl free --list --emoji
+
+		# Refresh the output every 10 seconds and show changes since the previous sample.
+		kubectl free --watch --interval 10s
+		kubectl free --list --watch
+
+		# Inspect a cluster dump offline (metrics dumps are optional).
+		kubectl get nodes,pods -A -o json > cluster.json
+		kubectl get --raw /apis/metrics.k8s.io/v1beta1/nodes > node-metrics.json
+		kubectl get --raw /apis/metrics.k8s.io/v1beta1/pods > pod-metrics.json
+		kubectl free -f cluster.json -f node-metrics.json -f pod-metrics.json
+		kubectl free --list -f ./must-gather/
+
+		# Show nodes of several clusters with totals per cluster.
+		kubectl free --contexts prod,staging
+		kubectl free --all-contexts
+
+		# Use options of a profile of ~/.config/kubectl-free/config.yaml, environment variables override it.
+		kubectl free --profile capacity-review
+		KUBECTL_FREE_WARN_THRESHOLD=70 kubectl free
+
+		# Show changes of capacity since a snapshot.
+		kubectl free snapshot > before.json
+		kubectl free diff before.json
+
+		# Record samples to a local history store and show trends.
+		kubectl free record
+		kubectl free history --since 168h
+
+		# Show the p95 usage of the last hour from Prometheus instead of metrics-server.
+		kubectl free --metrics-source prometheus --prometheus-url http://prometheus:9090 --usage-window 1h --usage-stat p95
+
+		# Read usage from the kubelet Summary API and show filesystem, ephemeral storage and network usage.
+		kubectl free --metrics-source kubelet --kubelet-stats
+
+		# Exit non-zero if the memory requests of a node are above 90%.
+		kubectl free check --rule "node:mem.req>90"
+
+		# Show resource quotas of namespaces against requests and usage, and containers defaulted by limit ranges.
+		kubectl free quota
+
+		# Show usage of persistent volume claims mounted by pods.
+		kubectl free volumes --storage-class standard
 	`)
 )
 
@@ -103,6 +350,8 @@ type FreeOptions struct {
 
 	// general options
 	labelSelector string
+	whereText     string
+	where         whereExpr
 	table         *table.OutputTable
 	pod           bool
 	emojiStatus   bool
@@ -123,24 +372,96 @@ type FreeOptions struct {
 	nocolor       bool
 	warnThreshold int64
 	critThreshold int64
+	thresholds    thresholds
 
 	// list options
 	list               bool
 	listContainerImage bool
 	listAll            bool
+	perPod             bool
+	evictionRisk       bool
+	overhead           bool
+	vpa                bool
+	vpaDivergence      int64
+
+	// oom options
+	oom            bool
+	limitThreshold int64
 
 	// sort options
 	sortByResource sortResource
 
-	// k8s clients
-	nodeClient        clientv1.NodeInterface
-	podClient         clientv1.PodInterface
-	metricsPodClient  metricsv1beta1.PodMetricsInterface
-	metricsNodeClient metricsv1beta1.NodeMetricsInterface
+	// output options
+	output outputFormat
+
+	// watch options
+	watch         bool
+	watchInterval time.Duration
+
+	// pod selection options, namespaces are set by -n with several namespaces
+	podSelector       string
+	podFieldSelector  string
+	namespaces        []string
+	excludeNamespaces []string
+	podFilter         *source.PodFilter
+
+	// data source of nodes, pods and metrics
+	source    source.Source
+	fromFiles []string
+
+	// metrics options, metrics is nil if usage is served by source (metrics-server)
+	metricsSource metricsSource
+	prometheusURL string
+	nodeNameLabel string
+	usageWindow   time.Duration
+	usageStat     string
+	kubeletStats  bool
+	metrics       source.MetricsSource
+
+	// volumes options
+	storageClasses []string
+
+	// multi cluster options, clusters are the data sources of the contexts
+	contexts    []string
+	allContexts bool
+	clusters    []cluster
+
+	// check options
+	criticalRules []string
+	warningRules  []string
+	rulesFile     string
+	nagios        bool
+
+	// cost options, prices is the price sheet of --pricing
+	pricingFile string
+	costBy      string
+	costWeight  string
+	prices      *priceSheet
+
+	// config file options
+	configFile string
+	profile    string
+
+	// k8s clients, dynamicClient is only set with --vpa
+	client        kubernetes.Interface
+	metricsClient metrics.Interface
+	dynamicClient dynamic.Interface
+	namespace     string
 
 	// table headers
 	freeTableHeaders []string
 	listTableHeaders []string
+
+	// history options (record, history)
+	historyStore string
+	recordCount  int
+	historySince time.Duration
+	historyBy    string
+	historyValue string
+
+	// previous samples (--watch)
+	prevNodeFree   map[string]nodeFree
+	prevContainers map[string]podInfo
 }
 
 // NewFreeOptions is an instance of FreeOptions
@@ -156,6 +477,8 @@ func NewFreeOptions(streams genericclioptions.IOStreams) *FreeOptions {
 		nocolor:            false,
 		warnThreshold:      60,
 		critThreshold:      90,
+		limitThreshold:     90,
+		vpaDivergence:      50,
 		IOStreams:          streams,
 		labelSelector:      "",
 		list:               false,
@@ -163,12 +486,26 @@ func NewFreeOptions(streams genericclioptions.IOStreams) *FreeOptions {
 		listAll:            false,
 		pod:                false,
 		emojiStatus:        false,
-		table:              table.NewOutputTable(os.Stdout),
+		table:              table.NewOutputTable(streams.Out),
 		allNamespaces:      true,
 		noHeaders:          false,
 		noMetrics:          false,
 		sortByResource:     memorySortResource,
+		output:             tableOutputFormat,
 		compactView:        true,
+		watch:              false,
+		watchInterval:      5 * time.Second,
+		metricsSource:      metricsServerMetricsSource,
+		nodeNameLabel:      source.DefaultNodeLabel,
+		usageStat:          source.UsageStatAvg,
+		historyStore:       filepath.Join(homedir.HomeDir(), ".kube", "free", "history.jsonl"),
+		recordCount:        1,
+		historySince:       24 * time.Hour,
+		historyBy:          historyByNode,
+		historyValue:       historyValueUse,
+		costBy:             costByNode,
+		costWeight:         costWeightRequests,
+		configFile:         filepath.Join(homedir.HomeDir(), ".config", "kubectl-free", "config.yaml"),
 	}
 }
 
@@ -182,6 +519,7 @@ func NewCmdFree(f cmdutil.Factory, streams genericclioptions.IOStreams, version,
 		Long:    freeLong,
 		Example: freeExample,
 		Version: version,
+		Args:    cobra.ArbitraryArgs,
 		Run: func(c *cobra.Command, args []string) {
 			cmdutil.CheckErr(o.Complete(f, c, args))
 			cmdutil.CheckErr(o.Validate())
@@ -190,32 +528,80 @@ func NewCmdFree(f cmdutil.Factory, streams genericclioptions.IOStreams, version,
 	}
 
 	// bool options
-	cmd.Flags().BoolVarP(&o.bytes, "bytes", "b", o.bytes, `Use 1-byte (1-Byte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.kByte, "kilobytes", "k", o.kByte, `Use 1024-byte (1-Kbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.mByte, "megabytes", "m", o.mByte, `Use 1048576-byte (1-Mbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.gByte, "gigabytes", "g", o.gByte, `Use 1073741824-byte (1-Gbyte) blocks rather than the default.`)
-	cmd.Flags().BoolVarP(&o.binPrefix, "binary-prefix", "B", o.binPrefix, `Use 1024 for basic unit calculation instead of 1000. (print like "KiB")`)
-	cmd.Flags().BoolVarP(&o.withoutUnit, "without-unit", "", o.withoutUnit, `Do not print size with unit string.`)
-	cmd.Flags().Var(&o.sortByResource, "sort-by-resource", "Sort container list by CPU or memory usage.")
-	cmd.Flags().BoolVarP(&o.nocolor, "no-color", "", o.nocolor, `Print without ansi color.`)
-	cmd.Flags().BoolVarP(&o.pod, "pod", "p", o.pod, `Show pod count and limit.`)
+	cmd.PersistentFlags().BoolVarP(&o.bytes, "bytes", "b", o.bytes, `Use 1-byte (1-Byte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.kByte, "kilobytes", "k", o.kByte, `Use 1024-byte (1-Kbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.mByte, "megabytes", "m", o.mByte, `Use 1048576-byte (1-Mbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.gByte, "gigabytes", "g", o.gByte, `Use 1073741824-byte (1-Gbyte) blocks rather than the default.`)
+	cmd.PersistentFlags().BoolVarP(&o.binPrefix, "binary-prefix", "B", o.binPrefix, `Use 1024 for basic unit calculation instead of 1000. (print like "KiB")`)
+	cmd.PersistentFlags().BoolVarP(&o.withoutUnit, "without-unit", "", o.withoutUnit, `Do not print size with unit string.`)
+	cmd.PersistentFlags().Var(&o.sortByResource, "sort-by-resource", "Sort container list by CPU or memory usage.")
+	cmd.Flags().VarP(&o.output, "output", "o", `Output format ("table" or "tree" of nodes, namespaces, pods and containers).`)
+	cmd.PersistentFlags().BoolVarP(&o.nocolor, "no-color", "", o.nocolor, `Print without ansi color.`)
+	cmd.PersistentFlags().BoolVarP(&o.pod, "pod", "p", o.pod, `Show pod count and limit.`)
 	cmd.Flags().BoolVarP(&o.list, "list", "", o.list, `Show container list on node.`)
-	cmd.Flags().BoolVarP(&o.listContainerImage, "list-image", "", o.listContainerImage, `Show pod list on node with container image.`)
-	cmd.Flags().BoolVarP(&o.listAll, "list-all", "", o.listAll, `Show pods even if they have no requests/limit`)
-	cmd.Flags().BoolVarP(&o.emojiStatus, "emoji", "", o.emojiStatus, `Let's smile!! 😃 😭`)
-	cmd.Flags().BoolVarP(&o.allNamespaces, "all-namespaces", "", o.allNamespaces, `If present, list pod resources(limits) across all namespaces. Namespace in current context is ignored even if specified with --namespace.`)
-	cmd.Flags().BoolVarP(&o.noHeaders, "no-headers", "", o.noHeaders, `Do not print table headers.`)
-	cmd.Flags().BoolVarP(&o.noMetrics, "no-metrics", "", o.noMetrics, `Do not print node/pods/containers usage from metrics-server.`)
-	cmd.Flags().BoolVarP(&o.compactView, "compact-view", "", o.compactView, `Only print usage of pods/containers in a compact view.`)
+	cmd.PersistentFlags().BoolVarP(&o.listContainerImage, "list-image", "", o.listContainerImage, `Show pod list on node with container image.`)
+	cmd.PersistentFlags().BoolVarP(&o.listAll, "list-all", "", o.listAll, `Show pods even if they have no requests/limit`)
+	cmd.PersistentFlags().BoolVarP(&o.emojiStatus, "emoji", "", o.emojiStatus, `Let's smile!! 😃 😭`)
+	cmd.PersistentFlags().BoolVarP(&o.allNamespaces, "all-namespaces", "", o.allNamespaces, `If present, list pod resources(limits) across all namespaces. Namespace in current context is ignored, --namespace only if this flag is given explicitly.`)
+	cmd.PersistentFlags().BoolVarP(&o.noHeaders, "no-headers", "", o.noHeaders, `Do not print table headers.`)
+	cmd.PersistentFlags().BoolVarP(&o.noMetrics, "no-metrics", "", o.noMetrics, `Do not print node/pods/containers usage from metrics-server.`)
+	cmd.PersistentFlags().BoolVarP(&o.compactView, "compact-view", "", o.compactView, `Only print usage of pods/containers in a compact view.`)
+	cmd.PersistentFlags().BoolVarP(&o.kubeletStats, "kubelet-stats", "", o.kubeletStats, `Show filesystem, ephemeral storage and network usage of nodes (--metrics-source kubelet).`)
+	cmd.Flags().BoolVarP(&o.perPod, "per-pod", "", o.perPod, `Show one row per pod with effective requests/limits of the pod instead of containers (--list).`)
+	cmd.Flags().BoolVarP(&o.overhead, "overhead", "", o.overhead, `Show capacity, reserved resources and requests of static, DaemonSet, kube-system and other pods of nodes.`)
+	cmd.Flags().BoolVarP(&o.vpa, "vpa", "", o.vpa, `Show target, lower and upper bound of VerticalPodAutoscaler recommendations of containers (--list).`)
+	cmd.Flags().BoolVarP(&o.evictionRisk, "eviction-risk", "", o.evictionRisk, `Rank pods of nodes under memory pressure in the order the kubelet would evict them.`)
+	cmd.Flags().BoolVarP(&o.oom, "oom", "", o.oom, `Show count of containers killed for running out of memory, with --list show those containers and containers close to their memory limit.`)
+	cmd.Flags().BoolVarP(&o.allContexts, "all-contexts", "", o.allContexts, `Show nodes of all contexts in the kubeconfig.`)
+	cmd.Flags().BoolVarP(&o.watch, "watch", "w", o.watch, `Refresh the output periodically and show changes since the previous sample.`)
+
+	// duration options
+	cmd.PersistentFlags().DurationVarP(&o.watchInterval, "interval", "", o.watchInterval, `Refresh interval of --watch and the terminal UI.`)
+	cmd.PersistentFlags().DurationVarP(&o.usageWindow, "usage-window", "", o.usageWindow, `Show a statistic of usage over this window instead of the current usage (prometheus only).`)
+
+	// metrics options
+	cmd.PersistentFlags().Var(&o.metricsSource, "metrics-source", `Backend of node and container usage ("metrics-server", "prometheus" or "kubelet").`)
+	cmd.PersistentFlags().StringVarP(&o.prometheusURL, "prometheus-url", "", o.prometheusURL, `URL of the Prometheus compatible HTTP API (--metrics-source prometheus).`)
+	cmd.PersistentFlags().StringVarP(&o.nodeNameLabel, "prometheus-node-label", "", o.nodeNameLabel, `Label of the node name of cAdvisor metrics, "instance" is used if it's missing (--metrics-source prometheus).`)
+	cmd.PersistentFlags().StringVarP(&o.usageStat, "usage-stat", "", o.usageStat, fmt.Sprintf(`Statistic of usage over --usage-window (%s).`, strings.Join(source.UsageStats, ", ")))
 
 	// int64 options
-	cmd.Flags().Int64VarP(&o.warnThreshold, "warn-threshold", "", o.warnThreshold, `Threshold of warn(yellow) color for USED column.`)
-	cmd.Flags().Int64VarP(&o.critThreshold, "crit-threshold", "", o.critThreshold, `Threshold of critical(red) color for USED column.`)
+	cmd.PersistentFlags().Int64VarP(&o.warnThreshold, "warn-threshold", "", o.warnThreshold, `Threshold of warn(yellow) color for USED column.`)
+	cmd.PersistentFlags().Int64VarP(&o.critThreshold, "crit-threshold", "", o.critThreshold, `Threshold of critical(red) color for USED column.`)
+	cmd.PersistentFlags().Var(&o.thresholds, "threshold", fmt.Sprintf(`Thresholds of warn and critical color of a column overriding --warn-threshold and --crit-threshold, e.g. cpu.lim=150:250 (%s).`, strings.Join(thresholdColumns, ", ")))
+	cmd.Flags().Int64VarP(&o.vpaDivergence, "vpa-divergence", "", o.vpaDivergence, `Percentage by which requests may differ from the VerticalPodAutoscaler target before they are flagged (--vpa).`)
+	cmd.Flags().Int64VarP(&o.limitThreshold, "limit-threshold", "", o.limitThreshold, `Percentage of the memory limit from which containers are shown and MEM/lim% is critical (--list --oom).`)
 
 	// string option
-	cmd.Flags().StringVarP(&o.labelSelector, "selector", "l", o.labelSelector, `Selector (label query) to filter on.`)
+	cmd.PersistentFlags().StringVarP(&o.labelSelector, "selector", "l", o.labelSelector, `Selector (label query) to filter on.`)
+	cmd.PersistentFlags().StringVarP(&o.podSelector, "pod-selector", "", o.podSelector, `Selector (label query) of pods counted on nodes and listed.`)
+	cmd.PersistentFlags().StringVarP(&o.podFieldSelector, "pod-field-selector", "", o.podFieldSelector, `Selector (field query) of pods counted on nodes and listed, e.g. status.phase=Running.`)
+	cmd.Flags().StringVarP(&o.whereText, "where", "", o.whereText, `Only show nodes (or containers with --list) matching the expression, e.g. 'mem.req% > 80 && status == Ready'.`)
+	cmd.Flags().StringVarP(&o.pricingFile, "pricing", "", o.pricingFile, `Show hourly cost of nodes from a price sheet of instance types and node labels, and its allocation to pods.`)
+	cmd.Flags().StringVarP(&o.costBy, "cost-by", "", o.costBy, `Allocate cost of nodes by "node", "namespace" or "workload" (--pricing).`)
+	cmd.Flags().StringVarP(&o.costWeight, "cost-weight", "", o.costWeight, `Weight cost allocation by "requests" or "max" of requests and usage (--pricing).`)
+	cmd.PersistentFlags().StringVarP(&o.configFile, "config", "", o.configFile, `Config file with defaults of options and named profiles.`)
+	cmd.PersistentFlags().StringVarP(&o.profile, "profile", "", o.profile, `Use options of this profile of the config file.`)
+
+	// string slice options
+	cmd.Flags().StringSliceVarP(&o.contexts, "contexts", "", o.contexts, `Show nodes of these contexts in the kubeconfig.`)
+	cmd.PersistentFlags().StringSliceVarP(&o.excludeNamespaces, "exclude-namespace", "", o.excludeNamespaces, `Do not count and list pods of these namespaces, e.g. kube-system.`)
+	cmd.PersistentFlags().StringSliceVarP(&o.fromFiles, "from-file", "f", o.fromFiles, `Read nodes, pods and metrics from json/yaml dump files or directories (e.g. must-gather) instead of the cluster.`)
+
+	o.configFlags.AddFlags(cmd.PersistentFlags())
+	if namespaceFlag := cmd.PersistentFlags().Lookup("namespace"); namespaceFlag != nil {
+		namespaceFlag.Value = &namespacesValue{namespace: o.configFlags.Namespace}
+	}
 
-	o.configFlags.AddFlags(cmd.Flags())
+	// sub commands
+	cmd.AddCommand(NewCmdUI(f, o))
+	cmd.AddCommand(NewCmdSnapshot(f, o))
+	cmd.AddCommand(NewCmdDiff(f, o))
+	cmd.AddCommand(NewCmdRecord(f, o))
+	cmd.AddCommand(NewCmdHistory(o))
+	cmd.AddCommand(NewCmdVolumes(f, o))
+	cmd.AddCommand(NewCmdCheck(f, o))
+	cmd.AddCommand(NewCmdQuota(f, o))
 
 	// add the klog flags
 	cmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
@@ -229,15 +615,107 @@ func NewCmdFree(f cmdutil.Factory, streams genericclioptions.IOStreams, version,
 // Complete prepares k8s clients
 func (o *FreeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
 
+	// options from environment variables and the config file (--config, --profile)
+	if err := o.applyConfig(cmd); err != nil {
+		return err
+	}
+
+	// filter of rows (--where), containers with --list and in the ui, nodes otherwise
+	if o.whereText != "" {
+		fields := nodeWhereFields(nodeFree{})
+		if o.list || cmd.Name() == "ui" {
+			fields = podWhereFields(podInfo{})
+		}
+		where, err := parseWhere(o.whereText, fields)
+		if err != nil {
+			return err
+		}
+		o.where = where
+	}
+
+	// price sheet of nodes (--pricing)
+	if o.pricingFile != "" {
+		prices, err := loadPriceSheet(o.pricingFile)
+		if err != nil {
+			return err
+		}
+		o.prices = prices
+	}
+
+	// namespace of pods, -n selects namespaces unless --all-namespaces is given too
+	allNamespaces := o.allNamespaces
+	if cmd.Flags().Changed("namespace") && !cmd.Flags().Changed("all-namespaces") {
+		allNamespaces = false
+	}
+	if allNamespaces {
+		// --all-namespace flag
+		o.namespace = v1.NamespaceAll
+	} else {
+		if *o.configFlags.Namespace == "" {
+			// default namespace is "default"
+			o.namespace = v1.NamespaceDefault
+		} else {
+			// targeted namespace (--namespace flag)
+			o.namespace = *o.configFlags.Namespace
+
+			// several namespaces (-n team-a,team-b or -n team-a -n team-b) are selected from pods of all namespaces
+			if namespaces := strings.Split(o.namespace, ","); len(namespaces) > 1 {
+				o.namespace = v1.NamespaceAll
+				o.namespaces = namespaces
+			}
+		}
+	}
+
+	// selection of pods (--pod-selector, --pod-field-selector, --exclude-namespace)
+	if o.podSelector != "" || o.podFieldSelector != "" || len(o.namespaces) > 0 || len(o.excludeNamespaces) > 0 {
+		podFilter, err := source.NewPodFilter(o.podSelector, o.podFieldSelector, o.namespaces, o.excludeNamespaces)
+		if err != nil {
+			return err
+		}
+		o.podFilter = podFilter
+	}
+
+	// data sources of several clusters (--contexts, --all-contexts)
+	if o.multiCluster() {
+		clusters, err := o.newClusters()
+		if err != nil {
+			return err
+		}
+		o.clusters = clusters
+
+		// prepare table header
+		o.prepareFreeTableHeader()
+
+		return nil
+	}
+
+	// read nodes, pods and metrics from dump files (--from-file), no cluster access required
+	if len(o.fromFiles) > 0 {
+		fs, err := source.NewFileSource(o.namespace, o.fromFiles, o.ErrOut)
+		if err != nil {
+			return err
+		}
+		o.source = o.withPodFilter(fs)
+
+		// usage from another backend (--metrics-source)
+		if o.metrics, err = o.newMetricsSource(); err != nil {
+			return err
+		}
+		o.source = o.withMetrics(o.source)
+
+		// prepare table header
+		o.prepareFreeTableHeader()
+		o.prepareListTableHeader()
+
+		return nil
+	}
+
 	// get k8s client
 	client, err := f.KubernetesClientSet()
 	if err != nil {
 		return err
 	}
 
-	// node client
-	o.nodeClient = client.CoreV1().Nodes()
-
 	// metric client
 	config, err := f.ToRESTConfig()
 	if err != nil {
@@ -249,23 +727,32 @@ func (o *FreeOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []str
 		return err
 	}
 
-	// pod and metrics client
-	if o.allNamespaces {
-		// --all-namespace flag
-		o.podClient = client.CoreV1().Pods(v1.NamespaceAll)
-		o.metricsPodClient = mclient.MetricsV1beta1().PodMetricses(v1.NamespaceAll)
-	} else {
-		if *o.configFlags.Namespace == "" {
-			// default namespace is "default"
-			o.podClient = client.CoreV1().Pods(v1.NamespaceDefault)
-			o.metricsPodClient = mclient.MetricsV1beta1().PodMetricses(v1.NamespaceDefault)
-		} else {
-			// targeted namespace (--namespace flag)
-			o.podClient = client.CoreV1().Pods(*o.configFlags.Namespace)
-			o.metricsPodClient = mclient.MetricsV1beta1().PodMetricses(*o.configFlags.Namespace)
+	o.client = client
+	o.metricsClient = mclient
+
+	// VerticalPodAutoscaler is a CRD, recommendations are read with the dynamic client (--vpa)
+	if o.vpa {
+		dclient, err := f.DynamicClient()
+		if err != nil {
+			return err
 		}
+		o.dynamicClient = dclient
 	}
-	o.metricsNodeClient = mclient.MetricsV1beta1().NodeMetricses()
+
+	// request data from the api server, long running modes switch to a cache (see startCache)
+	o.source = source.NewClientSource(
+		client.CoreV1().Nodes(),
+		client.CoreV1().Pods(o.namespace),
+		mclient.MetricsV1beta1().NodeMetricses(),
+		mclient.MetricsV1beta1().PodMetricses(o.namespace),
+	)
+	o.source = o.withPodFilter(o.source)
+
+	// usage from another backend (--metrics-source)
+	if o.metrics, err = o.newMetricsSource(); err != nil {
+		return err
+	}
+	o.source = o.withMetrics(o.source)
 
 	// prepare table header
 	o.prepareFreeTableHeader()
@@ -281,19 +768,136 @@ func (o *FreeOptions) Validate() error {
 	if err := util.ValidateThreshold(o.warnThreshold, o.critThreshold); err != nil {
 		return err
 	}
+	for _, column := range thresholdColumns {
+		if t, ok := o.thresholds[column]; ok {
+			if err := util.ValidateThreshold(t.warn, t.crit); err != nil {
+				return fmt.Errorf("invalid threshold of %s: %v", column, err)
+			}
+		}
+	}
+
+	// validate multi cluster options
+	if err := o.validateMultiCluster(); err != nil {
+		return err
+	}
+
+	// -o tree shows nodes and containers
+	if o.output == treeOutputFormat && (o.list || o.evictionRisk) {
+		return fmt.Errorf("-o %s can't be used with --list or --eviction-risk", treeOutputFormat)
+	}
+
+	// --where filters the node table and the container list
+	if o.whereText != "" && (o.output == treeOutputFormat || o.evictionRisk || o.overhead || o.pricingFile != "" || o.multiCluster() || (o.list && o.oom)) {
+		return fmt.Errorf("--where only filters the node table and the container list")
+	}
+
+	// --overhead is another view of nodes
+	if o.overhead && (o.list || o.evictionRisk || o.output == treeOutputFormat) {
+		return fmt.Errorf("--overhead can't be used with --list, --eviction-risk or -o tree")
+	}
+
+	// validate cost options
+	if err := o.validateCost(); err != nil {
+		return err
+	}
+
+	// --eviction-risk is another view of pods
+	if o.evictionRisk && o.list {
+		return fmt.Errorf("--eviction-risk can't be used with --list")
+	}
+
+	// --per-pod sums containers of the container list
+	if o.perPod && !o.list {
+		return fmt.Errorf("--per-pod requires --list")
+	}
+	if o.perPod && o.oom {
+		return fmt.Errorf("--per-pod can't be used with --oom")
+	}
+
+	// --vpa compares recommendations of containers in the container list
+	if o.vpa && (!o.list || o.perPod || o.oom) {
+		return fmt.Errorf("--vpa requires --list and can't be used with --per-pod or --oom")
+	}
+	if o.vpa && o.vpaDivergence <= 0 {
+		return fmt.Errorf("--vpa-divergence must be greater than 0, not given %d", o.vpaDivergence)
+	}
+
+	// validate limit threshold
+	if o.oom {
+		if err := util.ValidateLimitThreshold(o.limitThreshold); err != nil {
+			return err
+		}
+	}
+
+	// validate metrics options
+	if err := o.validateMetricsSource(); err != nil {
+		return err
+	}
+
+	// validate watch interval
+	if o.watch {
+		if err := util.ValidateInterval(o.watchInterval); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
 // Run printing disk usage of images
 func (o *FreeOptions) Run(args []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+
+	// refresh output until interrupted (--watch)
+	if o.watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		return o.runWatch(ctx, args)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
 	defer cancel()
 
+	return o.runOnce(ctx, args)
+}
+
+// runOnce prints cpu/mem/pod resource usage or the container list once
+func (o *FreeOptions) runOnce(ctx context.Context, args []string) error {
+
+	// print cpu/mem/pod resource usage of several clusters and return
+	if o.multiCluster() {
+		return o.showClusters(ctx, args)
+	}
+
 	// get nodes
-	nodes, err := util.GetNodes(ctx, o.nodeClient, args, o.labelSelector)
+	nodes, err := o.source.GetNodes(ctx, args, o.labelSelector)
 	if err != nil {
-		return nil
+		return err
+	}
+
+	// print nodes, namespaces, pods and containers as a tree and return
+	if o.output == treeOutputFormat {
+		return o.showTree(ctx, nodes)
+	}
+
+	// print capacity split into reserved resources and requests by class of pods and return
+	if o.overhead {
+		return o.showOverhead(ctx, nodes)
+	}
+
+	// print cost of nodes and its allocation to pods and return
+	if o.prices != nil {
+		return o.showCost(ctx, nodes)
+	}
+
+	// rank pods of nodes under memory pressure and return
+	if o.evictionRisk {
+		return o.showEvictionRisk(ctx, nodes)
+	}
+
+	// list containers killed or about to be killed for running out of memory and return
+	if o.list && o.oom {
+		return o.showOOMContainers(ctx, nodes)
 	}
 
 	// list pods and return
@@ -334,6 +938,13 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 	hPods := "PODS"
 	hPodsAlloc := "PODS/alloc"
 	hContainers := "CONTAINERS"
+	hOOM := "OOM"
+	hFSUse := "FS/use"
+	hFSCap := "FS/cap"
+	hFSUseP := "FS/use%"
+	hEPHUse := "EPH/use"
+	hNETRx := "NET/rx"
+	hNETTx := "NET/tx"
 
 	if !o.nocolor {
 		// hack: avoid breaking column by escape char
@@ -344,6 +955,7 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 		util.DefaultColor(&hMEMUseP) // MEM/use%
 		util.DefaultColor(&hMEMReqP) // MEM/req%
 		util.DefaultColor(&hMEMLimP) // MEM/lim%
+		util.DefaultColor(&hFSUseP)  // FS/use%
 	}
 
 	baseHeader := []string{
@@ -379,6 +991,15 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 		hContainers,
 	}
 
+	statsHeader := []string{
+		hFSUse,
+		hFSCap,
+		hFSUseP,
+		hEPHUse,
+		hNETRx,
+		hNETTx,
+	}
+
 	if !o.noMetrics {
 		// insert metrics columns
 		cpuHeader = append([]string{hCPUUse}, cpuHeader...)
@@ -400,6 +1021,14 @@ func (o *FreeOptions) prepareFreeTableHeader() {
 		fth = append(fth, podHeader...)
 	}
 
+	if o.oom {
+		fth = append(fth, hOOM)
+	}
+
+	if o.kubeletStats {
+		fth = append(fth, statsHeader...)
+	}
+
 	o.freeTableHeaders = fth
 }
 
@@ -412,7 +1041,13 @@ func (o *FreeOptions) prepareListTableHeader() {
 	hPodIP := "POD IP"
 	hPodStatus := "POD STATUS"
 	hPodAge := "POD AGE"
+	hQOS := "QOS"
+	hPriority := "PRIORITY CLASS"
 	hContainer := "CONTAINER"
+	hContainers := "CONTAINERS"
+	hState := "STATE"
+	hRestarts := "RESTARTS"
+	hLastReason := "LAST REASON"
 	hCPUUse := "CPU/use"
 	hCPUReq := "CPU/req"
 	hCPULim := "CPU/lim"
@@ -420,6 +1055,7 @@ func (o *FreeOptions) prepareListTableHeader() {
 	hMEMReq := "MEM/req"
 	hMEMLim := "MEM/lim"
 	hImage := "IMAGE"
+	hVPA := "VPA"
 
 	if !o.nocolor {
 		// hack: avoid breaking column by escape char
@@ -438,16 +1074,38 @@ func (o *FreeOptions) prepareListTableHeader() {
 			hPodAge,
 			hPodIP,
 			hPodStatus,
+			hQOS,
+			hPriority,
 		}
 	} else {
 		podHeader = []string{
 			hPod,
 			hPodStatus,
+			hQOS,
+			hPriority,
 		}
 	}
 
-	containerHeader := []string{
-		hContainer,
+	var containerHeader []string
+	if o.perPod {
+		containerHeader = []string{
+			hContainers,
+			hRestarts,
+			hLastReason,
+		}
+	} else if !o.compactView {
+		containerHeader = []string{
+			hContainer,
+			hState,
+			hRestarts,
+			hLastReason,
+		}
+	} else {
+		containerHeader = []string{
+			hContainer,
+			hRestarts,
+			hLastReason,
+		}
 	}
 
 	cpuHeader := []string{
@@ -468,6 +1126,11 @@ func (o *FreeOptions) prepareListTableHeader() {
 		if o.compactView {
 			cpuHeader = []string{hCPUUse}
 			memHeader = []string{hMEMUse}
+			if o.vpa {
+				// requests next to recommendations
+				cpuHeader = append(cpuHeader, hCPUReq)
+				memHeader = append(memHeader, hMEMReq)
+			}
 		} else {
 			// insert metrics columns
 			cpuHeader = append([]string{hCPUUse}, cpuHeader...)
@@ -475,6 +1138,12 @@ func (o *FreeOptions) prepareListTableHeader() {
 		}
 	}
 
+	// recommendations of VerticalPodAutoscalers (--vpa)
+	if o.vpa {
+		cpuHeader = append(cpuHeader, "CPU/vpa", "CPU/vpa-lo", "CPU/vpa-hi")
+		memHeader = append(memHeader, "MEM/vpa", "MEM/vpa-lo", "MEM/vpa-hi", hVPA)
+	}
+
 	// finally, join all columns
 	lth := []string{}
 
@@ -491,6 +1160,110 @@ func (o *FreeOptions) prepareListTableHeader() {
 	o.listTableHeaders = lth
 }
 
+// startCache replaces the data source with shared informers and a metrics poller
+// Long running modes use it to avoid listing all nodes and pods on every refresh.
+func (o *FreeOptions) startCache(ctx context.Context) error {
+
+	// clients are not available (e.g. tests with a static source)
+	if o.client == nil {
+		return nil
+	}
+
+	// poll metrics-server only if it serves the usage
+	var metricsClient metrics.Interface
+	if !o.noMetrics && o.metrics == nil {
+		metricsClient = o.metricsClient
+	}
+
+	cache := source.NewCacheSource(o.client, metricsClient, o.namespace, o.watchInterval)
+	if err := cache.Start(ctx, requestTimeout); err != nil {
+		return err
+	}
+	o.source = o.withMetrics(o.withPodFilter(cache))
+
+	return nil
+}
+
+// newMetricsSource returns the backend of usage (--metrics-source)
+// nil means usage is served by the data source from metrics-server.
+func (o *FreeOptions) newMetricsSource() (source.MetricsSource, error) {
+	switch o.metricsSource {
+	case prometheusMetricsSource:
+		return source.NewPrometheusSource(o.prometheusURL, o.namespace, o.usageWindow, o.usageStat, o.nodeNameLabel, nil), nil
+	case kubeletMetricsSource:
+		if o.client == nil {
+			return nil, fmt.Errorf("--metrics-source %s requires access to the cluster", kubeletMetricsSource)
+		}
+		return source.NewKubeletSource(o.client, o.namespace), nil
+	default:
+		return nil, nil
+	}
+}
+
+// withMetrics returns s serving usage from the backend of --metrics-source
+func (o *FreeOptions) withMetrics(s source.Source) source.Source {
+	if o.metrics == nil {
+		return s
+	}
+	return source.WithMetrics(s, o.metrics)
+}
+
+// withPodFilter returns s serving only the selected pods (--pod-selector, --pod-field-selector, --exclude-namespace)
+func (o *FreeOptions) withPodFilter(s source.Source) source.Source {
+	if o.podFilter == nil {
+		return s
+	}
+	return source.WithPodFilter(s, o.podFilter)
+}
+
+// validateMetricsSource validates options of the metrics backend
+func (o *FreeOptions) validateMetricsSource() error {
+
+	if o.metricsSource == prometheusMetricsSource && o.prometheusURL == "" {
+		return fmt.Errorf("--prometheus-url is required for --metrics-source %s", prometheusMetricsSource)
+	}
+
+	if o.usageWindow < 0 {
+		return fmt.Errorf("usage window must not be negative (usage-window:%s)", o.usageWindow)
+	}
+	if o.usageWindow > 0 && o.metricsSource != prometheusMetricsSource {
+		return fmt.Errorf("--usage-window requires --metrics-source %s", prometheusMetricsSource)
+	}
+
+	if o.kubeletStats && o.metricsSource != kubeletMetricsSource {
+		return fmt.Errorf("--kubelet-stats requires --metrics-source %s", kubeletMetricsSource)
+	}
+
+	if o.usageWindow > 0 && !slices.Contains(source.UsageStats, o.usageStat) {
+		return fmt.Errorf("usage stat must be one of %s (usage-stat:%s)", strings.Join(source.UsageStats, ", "), o.usageStat)
+	}
+
+	return nil
+}
+
+// validateMultiCluster validates options of several clusters
+func (o *FreeOptions) validateMultiCluster() error {
+
+	if !o.multiCluster() {
+		return nil
+	}
+
+	if len(o.contexts) > 0 && o.allContexts {
+		return fmt.Errorf("--contexts can't be used with --all-contexts")
+	}
+	if o.list || o.evictionRisk || o.overhead || o.pricingFile != "" || o.output == treeOutputFormat || o.watch {
+		return fmt.Errorf("--contexts and --all-contexts only show the node summary")
+	}
+	if len(o.fromFiles) > 0 {
+		return fmt.Errorf("--contexts and --all-contexts can't be used with --from-file")
+	}
+	if o.metricsSource != "" && o.metricsSource != metricsServerMetricsSource {
+		return fmt.Errorf("--contexts and --all-contexts require --metrics-source %s", metricsServerMetricsSource)
+	}
+
+	return nil
+}
+
 // setMetricsClient sets metrics client
 func (o *FreeOptions) setMetricsClient(config *rest.Config) (*metrics.Clientset, error) {
 
@@ -555,6 +1328,24 @@ func (o *FreeOptions) toMilliUnitOrDash(i int64) string {
 // warn < percentage < crit : Yellow
 // crit < percentage        : Red
 func (o *FreeOptions) toColorPercent(i int64) string {
+	return o.toColorPercentWithThreshold(i, threshold{warn: o.warnThreshold, crit: o.critThreshold})
+}
+
+// toColorColumnPercent returns colored strings with the thresholds of a column (--threshold)
+func (o *FreeOptions) toColorColumnPercent(column string, i int64) string {
+	return o.toColorPercentWithThreshold(i, o.getThreshold(column))
+}
+
+// getThreshold returns the thresholds of a column, --warn-threshold and --crit-threshold by default
+func (o *FreeOptions) getThreshold(column string) threshold {
+	if t, ok := o.thresholds[column]; ok {
+		return t
+	}
+	return threshold{warn: o.warnThreshold, crit: o.critThreshold}
+}
+
+// toColorPercentWithThreshold returns colored strings
+func (o *FreeOptions) toColorPercentWithThreshold(i int64, t threshold) string {
 	p := strconv.FormatInt(i, 10) + "%"
 
 	if o.nocolor {
@@ -563,10 +1354,10 @@ func (o *FreeOptions) toColorPercent(i int64) string {
 	}
 
 	switch {
-	case i < o.warnThreshold:
+	case i < t.warn:
 		// percentage < warn : Green
 		util.Green(&p)
-	case i < o.critThreshold:
+	case i < t.crit:
 		// warn < percentage < crit : Yellow
 		util.Yellow(&p)
 	default:
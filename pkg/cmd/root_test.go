@@ -3,10 +3,13 @@ package cmd
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
 	"github.com/thirdeyenick/kubectl-free/pkg/table"
 	"github.com/thirdeyenick/kubectl-free/pkg/util"
 
@@ -15,14 +18,11 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
-	fake "k8s.io/client-go/kubernetes/fake"
-	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/homedir"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
-	fakemetrics "k8s.io/metrics/pkg/client/clientset/versioned/fake"
 )
 
 // test node object
@@ -220,13 +220,15 @@ func TestNewFreeOptions(t *testing.T) {
 		configFlags:        genericclioptions.NewConfigFlags(true),
 		bytes:              false,
 		kByte:              false,
-		mByte:              false,
+		mByte:              true,
 		gByte:              false,
 		withoutUnit:        false,
 		binPrefix:          false,
 		nocolor:            false,
-		warnThreshold:      25,
-		critThreshold:      50,
+		warnThreshold:      60,
+		critThreshold:      90,
+		limitThreshold:     90,
+		vpaDivergence:      50,
 		IOStreams:          streams,
 		labelSelector:      "",
 		list:               false,
@@ -234,9 +236,26 @@ func TestNewFreeOptions(t *testing.T) {
 		listAll:            false,
 		pod:                false,
 		emojiStatus:        false,
-		table:              table.NewOutputTable(os.Stdout),
+		table:              table.NewOutputTable(streams.Out),
+		allNamespaces:      true,
 		noHeaders:          false,
 		noMetrics:          false,
+		sortByResource:     memorySortResource,
+		output:             tableOutputFormat,
+		compactView:        true,
+		watch:              false,
+		watchInterval:      5 * time.Second,
+		metricsSource:      metricsServerMetricsSource,
+		nodeNameLabel:      source.DefaultNodeLabel,
+		usageStat:          source.UsageStatAvg,
+		historyStore:       filepath.Join(homedir.HomeDir(), ".kube", "free", "history.jsonl"),
+		recordCount:        1,
+		historySince:       24 * time.Hour,
+		historyBy:          historyByNode,
+		historyValue:       historyValueUse,
+		costBy:             costByNode,
+		costWeight:         costWeightRequests,
+		configFile:         filepath.Join(homedir.HomeDir(), ".config", "kubectl-free", "config.yaml"),
 	}
 
 	actual := NewFreeOptions(streams)
@@ -362,6 +381,63 @@ func TestComplete(t *testing.T) {
 			return
 		}
 	})
+
+	t.Run("complete several namespaces", func(t *testing.T) {
+
+		o := &FreeOptions{
+			configFlags:       genericclioptions.NewConfigFlags(true),
+			excludeNamespaces: []string{"kube-system"},
+		}
+		*o.configFlags.Namespace = "team-a,team-b"
+
+		if err := o.Complete(f, rootCmd, []string{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if o.namespace != "" || !reflect.DeepEqual(o.namespaces, []string{"team-a", "team-b"}) || o.podFilter == nil {
+			t.Errorf("expected pods of team-a and team-b selected from all namespaces (got: namespace:%q namespaces:%v)", o.namespace, o.namespaces)
+		}
+	})
+
+	t.Run("complete repeated namespace flag", func(t *testing.T) {
+
+		dump := filepath.Join(t.TempDir(), "cluster.yaml")
+		pods := `
+kind: List
+items:
+- {kind: Node, metadata: {name: node1}, status: {conditions: [{type: Ready, status: "True"}]}}
+- {kind: Pod, metadata: {name: pod-a, namespace: team-a}, spec: {nodeName: node1, containers: [{name: app}]}, status: {phase: Running}}
+- {kind: Pod, metadata: {name: pod-b, namespace: team-b}, spec: {nodeName: node1, containers: [{name: app}]}, status: {phase: Running}}
+- {kind: Pod, metadata: {name: pod-c, namespace: team-c}, spec: {nodeName: node1, containers: [{name: app}]}, status: {phase: Running}}
+`
+		if err := os.WriteFile(dump, []byte(pods), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		buffer := &bytes.Buffer{}
+		cmd := NewCmdFree(tf, genericclioptions.IOStreams{In: os.Stdin, Out: buffer, ErrOut: buffer}, "v0.0.1", "abcd123", "1234567890")
+		if _, err := executeCommand(cmd, "--list", "--list-all", "--no-metrics", "--no-color", "--from-file", dump, "-n", "team-a", "--namespace", "team-b"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		out := buffer.String()
+		if !strings.Contains(out, "pod-a") || !strings.Contains(out, "pod-b") || strings.Contains(out, "pod-c") {
+			t.Errorf("expected pods of team-a and team-b (got: %q)", out)
+		}
+	})
+
+	t.Run("complete invalid pod selector", func(t *testing.T) {
+
+		o := &FreeOptions{
+			configFlags: genericclioptions.NewConfigFlags(true),
+			podSelector: "team==x==y",
+		}
+
+		if err := o.Complete(f, rootCmd, []string{}); err == nil {
+			t.Errorf("unexpected error: should return err")
+		}
+	})
 }
 
 func TestValidate(t *testing.T) {
@@ -381,6 +457,172 @@ func TestValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("validate column threshold", func(t *testing.T) {
+
+		o := &FreeOptions{
+			warnThreshold: 60,
+			critThreshold: 90,
+			thresholds:    thresholds{cpuLimThreshold: {warn: 250, crit: 150}},
+		}
+
+		err := o.Validate()
+		expected := "invalid threshold of cpu.lim: can not set critical threshold less than warn threshold (warn:250 crit:150)"
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected(%s) differ (got: %v)", expected, err)
+			return
+		}
+	})
+
+	t.Run("validate where", func(t *testing.T) {
+
+		o := &FreeOptions{
+			warnThreshold: 60,
+			critThreshold: 90,
+			whereText:     "status == Ready",
+			output:        treeOutputFormat,
+		}
+
+		err := o.Validate()
+		expected := "--where only filters the node table and the container list"
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected(%s) differ (got: %v)", expected, err)
+			return
+		}
+	})
+
+	t.Run("validate per-pod", func(t *testing.T) {
+
+		var tests = []struct {
+			description string
+			list        bool
+			oom         bool
+			expected    string
+		}{
+			{"without --list", false, false, "--per-pod requires --list"},
+			{"with --oom", true, true, "--per-pod can't be used with --oom"},
+		}
+
+		for _, test := range tests {
+			o := &FreeOptions{
+				perPod:         true,
+				list:           test.list,
+				oom:            test.oom,
+				limitThreshold: 90,
+			}
+
+			err := o.Validate()
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+			}
+		}
+	})
+
+	t.Run("validate vpa", func(t *testing.T) {
+
+		var tests = []struct {
+			description string
+			o           *FreeOptions
+			expected    string
+		}{
+			{
+				"without --list",
+				&FreeOptions{vpa: true, vpaDivergence: 50},
+				"--vpa requires --list and can't be used with --per-pod or --oom",
+			},
+			{
+				"with --per-pod",
+				&FreeOptions{vpa: true, vpaDivergence: 50, list: true, perPod: true},
+				"--vpa requires --list and can't be used with --per-pod or --oom",
+			},
+			{
+				"invalid divergence",
+				&FreeOptions{vpa: true, vpaDivergence: 0, list: true},
+				"--vpa-divergence must be greater than 0, not given 0",
+			},
+		}
+
+		for _, test := range tests {
+			err := test.o.Validate()
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+			}
+		}
+	})
+
+	t.Run("validate multi cluster", func(t *testing.T) {
+
+		var tests = []struct {
+			description string
+			o           *FreeOptions
+			expected    string
+		}{
+			{
+				"contexts and all contexts",
+				&FreeOptions{contexts: []string{"prod"}, allContexts: true},
+				"--contexts can't be used with --all-contexts",
+			},
+			{
+				"with --list",
+				&FreeOptions{contexts: []string{"prod"}, list: true},
+				"--contexts and --all-contexts only show the node summary",
+			},
+			{
+				"with --from-file",
+				&FreeOptions{allContexts: true, fromFiles: []string{"dump.json"}},
+				"--contexts and --all-contexts can't be used with --from-file",
+			},
+			{
+				"with prometheus",
+				&FreeOptions{allContexts: true, metricsSource: prometheusMetricsSource},
+				"--contexts and --all-contexts require --metrics-source metrics-server",
+			},
+		}
+
+		for _, test := range tests {
+			err := test.o.Validate()
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+			}
+		}
+	})
+
+	t.Run("validate metrics source", func(t *testing.T) {
+
+		var tests = []struct {
+			description string
+			o           *FreeOptions
+			expected    string
+		}{
+			{
+				"prometheus without url",
+				&FreeOptions{metricsSource: prometheusMetricsSource},
+				"--prometheus-url is required for --metrics-source prometheus",
+			},
+			{
+				"kubelet stats without kubelet",
+				&FreeOptions{metricsSource: metricsServerMetricsSource, kubeletStats: true},
+				"--kubelet-stats requires --metrics-source kubelet",
+			},
+			{
+				"usage window without prometheus",
+				&FreeOptions{metricsSource: metricsServerMetricsSource, usageWindow: time.Hour},
+				"--usage-window requires --metrics-source prometheus",
+			},
+			{
+				"invalid usage stat",
+				&FreeOptions{metricsSource: prometheusMetricsSource, prometheusURL: "http://localhost", usageWindow: time.Hour, usageStat: "p42"},
+				"usage stat must be one of avg, min, max, p50, p90, p95, p99 (usage-stat:p42)",
+			},
+		}
+
+		for _, test := range tests {
+			err := test.o.Validate()
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+			}
+		}
+	})
+
 	t.Run("validate success", func(t *testing.T) {
 
 		o := &FreeOptions{
@@ -408,8 +650,8 @@ func TestRun(t *testing.T) {
 			[]string{},
 			false,
 			[]string{
-				"NAME    STATUS   CPU/use   CPU/req   CPU/lim   CPU/alloc   CPU/use%   CPU/req%   CPU/lim%   MEM/use   MEM/req   MEM/lim   MEM/alloc   MEM/use%   MEM/req%   MEM/lim%",
-				"node1   Ready    100m      1         2         4           2%         25%        50%        1K        1K        2K        4K          25%        25%        50%",
+				"NAME  STATUS CPU/use CPU/req CPU/lim CPU/alloc CPU/use% CPU/req% CPU/lim% MEM/use MEM/req MEM/lim MEM/alloc MEM/use% MEM/req% MEM/lim%",
+				"node1 Ready  100m    1       2       4         2%       25%      50%      1K      1K      2K      4K        25%      25%      50%",
 				"",
 			},
 		},
@@ -418,8 +660,8 @@ func TestRun(t *testing.T) {
 			[]string{},
 			true,
 			[]string{
-				"NODE NAME   NAMESPACE   POD NAME   POD AGE     POD IP    POD STATUS   CONTAINER    CPU/use   CPU/req   CPU/lim   MEM/use   MEM/req   MEM/lim",
-				"node1       default     pod1       <unknown>   1.2.3.4   Running      container1   10m       1         2         0K        1K        2K",
+				"NODE NAME NAMESPACE POD NAME POD AGE   POD IP  POD STATUS QOS       PRIORITY CLASS CONTAINER  STATE RESTARTS LAST REASON CPU/use CPU/req CPU/lim MEM/use MEM/req MEM/lim",
+				"node1     default   pod1     <unknown> 1.2.3.4 Running    Burstable -              container1 -     0        -           10m     1       2       0K      1K      2K",
 				"",
 			},
 		},
@@ -428,20 +670,12 @@ func TestRun(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 
-			fakeNodeClient := fake.NewSimpleClientset(&testNodes[0])
-			fakePodClient := fake.NewSimpleClientset(&testPods[0])
-			fakeMetricsNodeClient := prepareTestNodeMetricsClient()
-			fakeMetricsPodClient := prepareTestPodMetricsClient()
-
 			buffer := &bytes.Buffer{}
 			o := &FreeOptions{
-				nocolor:           true,
-				table:             table.NewOutputTable(buffer),
-				list:              test.listOption,
-				nodeClient:        fakeNodeClient.CoreV1().Nodes(),
-				podClient:         fakePodClient.CoreV1().Pods("default"),
-				metricsPodClient:  fakeMetricsPodClient.MetricsV1beta1().PodMetricses("default"),
-				metricsNodeClient: fakeMetricsNodeClient.MetricsV1beta1().NodeMetricses(),
+				nocolor: true,
+				table:   table.NewOutputTable(buffer),
+				list:    test.listOption,
+				source:  newTestSource("default", testNodes[:1], testPods[:1]),
 			}
 
 			o.prepareFreeTableHeader()
@@ -624,7 +858,12 @@ func TestPrepareListTableHeader(t *testing.T) {
 				"POD AGE",
 				"POD IP",
 				"POD STATUS",
+				"QOS",
+				"PRIORITY CLASS",
 				"CONTAINER",
+				"STATE",
+				"RESTARTS",
+				"LAST REASON",
 				"CPU/req",
 				"CPU/lim",
 				"MEM/req",
@@ -644,7 +883,12 @@ func TestPrepareListTableHeader(t *testing.T) {
 				"POD AGE",
 				"POD IP",
 				"POD STATUS",
+				"QOS",
+				"PRIORITY CLASS",
 				"CONTAINER",
+				"STATE",
+				"RESTARTS",
+				"LAST REASON",
 				"CPU/use",
 				"CPU/req",
 				"CPU/lim",
@@ -666,7 +910,12 @@ func TestPrepareListTableHeader(t *testing.T) {
 				"POD AGE",
 				"POD IP",
 				"POD STATUS",
+				"QOS",
+				"PRIORITY CLASS",
 				"CONTAINER",
+				"STATE",
+				"RESTARTS",
+				"LAST REASON",
 				"CPU/req",
 				"CPU/lim",
 				"MEM/req",
@@ -687,7 +936,12 @@ func TestPrepareListTableHeader(t *testing.T) {
 				"POD AGE",
 				"POD IP",
 				colorStatus,
+				"QOS",
+				"PRIORITY CLASS",
 				"CONTAINER",
+				"STATE",
+				"RESTARTS",
+				"LAST REASON",
 				"CPU/req",
 				"CPU/lim",
 				"MEM/req",
@@ -874,6 +1128,103 @@ func TestToColorPercent(t *testing.T) {
 
 }
 
+func TestToColorColumnPercent(t *testing.T) {
+
+	o := &FreeOptions{
+		warnThreshold: 60,
+		critThreshold: 90,
+		thresholds:    thresholds{cpuLimThreshold: {warn: 150, crit: 250}},
+	}
+
+	var tests = []struct {
+		description string
+		column      string
+		p           int64
+		expected    string
+	}{
+		{"column threshold", cpuLimThreshold, 120, color.Green.Sprint("120%")},
+		{"column threshold warn", cpuLimThreshold, 200, color.Yellow.Sprint("200%")},
+		{"default threshold", memLimThreshold, 120, color.Red.Sprint("120%")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := o.toColorColumnPercent(test.column, test.p)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestThresholdsSet(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		values      []string
+		expected    string
+		expectedErr string
+	}{
+		{
+			"one column",
+			[]string{"cpu.lim=150:250"},
+			"cpu.lim=150:250",
+			"",
+		},
+		{
+			"several columns",
+			[]string{"mem.lim=100:120,cpu.use=70:90", "cpu.lim=150:250"},
+			"cpu.use=70:90,cpu.lim=150:250,mem.lim=100:120",
+			"",
+		},
+		{
+			"unknown column",
+			[]string{"disk.use=70:90"},
+			"",
+			`threshold must be <column>=<warn>:<crit> of the columns cpu.use, cpu.req, cpu.lim, mem.use, mem.req, mem.lim, not given "disk.use=70:90"`,
+		},
+		{
+			"without crit",
+			[]string{"cpu.lim=150"},
+			"",
+			`threshold of cpu.lim must be <warn>:<crit>, not given "150"`,
+		},
+		{
+			"invalid number",
+			[]string{"cpu.lim=150:high"},
+			"",
+			`invalid critical threshold of cpu.lim: strconv.ParseInt: parsing "high": invalid syntax`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			var th thresholds
+			var err error
+			for _, v := range test.values {
+				if err = th.Set(v); err != nil {
+					break
+				}
+			}
+
+			if test.expectedErr != "" {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if th.String() != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, th.String())
+			}
+		})
+	}
+}
+
 // Test Helper
 func executeCommand(root *cobra.Command, args ...string) (output string, err error) {
 	_, output, err = executeCommandC(root, args...)
@@ -890,18 +1241,7 @@ func executeCommandC(root *cobra.Command, args ...string) (c *cobra.Command, out
 	return c, buf.String(), err
 }
 
-func prepareTestNodeMetricsClient() *fakemetrics.Clientset {
-	fakeMetricsClient := &fakemetrics.Clientset{}
-	fakeMetricsClient.AddReactor("get", "nodes", func(action core.Action) (handled bool, ret runtime.Object, err error) {
-		return true, &testNodeMetrics.Items[0], nil
-	})
-	return fakeMetricsClient
-}
-
-func prepareTestPodMetricsClient() *fakemetrics.Clientset {
-	fakeMetricsClient := &fakemetrics.Clientset{}
-	fakeMetricsClient.AddReactor("list", "pods", func(action core.Action) (handled bool, ret runtime.Object, err error) {
-		return true, testPodMetrics, nil
-	})
-	return fakeMetricsClient
+// newTestSource returns a source serving the given nodes and pods with the test metrics
+func newTestSource(namespace string, nodes []v1.Node, pods []v1.Pod) *source.StaticSource {
+	return source.NewStaticSource(namespace, nodes, pods, testNodeMetrics.Items, testPodMetrics.Items)
 }
@@ -5,12 +5,44 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/thirdeyenick/kubectl-free/pkg/constants"
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
 	"github.com/thirdeyenick/kubectl-free/pkg/util"
 
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// nodeFree is the calculated resource usage of a node
+type nodeFree struct {
+	name   string
+	status string
+
+	cpuUsed        int64
+	cpuRequested   int64
+	cpuLimited     int64
+	cpuAllocatable int64
+
+	memUsed        int64
+	memRequested   int64
+	memLimited     int64
+	memAllocatable int64
+
+	// usage is available (metrics)
+	hasUsage bool
+
+	podCount       int
+	podAllocatable int64
+	containerCount int
+	oomCount       int
+
+	// kubelet stats (--kubelet-stats)
+	fsUsed        int64
+	fsCapacity    int64
+	ephemeralUsed int64
+	netRx         int64
+	netTx         int64
+}
+
 // showFree prints requested and allocatable resources
 func (o *FreeOptions) showFree(ctx context.Context, nodes []v1.Node) error {
 
@@ -19,129 +51,244 @@ func (o *FreeOptions) showFree(ctx context.Context, nodes []v1.Node) error {
 		o.table.Header = o.freeTableHeaders
 	}
 
+	samples := map[string]nodeFree{}
+
 	// node loop
 	for _, node := range nodes {
 
-		var (
-			cpuMetricsUsed  int64
-			memMetricsUsed  int64
-			cpuMetricsUsedP int64
-			memMetricsUsedP int64
-		)
-
-		// node name
-		nodeName := node.ObjectMeta.Name
-
-		// node status
-		nodeStatus, err := util.GetNodeStatus(node, o.emojiStatus)
+		nf, err := o.getNodeFree(ctx, node)
 		if err != nil {
 			return err
 		}
 
-		util.SetNodeStatusColor(&nodeStatus, o.nocolor)
+		// filter rows (--where)
+		match, err := o.matchNode(nf)
+		if err != nil {
+			return err
+		}
+		if !match {
+			continue
+		}
 
-		// get pods on node
-		pods, perr := util.GetPods(ctx, o.podClient, nodeName)
-		if perr != nil {
-			return perr
+		var prev *nodeFree
+		if p, ok := o.prevNodeFree[nf.name]; ok {
+			prev = &p
 		}
 
-		// calculate requested resources by pods
-		cpuRequested, memRequested, cpuLimited, memLimited := util.GetPodResources(*pods)
+		o.table.AddRow(o.nodeFreeToRow(nf, prev))
+		samples[nf.name] = nf
+	}
+
+	o.table.Print()
 
-		// get cpu allocatable
-		cpuAllocatable := node.Status.Allocatable.Cpu().MilliValue()
+	// remember this sample to show deltas on the next run (--watch)
+	o.prevNodeFree = samples
 
-		// get memoly allocatable
-		memAllocatable := node.Status.Allocatable.Memory().Value()
+	return nil
+}
 
-		// get usage
-		cpuRequestedP := util.GetPercentage(cpuRequested, cpuAllocatable)
-		cpuLimitedP := util.GetPercentage(cpuLimited, cpuAllocatable)
-		memRequestedP := util.GetPercentage(memRequested, memAllocatable)
-		memLimitedP := util.GetPercentage(memLimited, memAllocatable)
+// getNodeFree calculates requested, limited, used and allocatable resources of a node
+func (o *FreeOptions) getNodeFree(ctx context.Context, node v1.Node) (nodeFree, error) {
 
-		// get metrics
-		if !o.noMetrics && o.metricsNodeClient != nil {
-			nodeMetrics, err := o.metricsNodeClient.Get(ctx, nodeName, metav1.GetOptions{})
+	// node name
+	nf := nodeFree{
+		name: node.ObjectMeta.Name,
+	}
+
+	// node status
+	status, err := util.GetNodeStatus(node, false)
+	if err != nil {
+		return nf, err
+	}
+	nf.status = status
+
+	// get pods on node
+	pods, perr := o.source.GetPods(ctx, nf.name)
+	if perr != nil {
+		return nf, perr
+	}
+
+	// calculate requested resources by pods
+	nf.cpuRequested, nf.memRequested, nf.cpuLimited, nf.memLimited = util.GetPodResources(*pods)
+
+	// get cpu allocatable
+	nf.cpuAllocatable = node.Status.Allocatable.Cpu().MilliValue()
+
+	// get memoly allocatable
+	nf.memAllocatable = node.Status.Allocatable.Memory().Value()
+
+	// get metrics
+	if !o.noMetrics {
+		nodeMetrics, err := o.source.GetNodeMetrics(ctx, nf.name)
+		if err == nil {
+			nf.cpuUsed = nodeMetrics.Usage.Cpu().MilliValue()
+			nf.memUsed = nodeMetrics.Usage.Memory().Value()
+			nf.hasUsage = true
+		}
+		// ignore fetching metrics error
+	}
+
+	// pod and container count
+	nf.podCount = util.GetPodCount(*pods)
+	nf.containerCount = util.GetContainerCount(*pods)
+	nf.podAllocatable = node.Status.Allocatable.Pods().Value()
+
+	// containers killed for running out of memory
+	nf.oomCount = util.GetOOMKilledCount(*pods)
+
+	// filesystem, ephemeral storage and network usage (--kubelet-stats)
+	if o.kubeletStats {
+		if statsSource, ok := o.metrics.(source.StatsSource); ok {
+			stats, err := statsSource.GetNodeStats(ctx, nf.name)
 			if err == nil {
-				cpuMetricsUsed = nodeMetrics.Usage.Cpu().MilliValue()
-				memMetricsUsed = nodeMetrics.Usage.Memory().Value()
-				cpuMetricsUsedP = util.GetPercentage(cpuMetricsUsed, cpuAllocatable)
-				memMetricsUsedP = util.GetPercentage(memMetricsUsed, memAllocatable)
+				nf.fsUsed = stats.FsUsed
+				nf.fsCapacity = stats.FsCapacity
+				nf.ephemeralUsed = stats.EphemeralUsed
+				nf.netRx = stats.NetworkRx
+				nf.netTx = stats.NetworkTx
 			}
-			// ignore fetching metrics error
+			// ignore fetching stats error
 		}
+	}
 
-		// create table row
-		// basic row
-		row := []string{
-			nodeName,   // node name
-			nodeStatus, // node status
-		}
+	return nf, nil
+}
 
-		// cpu
-		if !o.noMetrics {
-			row = append(row, o.toMilliUnitOrDash(cpuMetricsUsed)) // cpu used (from metrics)
-		}
-		row = append(
-			row,
-			o.toMilliUnitOrDash(cpuRequested),   // cpu requested
-			o.toMilliUnitOrDash(cpuLimited),     // cpu limited
-			o.toMilliUnitOrDash(cpuAllocatable), // cpu allocatable
-		)
-		if !o.noMetrics {
-			row = append(row, o.toColorPercent(cpuMetricsUsedP)) // cpu used %
-		}
-		row = append(
-			row,
-			o.toColorPercent(cpuRequestedP), // cpu requested %
-			o.toColorPercent(cpuLimitedP),   // cpu limited %
-		)
+// nodeFreePercentages is the usage of a node in percent of its allocatable resources
+type nodeFreePercentages struct {
+	cpuUsed      int64
+	cpuRequested int64
+	cpuLimited   int64
+	memUsed      int64
+	memRequested int64
+	memLimited   int64
+}
 
-		// mem
-		if !o.noMetrics {
-			row = append(row, o.toUnitOrDash(memMetricsUsed)) // mem used (from metrics)
-		}
-		row = append(
-			row,
-			o.toUnitOrDash(memRequested),   // mem requested
-			o.toUnitOrDash(memLimited),     // mem limited
-			o.toUnitOrDash(memAllocatable), // mem allocatable
-		)
-		if !o.noMetrics {
-			row = append(row, o.toColorPercent(memMetricsUsedP)) // mem used %
+// percentages calculates the usage of a node in percent
+func (nf nodeFree) percentages() nodeFreePercentages {
+	return nodeFreePercentages{
+		cpuUsed:      util.GetPercentage(nf.cpuUsed, nf.cpuAllocatable),
+		cpuRequested: util.GetPercentage(nf.cpuRequested, nf.cpuAllocatable),
+		cpuLimited:   util.GetPercentage(nf.cpuLimited, nf.cpuAllocatable),
+		memUsed:      util.GetPercentage(nf.memUsed, nf.memAllocatable),
+		memRequested: util.GetPercentage(nf.memRequested, nf.memAllocatable),
+		memLimited:   util.GetPercentage(nf.memLimited, nf.memAllocatable),
+	}
+}
+
+// nodeFreeToRow creates a table row of a node
+// If prev is not nil, percentages are marked with the change since prev.
+func (o *FreeOptions) nodeFreeToRow(nf nodeFree, prev *nodeFree) []string {
+
+	// get usage
+	p := nf.percentages()
+
+	// previous usage
+	var pp nodeFreePercentages
+	if prev != nil {
+		pp = prev.percentages()
+	}
+
+	percent := func(column string, cur, before int64) string {
+		s := o.toColorColumnPercent(column, cur)
+		if prev != nil {
+			s += deltaMark(cur, before)
 		}
-		row = append(
-			row,
-			o.toColorPercent(memRequestedP), // mem requested %
-			o.toColorPercent(memLimitedP),   // mem limited %
-		)
+		return s
+	}
 
-		// show pod and container (--pod option)
-		if o.pod {
+	// node status
+	nodeStatus := nf.status
+	if o.emojiStatus {
+		nodeStatus = util.GetNodeStatusEmoji(nodeStatus)
+	}
+	util.SetNodeStatusColor(&nodeStatus, o.nocolor)
 
-			// pod count
-			podCount := util.GetPodCount(*pods)
+	// create table row
+	// basic row
+	row := []string{
+		nf.name,    // node name
+		nodeStatus, // node status
+	}
 
-			// container count
-			containerCount := util.GetContainerCount(*pods)
+	// cpu
+	if !o.noMetrics {
+		row = append(row, o.toMilliUnitOrDash(nf.cpuUsed)) // cpu used (from metrics)
+	}
+	row = append(
+		row,
+		o.toMilliUnitOrDash(nf.cpuRequested),   // cpu requested
+		o.toMilliUnitOrDash(nf.cpuLimited),     // cpu limited
+		o.toMilliUnitOrDash(nf.cpuAllocatable), // cpu allocatable
+	)
+	if !o.noMetrics {
+		row = append(row, percent(cpuUseThreshold, p.cpuUsed, pp.cpuUsed)) // cpu used %
+	}
+	row = append(
+		row,
+		percent(cpuReqThreshold, p.cpuRequested, pp.cpuRequested), // cpu requested %
+		percent(cpuLimThreshold, p.cpuLimited, pp.cpuLimited),     // cpu limited %
+	)
 
-			// get pod allocatable
-			podAllocatable := node.Status.Allocatable.Pods().Value()
+	// mem
+	if !o.noMetrics {
+		row = append(row, o.toUnitOrDash(nf.memUsed)) // mem used (from metrics)
+	}
+	row = append(
+		row,
+		o.toUnitOrDash(nf.memRequested),   // mem requested
+		o.toUnitOrDash(nf.memLimited),     // mem limited
+		o.toUnitOrDash(nf.memAllocatable), // mem allocatable
+	)
+	if !o.noMetrics {
+		row = append(row, percent(memUseThreshold, p.memUsed, pp.memUsed)) // mem used %
+	}
+	row = append(
+		row,
+		percent(memReqThreshold, p.memRequested, pp.memRequested), // mem requested %
+		percent(memLimThreshold, p.memLimited, pp.memLimited),     // mem limited %
+	)
 
-			row = append(
-				row,
-				fmt.Sprintf("%d", podCount),           // pod used
-				strconv.FormatInt(podAllocatable, 10), // pod allocatable
-				fmt.Sprintf("%d", containerCount),     // containers
-			)
-		}
+	// show pod and container (--pod option)
+	if o.pod {
+		row = append(
+			row,
+			fmt.Sprintf("%d", nf.podCount),           // pod used
+			strconv.FormatInt(nf.podAllocatable, 10), // pod allocatable
+			fmt.Sprintf("%d", nf.containerCount),     // containers
+		)
+	}
 
-		o.table.AddRow(row)
+	// show containers killed for running out of memory (--oom option)
+	if o.oom {
+		row = append(row, fmt.Sprintf("%d", nf.oomCount)) // oom killed containers
 	}
 
-	o.table.Print()
+	// show filesystem, ephemeral storage and network usage (--kubelet-stats)
+	if o.kubeletStats {
+		fsPercent := util.GetPercentage(nf.fsUsed, nf.fsCapacity)
+		row = append(
+			row,
+			o.toUnitOrDash(nf.fsUsed),        // fs used
+			o.toUnitOrDash(nf.fsCapacity),    // fs capacity
+			o.toColorPercent(fsPercent),      // fs used %
+			o.toUnitOrDash(nf.ephemeralUsed), // ephemeral storage used by pods
+			o.toUnitOrDash(nf.netRx),         // network received
+			o.toUnitOrDash(nf.netTx),         // network transmitted
+		)
+	}
 
-	return nil
+	return row
+}
+
+// deltaMark returns an arrow showing the direction of the change from prev to cur
+func deltaMark(cur, prev int64) string {
+	switch {
+	case cur > prev:
+		return constants.DeltaUp
+	case cur < prev:
+		return constants.DeltaDown
+	default:
+		return ""
+	}
 }
@@ -3,13 +3,14 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
 	"github.com/thirdeyenick/kubectl-free/pkg/table"
 
 	v1 "k8s.io/api/core/v1"
-	fake "k8s.io/client-go/kubernetes/fake"
 )
 
 func TestShowFree(t *testing.T) {
@@ -31,7 +32,7 @@ func TestShowFree(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node1   Ready   1     2     4     25%   50%   1K    2K    4K    25%   50%",
+				"node1 Ready 1 2 4 25% 50% 1K 2K 4K 25% 50%",
 				"",
 			},
 			nil,
@@ -43,7 +44,7 @@ func TestShowFree(t *testing.T) {
 			true,
 			false,
 			[]string{
-				"node1   Ready   100m   1     2     4     2%    25%   50%   1K    1K    2K    4K    25%   25%   50%",
+				"node1 Ready 100m 1 2 4 2% 25% 50% 1K 1K 2K 4K 25% 25% 50%",
 				"",
 			},
 			nil,
@@ -55,7 +56,7 @@ func TestShowFree(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node1   Ready   1     2     4     25%   50%   1K    2K    4K    25%   50%   1     110   1",
+				"node1 Ready 1 2 4 25% 50% 1K 2K 4K 25% 50% 1 110 1",
 				"",
 			},
 			nil,
@@ -67,7 +68,7 @@ func TestShowFree(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node1   Ready   200m   200m   4     5%    5%    0K    0K    4K    7%    7%    1     110   2",
+				"node1 Ready 200m 200m 4 5% 5% 0K 0K 4K 7% 7% 1 110 2",
 				"",
 			},
 			nil,
@@ -77,23 +78,15 @@ func TestShowFree(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 
-			fakeNodeClient := fake.NewSimpleClientset(&testNodes[0])
-			fakePodClient := fake.NewSimpleClientset(&testPods[0], &testPods[2])
-			fakeMetricsPodClient := prepareTestPodMetricsClient()
-			fakeMetricsNodeClient := prepareTestNodeMetricsClient()
-
 			buffer := &bytes.Buffer{}
 			o := &FreeOptions{
-				nocolor:           true,
-				table:             table.NewOutputTable(buffer),
-				list:              false,
-				pod:               test.pod,
-				noHeaders:         true,
-				noMetrics:         test.nometrics,
-				nodeClient:        fakeNodeClient.CoreV1().Nodes(),
-				podClient:         fakePodClient.CoreV1().Pods(test.namespace),
-				metricsPodClient:  fakeMetricsPodClient.MetricsV1beta1().PodMetricses("default"),
-				metricsNodeClient: fakeMetricsNodeClient.MetricsV1beta1().NodeMetricses(),
+				nocolor:   true,
+				table:     table.NewOutputTable(buffer),
+				list:      false,
+				pod:       test.pod,
+				noHeaders: true,
+				noMetrics: test.nometrics,
+				source:    newTestSource(test.namespace, testNodes[:1], []v1.Pod{testPods[0], testPods[2]}),
 			}
 
 			if err := o.showFree(ctx, []v1.Node{testNodes[0]}); err != nil {
@@ -112,24 +105,16 @@ func TestShowFree(t *testing.T) {
 
 	t.Run("Allnamespace", func(t *testing.T) {
 
-		fakeNodeClient := fake.NewSimpleClientset(&testNodes[0])
-		fakePodClient := fake.NewSimpleClientset(&testPods[0], &testPods[1], &testPods[2])
-		fakeMetricsPodClient := prepareTestPodMetricsClient()
-		fakeMetricsNodeClient := prepareTestNodeMetricsClient()
-
 		buffer := &bytes.Buffer{}
 		o := &FreeOptions{
-			nocolor:           true,
-			table:             table.NewOutputTable(buffer),
-			list:              false,
-			pod:               false,
-			allNamespaces:     true,
-			noHeaders:         true,
-			noMetrics:         true,
-			nodeClient:        fakeNodeClient.CoreV1().Nodes(),
-			podClient:         fakePodClient.CoreV1().Pods(""),
-			metricsPodClient:  fakeMetricsPodClient.MetricsV1beta1().PodMetricses("default"),
-			metricsNodeClient: fakeMetricsNodeClient.MetricsV1beta1().NodeMetricses(),
+			nocolor:       true,
+			table:         table.NewOutputTable(buffer),
+			list:          false,
+			pod:           false,
+			allNamespaces: true,
+			noHeaders:     true,
+			noMetrics:     true,
+			source:        newTestSource("", testNodes[:1], testPods),
 		}
 
 		if err := o.showFree(ctx, []v1.Node{testNodes[0]}); err != nil {
@@ -138,7 +123,7 @@ func TestShowFree(t *testing.T) {
 		}
 
 		expected := []string{
-			"node1   Ready   1700m   2700m   4     42%   67%   2K    3K    4K    57%   82%",
+			"node1 Ready 1700m 2700m 4 42% 67% 2K 3K 4K 57% 82%",
 			"",
 		}
 		e := strings.Join(expected, "\n")
@@ -149,3 +134,56 @@ func TestShowFree(t *testing.T) {
 
 	})
 }
+
+// testStatsSource serves fixed usage and stats of nodes
+type testStatsSource struct {
+	source.MetricsSource
+	stats map[string]*source.NodeStats
+}
+
+// GetNodeStats returns stats of a node
+func (s *testStatsSource) GetNodeStats(_ context.Context, nodeName string) (*source.NodeStats, error) {
+	stats, ok := s.stats[nodeName]
+	if !ok {
+		return nil, fmt.Errorf("no stats of node %q", nodeName)
+	}
+	return stats, nil
+}
+
+func TestShowFreeKubeletStats(t *testing.T) {
+	ctx := context.Background()
+
+	staticSource := newTestSource("default", testNodes, testPods[:1])
+	stats := &testStatsSource{
+		MetricsSource: staticSource,
+		stats: map[string]*source.NodeStats{
+			"node1": {FsUsed: 3000, FsCapacity: 4000, EphemeralUsed: 1000, NetworkRx: 5000, NetworkTx: 6000},
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:      true,
+		table:        table.NewOutputTable(buffer),
+		noHeaders:    true,
+		noMetrics:    true,
+		kByte:        true,
+		kubeletStats: true,
+		metrics:      stats,
+		source:       source.WithMetrics(staticSource, stats),
+	}
+
+	if err := o.showFree(ctx, testNodes); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		"node1 Ready    1 2 4 25% 50% 1K 2K 4K 25% 50% 3K 4K 75% 1K 5K 6K",
+		"node2 NotReady - - 8 0%  0%  -  -  8K 0%  0%  -  -  0%  -  -  -",
+		"",
+	}, "\n")
+	if buffer.String() != expected {
+		t.Errorf("expected(%q) differ (got: %q)", expected, buffer.String())
+	}
+}
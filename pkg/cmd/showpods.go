@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/thirdeyenick/kubectl-free/pkg/util"
@@ -9,74 +11,98 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/duration"
+	resourcehelper "k8s.io/kubectl/pkg/util/resource"
 	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
-type formattedResource struct {
-	*resource.Quantity
-	formatted string
-}
-
+// podInfo is the calculated resource usage of a container in a pod
+// Usage is nil if no metrics are available for the container.
 type podInfo struct {
 	nodeName                 string
 	podNamespace             string
 	podName                  string
 	podAge                   string
+	podCreated               time.Time
 	podIP                    string
+	podPhase                 string
 	podStatus                string
+	podQOSClass              string
+	podPriorityClass         string
+	podPriority              int32
+	podWorkload              string
 	containerName            string
-	containerCPUUsed         *formattedResource
-	containerCPURequested    string
-	containerCPULimit        string
-	containerMemoryUsed      *formattedResource
-	containerMemoryRequested string
-	containerMemoryLimit     string
+	containerCount           int
+	containerState           string
+	containerRestarts        int32
+	containerLastReason      string
+	containerOOMKilled       bool
+	containerCPUUsed         *resource.Quantity
+	containerCPURequested    int64
+	containerCPULimit        int64
+	containerMemoryUsed      *resource.Quantity
+	containerMemoryRequested int64
+	containerMemoryLimit     int64
 	containerImage           string
+
+	// recommendation of a VerticalPodAutoscaler (--vpa), nil without recommendation
+	vpa *vpaRecommendation
+}
+
+// key identifies the container of a pod across samples
+func (info podInfo) key() string {
+	return info.nodeName + "/" + info.podNamespace + "/" + info.podName + "/" + info.containerName
 }
 
 func (o *FreeOptions) sortEntries(items []podInfo) []podInfo {
 	switch o.sortByResource {
 	case memorySortResource:
 		slices.SortFunc(items, func(a, b podInfo) bool {
-			if a.containerMemoryUsed == nil && b.containerMemoryUsed == nil {
-				return false
-			}
-			if a.containerMemoryUsed != nil && b.containerMemoryUsed == nil {
-				return false
-			}
-			if a.containerMemoryUsed == nil && b.containerMemoryUsed != nil {
-				return true
-			}
-			return a.containerMemoryUsed.Quantity.Cmp(*b.containerMemoryUsed.Quantity) < 0
+			return lessUsage(a.containerMemoryUsed, b.containerMemoryUsed)
 		})
 	case cpuSortResource:
 		slices.SortFunc(items, func(a, b podInfo) bool {
-			if a.containerCPUUsed == nil && b.containerCPUUsed == nil {
-				return false
-			}
-			if a.containerCPUUsed != nil && b.containerCPUUsed == nil {
-				return false
-			}
-			if a.containerCPUUsed == nil && b.containerCPUUsed != nil {
-				return true
-			}
-			return a.containerCPUUsed.Quantity.Cmp(*b.containerCPUUsed.Quantity) < 0
+			return lessUsage(a.containerCPUUsed, b.containerCPUUsed)
 		})
 	}
 	return items
 }
 
-func (o *FreeOptions) podInfoToRow(info podInfo) []string {
+// lessUsage reports whether usage a is less than b
+// Missing usage is less than any usage.
+func lessUsage(a, b *resource.Quantity) bool {
+	if a == nil && b == nil {
+		return false
+	}
+	if a != nil && b == nil {
+		return false
+	}
+	if a == nil && b != nil {
+		return true
+	}
+	return a.Cmp(*b) < 0
+}
+
+// podInfoToRow creates a table row of a container
+// If prev is not nil, usage is marked with the change since prev.
+func (o *FreeOptions) podInfoToRow(info podInfo, prev *podInfo) []string {
+
+	podStatus := util.GetPodStatus(info.podStatus, o.nocolor, o.emojiStatus)
+
+	priorityClass := info.podPriorityClass
+	if priorityClass == "" {
+		priorityClass = "-"
+	}
+
 	var result []string
 	if o.compactView {
 		result = []string{
 			info.nodeName,
 			info.podNamespace,
 			info.podName,
-			info.podStatus,
-			info.containerName,
+			podStatus,
+			info.podQOSClass,
+			priorityClass,
 		}
 	} else {
 		result = []string{
@@ -85,22 +111,61 @@ func (o *FreeOptions) podInfoToRow(info podInfo) []string {
 			info.podName,
 			info.podAge,
 			info.podIP,
-			info.podStatus,
-			info.containerName,
+			podStatus,
+			info.podQOSClass,
+			priorityClass,
 		}
 	}
 
-	if !o.noMetrics && info.containerCPUUsed != nil {
-		result = append(result, info.containerCPUUsed.formatted)
+	// containers of the pod (--per-pod) or the container
+	switch {
+	case o.perPod:
+		result = append(result, fmt.Sprintf("%d", info.containerCount))
+	case o.compactView:
+		result = append(result, info.containerName)
+	default:
+		result = append(result, info.containerName, info.containerState)
 	}
-	if !o.compactView {
-		result = append(result, info.containerCPURequested, info.containerCPULimit)
+	result = append(result, fmt.Sprintf("%d", info.containerRestarts), info.containerLastReason)
+
+	if !o.noMetrics {
+		cpuUsed := "-"
+		if info.containerCPUUsed != nil {
+			cpuUsed = o.toMilliUnitOrDash(info.containerCPUUsed.MilliValue())
+			if prev != nil && prev.containerCPUUsed != nil {
+				cpuUsed += deltaMark(info.containerCPUUsed.MilliValue(), prev.containerCPUUsed.MilliValue())
+			}
+		}
+		result = append(result, cpuUsed)
 	}
-	if !o.noMetrics && info.containerMemoryUsed != nil {
-		result = append(result, info.containerMemoryUsed.formatted)
+	// without metrics, the compact view shows requests/limits instead
+	if !o.compactView || o.noMetrics {
+		result = append(result, o.toMilliUnitOrDash(info.containerCPURequested), o.toMilliUnitOrDash(info.containerCPULimit))
+	} else if o.vpa {
+		// requests next to recommendations
+		result = append(result, o.toMilliUnitOrDash(info.containerCPURequested))
 	}
-	if !o.compactView {
-		result = append(result, info.containerMemoryRequested, info.containerCPULimit)
+	if o.vpa {
+		result = append(result, o.vpaCPUColumns(info)...)
+	}
+	if !o.noMetrics {
+		memUsed := "-"
+		if info.containerMemoryUsed != nil {
+			memUsed = o.toUnitOrDash(info.containerMemoryUsed.Value())
+			if prev != nil && prev.containerMemoryUsed != nil {
+				memUsed += deltaMark(info.containerMemoryUsed.Value(), prev.containerMemoryUsed.Value())
+			}
+		}
+		result = append(result, memUsed)
+	}
+	if !o.compactView || o.noMetrics {
+		result = append(result, o.toUnitOrDash(info.containerMemoryRequested), o.toUnitOrDash(info.containerMemoryLimit))
+	} else if o.vpa {
+		result = append(result, o.toUnitOrDash(info.containerMemoryRequested))
+	}
+	if o.vpa {
+		result = append(result, o.vpaMemoryColumns(info)...)
+		result = append(result, o.vpaStatus(info))
 	}
 	if o.listContainerImage {
 		result = append(result, info.containerImage)
@@ -116,100 +181,211 @@ func (o *FreeOptions) showPodsOnNode(ctx context.Context, nodes []v1.Node) error
 	}
 
 	// get pod metrics
-	var podMetrics *metricsapiv1beta1.PodMetricsList
-	if !o.noMetrics && o.metricsPodClient != nil {
-		podMetrics, _ = o.metricsPodClient.List(ctx, metav1.ListOptions{})
+	podMetrics := o.getPodMetrics(ctx)
+
+	// get recommendations of VerticalPodAutoscalers (--vpa)
+	var recommendations map[string]vpaRecommendation
+	if o.vpa {
+		recommendations = o.getVPARecommendations(ctx)
 	}
 
+	samples := map[string]podInfo{}
+
 	// node loop
 	for _, node := range nodes {
 
-		// node name
-		nodeName := node.ObjectMeta.Name
-
-		// get pods on node
-		pods, perr := util.GetPods(ctx, o.podClient, nodeName)
-		if perr != nil {
-			return perr
-		}
-		nodePods := []podInfo{}
-
-		// node loop
-		for _, pod := range pods.Items {
-			// pod information
-			podName := pod.ObjectMeta.Name
-			podNamespace := pod.ObjectMeta.Namespace
-			podIP := pod.Status.PodIP
-			podStatus := util.GetPodStatus(string(pod.Status.Phase), o.nocolor, o.emojiStatus)
-			podCreationTime := pod.ObjectMeta.CreationTimestamp.UTC()
-			podCreationTimeDiff := time.Since(podCreationTime)
-			podAge := "<unknown>"
-			if !podCreationTime.IsZero() {
-				podAge = duration.HumanDuration(podCreationTimeDiff)
+		nodePods, err := o.getPodInfos(ctx, node, podMetrics)
+		if err != nil {
+			return err
+		}
+
+		if !o.noMetrics {
+			nodePods = o.sortEntries(nodePods)
+		}
+		for _, containerOfPod := range nodePods {
+			if r, ok := recommendations[vpaKey(containerOfPod.podNamespace, containerOfPod.podWorkload, containerOfPod.containerName)]; ok {
+				containerOfPod.vpa = &r
 			}
-			// container loop
-			for _, container := range pod.Spec.Containers {
-				containerName := container.Name
-				containerImage := container.Image
-				cCpuRequested := container.Resources.Requests.Cpu().MilliValue()
-				cCpuLimit := container.Resources.Limits.Cpu().MilliValue()
-				cMemRequested := container.Resources.Requests.Memory().Value()
-				cMemLimit := container.Resources.Limits.Memory().Value()
-
-				row := podInfo{
-					nodeName:      nodeName,      // node name
-					podNamespace:  podNamespace,  // namespace
-					podName:       podName,       // pod name
-					podAge:        podAge,        // pod age
-					podIP:         podIP,         // pod ip
-					podStatus:     podStatus,     // pod status
-					containerName: containerName, // container name
-				}
 
-				if !o.noMetrics && podMetrics != nil {
-					cpuUsed, memoryUsed := util.GetContainerMetrics(podMetrics, podName, containerName)
-					if cpuUsed != nil {
-						row.containerCPUUsed = &formattedResource{
-							Quantity:  cpuUsed,
-							formatted: o.toMilliUnitOrDash(cpuUsed.MilliValue()),
-						}
-					}
-					if memoryUsed != nil {
-						row.containerMemoryUsed = &formattedResource{
-							Quantity:  memoryUsed,
-							formatted: o.toUnitOrDash(memoryUsed.Value()),
-						}
-					}
-				}
+			// filter rows (--where)
+			match, err := o.matchPod(containerOfPod)
+			if err != nil {
+				return err
+			}
+			if !match {
+				continue
+			}
 
-				// skip if the requested/limit resources are not set
-				if !o.listAll {
-					if cCpuRequested == 0 && cCpuLimit == 0 && cMemRequested == 0 && cMemLimit == 0 {
-						continue
-					}
-				}
+			var prev *podInfo
+			if p, ok := o.prevContainers[containerOfPod.key()]; ok {
+				prev = &p
+			}
+			o.table.AddRow(o.podInfoToRow(containerOfPod, prev))
+			samples[containerOfPod.key()] = containerOfPod
+		}
+	}
+	o.table.Print()
 
-				row.containerCPURequested = o.toMilliUnitOrDash(cCpuRequested)
-				row.containerCPULimit = o.toMilliUnitOrDash(cCpuLimit)
-				row.containerMemoryRequested = o.toUnitOrDash(cMemRequested)
-				row.containerMemoryLimit = o.toUnitOrDash(cMemLimit)
+	// remember this sample to show deltas on the next run (--watch)
+	o.prevContainers = samples
 
-				if o.listContainerImage {
-					row.containerImage = containerImage
-				}
-				nodePods = append(nodePods, row)
+	return nil
+}
+
+// getPodMetrics returns metrics of all pods or nil if metrics are not available
+func (o *FreeOptions) getPodMetrics(ctx context.Context) *metricsapiv1beta1.PodMetricsList {
+	if o.noMetrics {
+		return nil
+	}
+
+	// ignore fetching metrics error
+	podMetrics, err := o.source.GetPodMetrics(ctx)
+	if err != nil {
+		return nil
+	}
+	return podMetrics
+}
+
+// getPodInfos returns resources of containers in pods on a node
+func (o *FreeOptions) getPodInfos(ctx context.Context, node v1.Node, podMetrics *metricsapiv1beta1.PodMetricsList) ([]podInfo, error) {
+
+	// node name
+	nodeName := node.ObjectMeta.Name
+
+	// get pods on node
+	pods, perr := o.source.GetPods(ctx, nodeName)
+	if perr != nil {
+		return nil, perr
+	}
+	nodePods := []podInfo{}
+
+	// pod loop
+	for _, pod := range pods.Items {
+		// pod information
+		podName := pod.ObjectMeta.Name
+		podNamespace := pod.ObjectMeta.Namespace
+		podIP := pod.Status.PodIP
+		podCreationTime := pod.ObjectMeta.CreationTimestamp.UTC()
+		podCreationTimeDiff := time.Since(podCreationTime)
+		podAge := "<unknown>"
+		if !podCreationTime.IsZero() {
+			podAge = duration.HumanDuration(podCreationTimeDiff)
+		}
+		podStatus := util.GetPodStatusReason(pod)
+		podQOSClass := util.GetPodQOSClass(pod)
+		var podPriority int32
+		if pod.Spec.Priority != nil {
+			podPriority = *pod.Spec.Priority
+		}
+		// status of containers by name
+		containerStatuses := map[string]v1.ContainerStatus{}
+		for _, status := range pod.Status.ContainerStatuses {
+			containerStatuses[status.Name] = status
+		}
+
+		// pod columns of all containers
+		base := podInfo{
+			nodeName:         nodeName,                   // node name
+			podNamespace:     podNamespace,               // namespace
+			podName:          podName,                    // pod name
+			podAge:           podAge,                     // pod age
+			podCreated:       podCreationTime,            // pod creation time
+			podIP:            podIP,                      // pod ip
+			podPhase:         string(pod.Status.Phase),   // pod phase
+			podStatus:        podStatus,                  // pod status
+			podQOSClass:      podQOSClass,                // qos class
+			podPriorityClass: pod.Spec.PriorityClassName, // priority class
+			podPriority:      podPriority,                // priority
+			podWorkload:      util.GetPodOwner(pod),      // workload
+		}
+
+		// container loop
+		rows := []podInfo{}
+		for _, container := range pod.Spec.Containers {
+			containerStatus := containerStatuses[container.Name]
+
+			row := base
+			row.containerName = container.Name                                           // container name
+			row.containerState = util.GetContainerState(containerStatus)                 // container state
+			row.containerRestarts = containerStatus.RestartCount                         // container restarts
+			row.containerLastReason = util.GetContainerLastReason(containerStatus)       // last termination reason
+			row.containerOOMKilled = util.IsOOMKilled(containerStatus)                   // killed for running out of memory
+			row.containerCPURequested = container.Resources.Requests.Cpu().MilliValue()  // cpu requested
+			row.containerCPULimit = container.Resources.Limits.Cpu().MilliValue()        // cpu limit
+			row.containerMemoryRequested = container.Resources.Requests.Memory().Value() // memory requested
+			row.containerMemoryLimit = container.Resources.Limits.Memory().Value()       // memory limit
+			row.containerImage = container.Image                                         // container image
+
+			if !o.noMetrics && podMetrics != nil {
+				row.containerCPUUsed, row.containerMemoryUsed = util.GetPodContainerMetrics(podMetrics, podNamespace, podName, container.Name)
 			}
+
+			rows = append(rows, row)
 		}
 
-		if !o.noMetrics {
-			nodePods = o.sortEntries(nodePods)
+		// one row per pod (--per-pod)
+		if o.perPod {
+			rows = []podInfo{newPodLevelInfo(base, pod, rows)}
 		}
-		for _, containerOfPod := range nodePods {
-			o.table.AddRow(o.podInfoToRow(containerOfPod))
 
+		for _, row := range rows {
+			// skip if the requested/limit resources are not set
+			if !o.listAll {
+				if row.containerCPURequested == 0 && row.containerCPULimit == 0 && row.containerMemoryRequested == 0 && row.containerMemoryLimit == 0 {
+					continue
+				}
+			}
+
+			nodePods = append(nodePods, row)
 		}
 	}
-	o.table.Print()
 
-	return nil
+	return nodePods, nil
+}
+
+// newPodLevelInfo sums containers of a pod into one row
+// Requests and limits are the effective ones of the pod as seen by the scheduler,
+// i.e. including init containers and pod overhead.
+func newPodLevelInfo(base podInfo, pod v1.Pod, containers []podInfo) podInfo {
+
+	info := base
+	info.containerCount = len(containers)
+	info.containerLastReason = "-"
+
+	requests, limits := resourcehelper.PodRequestsAndLimits(&pod)
+	info.containerCPURequested = requests.Cpu().MilliValue()
+	info.containerCPULimit = limits.Cpu().MilliValue()
+	info.containerMemoryRequested = requests.Memory().Value()
+	info.containerMemoryLimit = limits.Memory().Value()
+
+	images := []string{}
+	for _, c := range containers {
+		info.containerRestarts += c.containerRestarts
+		info.containerOOMKilled = info.containerOOMKilled || c.containerOOMKilled
+		if info.containerLastReason == "-" {
+			info.containerLastReason = c.containerLastReason
+		}
+		if !slices.Contains(images, c.containerImage) {
+			images = append(images, c.containerImage)
+		}
+
+		info.containerCPUUsed = addUsage(info.containerCPUUsed, c.containerCPUUsed)
+		info.containerMemoryUsed = addUsage(info.containerMemoryUsed, c.containerMemoryUsed)
+	}
+	info.containerImage = strings.Join(images, ",")
+
+	return info
+}
+
+// addUsage returns the sum of usage a and b
+// Missing usage is nil, the sum is only missing if both are missing.
+func addUsage(a, b *resource.Quantity) *resource.Quantity {
+	if b == nil {
+		return a
+	}
+	sum := b.DeepCopy()
+	if a != nil {
+		sum.Add(*a)
+	}
+	return &sum
 }
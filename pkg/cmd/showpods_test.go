@@ -6,11 +6,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
 	"github.com/thirdeyenick/kubectl-free/pkg/table"
 
 	v1 "k8s.io/api/core/v1"
-	fake "k8s.io/client-go/kubernetes/fake"
-	fakemetrics "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
 func TestShowPodsOnNode(t *testing.T) {
@@ -29,7 +31,7 @@ func TestShowPodsOnNode(t *testing.T) {
 			false,
 			false,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   -     500m   500m   -     1K    1K",
+				"node1 default pod2 <unknown> 2.3.4.5 Running Burstable - container2a - 0 - - 500m 500m - 1K 1K",
 				"",
 			},
 		},
@@ -39,7 +41,7 @@ func TestShowPodsOnNode(t *testing.T) {
 			false,
 			true,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K",
+				"node1 default pod2 <unknown> 2.3.4.5 Running Burstable - container2a - 0 - 500m 500m 1K 1K",
 				"",
 			},
 		},
@@ -49,7 +51,7 @@ func TestShowPodsOnNode(t *testing.T) {
 			false,
 			true,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K    nginx:latest",
+				"node1 default pod2 <unknown> 2.3.4.5 Running Burstable - container2a - 0 - 500m 500m 1K 1K nginx:latest",
 				"",
 			},
 		},
@@ -59,8 +61,8 @@ func TestShowPodsOnNode(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K",
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2b   -      -      -     -",
+				"node1 default pod2 <unknown> 2.3.4.5 Running Burstable - container2a - 0 - 500m 500m 1K 1K",
+				"node1 default pod2 <unknown> 2.3.4.5 Running Burstable - container2b - 0 - -    -    -  -",
 				"",
 			},
 		},
@@ -70,8 +72,8 @@ func TestShowPodsOnNode(t *testing.T) {
 			true,
 			true,
 			[]string{
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2a   500m   500m   1K    1K    nginx:latest",
-				"node2   default   pod2   <unknown>   2.3.4.5   Running   container2b   -      -      -     -     busybox:latest",
+				"node1 default pod2 <unknown> 2.3.4.5 Running Burstable - container2a - 0 - 500m 500m 1K 1K nginx:latest",
+				"node1 default pod2 <unknown> 2.3.4.5 Running Burstable - container2b - 0 - -    -    -  -  busybox:latest",
 				"",
 			},
 		},
@@ -80,9 +82,6 @@ func TestShowPodsOnNode(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
 			buffer := &bytes.Buffer{}
-			fakeClient := fake.NewSimpleClientset(&testPods[1])
-			fakeMetricsNodeClient := fakemetrics.NewSimpleClientset(&testNodeMetrics.Items[0])
-			fakeMetricsPodClient := fakemetrics.NewSimpleClientset(testPodMetrics)
 
 			o := &FreeOptions{
 				table:              table.NewOutputTable(buffer),
@@ -91,12 +90,10 @@ func TestShowPodsOnNode(t *testing.T) {
 				nocolor:            true,
 				listContainerImage: test.listContainer,
 				listAll:            test.listAll,
-				podClient:          fakeClient.CoreV1().Pods(""),
-				metricsPodClient:   fakeMetricsPodClient.MetricsV1beta1().PodMetricses("default"),
-				metricsNodeClient:  fakeMetricsNodeClient.MetricsV1beta1().NodeMetricses(),
+				source:             newTestSource("", testNodes, testPods[1:2]),
 			}
 
-			if err := o.showPodsOnNode(ctx, []v1.Node{testNodes[1]}); err != nil {
+			if err := o.showPodsOnNode(ctx, []v1.Node{testNodes[0]}); err != nil {
 				t.Errorf("unexpected error: %v", err)
 				return
 			}
@@ -110,3 +107,139 @@ func TestShowPodsOnNode(t *testing.T) {
 		})
 	}
 }
+
+func TestShowPodsOnNodeStatus(t *testing.T) {
+	ctx := context.Background()
+
+	pod := *testPods[0].DeepCopy()
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{
+			Name:                 "container1",
+			RestartCount:         5,
+			State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		table:     table.NewOutputTable(buffer),
+		noHeaders: true,
+		noMetrics: true,
+		nocolor:   true,
+		source:    newTestSource("", testNodes, []v1.Pod{pod}),
+	}
+
+	if err := o.showPodsOnNode(ctx, []v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := "node1 default pod1 <unknown> 1.2.3.4 CrashLoopBackOff Burstable - container1 CrashLoopBackOff 5 OOMKilled 1 2 1K 2K\n"
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+	}
+}
+
+func TestShowPodsOnNodePerPod(t *testing.T) {
+	ctx := context.Background()
+
+	// init container requests more cpu than the app containers
+	pod := *testPods[1].DeepCopy()
+	pod.Spec.InitContainers = []v1.Container{
+		{
+			Name: "init",
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: *resource.NewMilliQuantity(1000, resource.DecimalSI)},
+			},
+		},
+	}
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{Name: "container2a", RestartCount: 1},
+		{Name: "container2b", RestartCount: 2, LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Error"}}},
+	}
+
+	podMetrics := []metricsapiv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "default"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{
+				{Name: "container2a", Usage: v1.ResourceList{v1.ResourceCPU: *resource.NewMilliQuantity(100, resource.DecimalSI), v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI)}},
+				{Name: "container2b", Usage: v1.ResourceList{v1.ResourceCPU: *resource.NewMilliQuantity(50, resource.DecimalSI), v1.ResourceMemory: *resource.NewQuantity(2000, resource.DecimalSI)}},
+			},
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		table:              table.NewOutputTable(buffer),
+		nocolor:            true,
+		kByte:              true,
+		perPod:             true,
+		listContainerImage: true,
+		sortByResource:     memorySortResource,
+		source:             source.NewStaticSource("", testNodes, []v1.Pod{pod}, nil, podMetrics),
+	}
+	o.prepareListTableHeader()
+
+	if err := o.showPodsOnNode(ctx, []v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		"NODE NAME NAMESPACE POD NAME POD AGE   POD IP  POD STATUS QOS       PRIORITY CLASS CONTAINERS RESTARTS LAST REASON CPU/use CPU/req CPU/lim MEM/use MEM/req MEM/lim IMAGE",
+		"node1     default   pod2     <unknown> 2.3.4.5 Running    Burstable -              2          3        Error       150m    1       500m    3K      1K      1K      nginx:latest,busybox:latest",
+		"",
+	}, "\n")
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+	}
+}
+
+func TestShowPodsOnNodeSameName(t *testing.T) {
+	ctx := context.Background()
+
+	// pods of the same name in two namespaces
+	staging := *testPods[1].DeepCopy()
+	staging.ObjectMeta.Name = "web-0"
+	staging.ObjectMeta.Namespace = "staging"
+	staging.Spec.Containers = staging.Spec.Containers[:1]
+	prod := *staging.DeepCopy()
+	prod.ObjectMeta.Namespace = "prod"
+
+	podMetrics := []metricsapiv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "staging"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{
+				{Name: "container2a", Usage: v1.ResourceList{v1.ResourceCPU: *resource.NewMilliQuantity(10, resource.DecimalSI), v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI)}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "prod"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{
+				{Name: "container2a", Usage: v1.ResourceList{v1.ResourceCPU: *resource.NewMilliQuantity(400, resource.DecimalSI), v1.ResourceMemory: *resource.NewQuantity(3000, resource.DecimalSI)}},
+			},
+		},
+	}
+
+	o := &FreeOptions{
+		source: source.NewStaticSource("", testNodes, []v1.Pod{staging, prod}, nil, podMetrics),
+	}
+
+	infos, err := o.getPodInfos(ctx, testNodes[0], o.getPodMetrics(ctx))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := map[string]int64{"staging": 10, "prod": 400}
+	if len(infos) != len(expected) {
+		t.Errorf("expected(%d) differ (got: %d)", len(expected), len(infos))
+		return
+	}
+	for _, info := range infos {
+		if info.containerCPUUsed == nil || info.containerCPUUsed.MilliValue() != expected[info.podNamespace] {
+			t.Errorf("[%s] expected(%d) differ (got: %v)", info.podNamespace, expected[info.podNamespace], info.containerCPUUsed)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	// snapshotLong defines long description
+	snapshotLong = templates.LongDesc(`
+		Print resources of Kubernetes nodes and their containers as json.
+
+		The snapshot can be compared with another snapshot or the live cluster with "kubectl free diff".
+		Containers without requests/limits are always included.
+	`)
+
+	// snapshotExample defines command examples
+	snapshotExample = templates.Examples(`
+		# Save resources of all nodes before a release.
+		kubectl free snapshot > before.json
+
+		# Save resources of nodes matching a label selector.
+		kubectl free snapshot -l key=value > before.json
+	`)
+)
+
+// snapshot is the calculated resource usage of nodes and containers at a point in time
+type snapshot struct {
+	Time       time.Time           `json:"time"`
+	Namespace  string              `json:"namespace"`
+	Nodes      []snapshotNode      `json:"nodes"`
+	Containers []snapshotContainer `json:"containers"`
+}
+
+// snapshotNode is the json representation of nodeFree
+type snapshotNode struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	CPUUsed        int64  `json:"cpuUsed"`
+	CPURequested   int64  `json:"cpuRequested"`
+	CPULimited     int64  `json:"cpuLimited"`
+	CPUAllocatable int64  `json:"cpuAllocatable"`
+	MemUsed        int64  `json:"memUsed"`
+	MemRequested   int64  `json:"memRequested"`
+	MemLimited     int64  `json:"memLimited"`
+	MemAllocatable int64  `json:"memAllocatable"`
+	PodCount       int    `json:"podCount"`
+	PodAllocatable int64  `json:"podAllocatable"`
+	ContainerCount int    `json:"containerCount"`
+
+	// usage is available, not part of snapshot files
+	hasUsage bool
+}
+
+// snapshotContainer is the json representation of podInfo
+// Usage is nil if no metrics are available for the container.
+type snapshotContainer struct {
+	NodeName        string `json:"nodeName"`
+	Namespace       string `json:"namespace"`
+	PodName         string `json:"podName"`
+	PodPhase        string `json:"podPhase"`
+	PodIP           string `json:"podIP,omitempty"`
+	Name            string `json:"name"`
+	Image           string `json:"image"`
+	CPUUsed         *int64 `json:"cpuUsed,omitempty"`
+	CPURequested    int64  `json:"cpuRequested"`
+	CPULimit        int64  `json:"cpuLimit"`
+	MemoryUsed      *int64 `json:"memoryUsed,omitempty"`
+	MemoryRequested int64  `json:"memoryRequested"`
+	MemoryLimit     int64  `json:"memoryLimit"`
+}
+
+// newSnapshotNode converts nodeFree to snapshotNode
+func newSnapshotNode(nf nodeFree) snapshotNode {
+	return snapshotNode{
+		Name:           nf.name,
+		Status:         nf.status,
+		CPUUsed:        nf.cpuUsed,
+		CPURequested:   nf.cpuRequested,
+		CPULimited:     nf.cpuLimited,
+		CPUAllocatable: nf.cpuAllocatable,
+		MemUsed:        nf.memUsed,
+		MemRequested:   nf.memRequested,
+		MemLimited:     nf.memLimited,
+		MemAllocatable: nf.memAllocatable,
+		PodCount:       nf.podCount,
+		PodAllocatable: nf.podAllocatable,
+		ContainerCount: nf.containerCount,
+		hasUsage:       nf.hasUsage,
+	}
+}
+
+// newSnapshotContainer converts podInfo to snapshotContainer
+func newSnapshotContainer(info podInfo) snapshotContainer {
+	c := snapshotContainer{
+		NodeName:        info.nodeName,
+		Namespace:       info.podNamespace,
+		PodName:         info.podName,
+		PodPhase:        info.podPhase,
+		PodIP:           info.podIP,
+		Name:            info.containerName,
+		Image:           info.containerImage,
+		CPURequested:    info.containerCPURequested,
+		CPULimit:        info.containerCPULimit,
+		MemoryRequested: info.containerMemoryRequested,
+		MemoryLimit:     info.containerMemoryLimit,
+	}
+	if info.containerCPUUsed != nil {
+		v := info.containerCPUUsed.MilliValue()
+		c.CPUUsed = &v
+	}
+	if info.containerMemoryUsed != nil {
+		v := info.containerMemoryUsed.Value()
+		c.MemoryUsed = &v
+	}
+	return c
+}
+
+// NewCmdSnapshot is a cobra command wrapping snapshot
+func NewCmdSnapshot(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "snapshot [NODE...]",
+		Short:   "Print resources of Kubernetes nodes and their containers as json.",
+		Long:    snapshotLong,
+		Example: snapshotExample,
+		Run: func(c *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunSnapshot(args))
+		},
+	}
+
+	return cmd
+}
+
+// RunSnapshot prints a snapshot of nodes and containers as json
+func (o *FreeOptions) RunSnapshot(args []string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	s, err := o.takeSnapshot(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(o.Out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("failed to write snapshot: %v", err)
+	}
+
+	return nil
+}
+
+// takeSnapshot calculates resources of nodes and all of their containers
+func (o *FreeOptions) takeSnapshot(ctx context.Context, args []string) (*snapshot, error) {
+
+	// get nodes
+	nodes, err := o.source.GetNodes(ctx, args, o.labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &snapshot{
+		Time:       time.Now().UTC(),
+		Namespace:  o.namespace,
+		Nodes:      []snapshotNode{},
+		Containers: []snapshotContainer{},
+	}
+
+	// include containers without requests/limits, they count as pods of a namespace
+	listAll := o.listAll
+	o.listAll = true
+	defer func() { o.listAll = listAll }()
+
+	// get pod metrics
+	podMetrics := o.getPodMetrics(ctx)
+
+	for _, node := range nodes {
+		nf, err := o.getNodeFree(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+		s.Nodes = append(s.Nodes, newSnapshotNode(nf))
+
+		infos, err := o.getPodInfos(ctx, node, podMetrics)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			s.Containers = append(s.Containers, newSnapshotContainer(info))
+		}
+	}
+
+	return s, nil
+}
+
+// readSnapshot reads a snapshot written by "kubectl free snapshot"
+func readSnapshot(path string) (*snapshot, error) {
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+
+	s := &snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %v", path, err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	o := &FreeOptions{
+		nocolor: true,
+		table:   table.NewOutputTable(&bytes.Buffer{}),
+		source:  newTestSource("", testNodes, testPods),
+	}
+
+	s, err := o.takeSnapshot(ctx, []string{"node1"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expectedNode := snapshotNode{
+		Name:           "node1",
+		Status:         "Ready",
+		CPUUsed:        100,
+		CPURequested:   1700,
+		CPULimited:     2700,
+		CPUAllocatable: 4000,
+		MemUsed:        1024,
+		MemRequested:   2300,
+		MemLimited:     3300,
+		MemAllocatable: 4000,
+		PodCount:       3,
+		PodAllocatable: 110,
+		ContainerCount: 5,
+		hasUsage:       true,
+	}
+	if !reflect.DeepEqual(s.Nodes, []snapshotNode{expectedNode}) {
+		t.Errorf("expected(%v) differ (got: %v)", expectedNode, s.Nodes)
+	}
+
+	// containers without requests/limits are included
+	if len(s.Containers) != 5 {
+		t.Errorf("expected(5) differ (got: %d)", len(s.Containers))
+		return
+	}
+	if o.listAll {
+		t.Errorf("expected --list-all to be restored")
+	}
+
+	c := s.Containers[0]
+	if c.Name != "container1" || c.CPUUsed == nil || *c.CPUUsed != 10 || c.MemoryUsed == nil || *c.MemoryUsed != 10 {
+		t.Errorf("unexpected container: %+v", c)
+	}
+	if s.Containers[1].CPUUsed != nil {
+		t.Errorf("expected no usage of container without metrics (got: %d)", *s.Containers[1].CPUUsed)
+	}
+}
+
+func TestRunSnapshot(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		IOStreams: genericclioptions.IOStreams{Out: buffer},
+		nocolor:   true,
+		table:     table.NewOutputTable(buffer),
+		source:    newTestSource("", testNodes, testPods),
+	}
+
+	if err := o.RunSnapshot([]string{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, buffer.Bytes(), 0o600); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	s, err := readSnapshot(path)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if len(s.Nodes) != 2 || s.Nodes[0].Name != "node1" || s.Nodes[1].Name != "node2" {
+		t.Errorf("unexpected nodes: %+v", s.Nodes)
+	}
+	if len(s.Containers) != 5 {
+		t.Errorf("expected(5) differ (got: %d)", len(s.Containers))
+	}
+
+	if _, err := readSnapshot(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("unexpected error: should return err")
+	}
+}
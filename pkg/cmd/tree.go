@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/constants"
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+)
+
+// treeUsage is the usage of a level of the tree (node, namespace, pod or container)
+type treeUsage struct {
+	cpuUsed      int64
+	cpuRequested int64
+	cpuLimited   int64
+	memUsed      int64
+	memRequested int64
+	memLimited   int64
+}
+
+// add adds the resources of a container
+func (u *treeUsage) add(info podInfo) {
+	if info.containerCPUUsed != nil {
+		u.cpuUsed += info.containerCPUUsed.MilliValue()
+	}
+	if info.containerMemoryUsed != nil {
+		u.memUsed += info.containerMemoryUsed.Value()
+	}
+	u.cpuRequested += info.containerCPURequested
+	u.cpuLimited += info.containerCPULimit
+	u.memRequested += info.containerMemoryRequested
+	u.memLimited += info.containerMemoryLimit
+}
+
+// showTree prints nodes, namespaces on the nodes, pods and containers as a tree (-o tree)
+// Every level shows the subtotal of its children, percentages are of the allocatable resources of the node.
+func (o *FreeOptions) showTree(ctx context.Context, nodes []v1.Node) error {
+
+	// include containers without requests/limits, they count for usage
+	listAll := o.listAll
+	o.listAll = true
+	defer func() { o.listAll = listAll }()
+
+	// get pod metrics
+	podMetrics := o.getPodMetrics(ctx)
+
+	if !o.noHeaders {
+		o.table.Header = o.treeTableHeader()
+	}
+
+	for _, node := range nodes {
+
+		nf, err := o.getNodeFree(ctx, node)
+		if err != nil {
+			return err
+		}
+
+		infos, err := o.getPodInfos(ctx, node, podMetrics)
+		if err != nil {
+			return err
+		}
+
+		// only running pods like the subtotal of the node
+		running := []podInfo{}
+		for _, info := range infos {
+			if info.podPhase == string(v1.PodRunning) {
+				running = append(running, info)
+			}
+		}
+
+		nodeStatus := nf.status
+		if o.emojiStatus {
+			nodeStatus = util.GetNodeStatusEmoji(nodeStatus)
+		}
+		util.SetNodeStatusColor(&nodeStatus, o.nocolor)
+
+		nodeUsage := treeUsage{
+			cpuUsed:      nf.cpuUsed,
+			cpuRequested: nf.cpuRequested,
+			cpuLimited:   nf.cpuLimited,
+			memUsed:      nf.memUsed,
+			memRequested: nf.memRequested,
+			memLimited:   nf.memLimited,
+		}
+		o.table.AddRow(o.treeRow(nf.name, nodeStatus, nodeUsage, nf))
+
+		o.addTreeNamespaces(running, nf)
+	}
+
+	o.table.Print()
+
+	return nil
+}
+
+// addTreeNamespaces adds namespaces, pods and containers of a node to the tree
+func (o *FreeOptions) addTreeNamespaces(infos []podInfo, nf nodeFree) {
+
+	// containers by namespace and pod
+	namespaces := map[string]map[string][]podInfo{}
+	for _, info := range infos {
+		if _, ok := namespaces[info.podNamespace]; !ok {
+			namespaces[info.podNamespace] = map[string][]podInfo{}
+		}
+		namespaces[info.podNamespace][info.podName] = append(namespaces[info.podNamespace][info.podName], info)
+	}
+
+	namespaceNames := maps.Keys(namespaces)
+	slices.Sort(namespaceNames)
+
+	for i, namespace := range namespaceNames {
+		lastNamespace := i == len(namespaceNames)-1
+		pods := namespaces[namespace]
+
+		podNames := maps.Keys(pods)
+		slices.Sort(podNames)
+
+		var namespaceUsage treeUsage
+		for _, containers := range pods {
+			for _, c := range containers {
+				namespaceUsage.add(c)
+			}
+		}
+		o.table.AddRow(o.treeRow(treeBranch(lastNamespace)+namespace, o.treeStatus(""), namespaceUsage, nf))
+
+		namespaceIndent := treeIndent(lastNamespace)
+		for j, podName := range podNames {
+			lastPod := j == len(podNames)-1
+			containers := pods[podName]
+
+			var podUsage treeUsage
+			for _, c := range containers {
+				podUsage.add(c)
+			}
+			podStatus := util.GetPodStatus(containers[0].podStatus, o.nocolor, o.emojiStatus)
+			o.table.AddRow(o.treeRow(namespaceIndent+treeBranch(lastPod)+podName, podStatus, podUsage, nf))
+
+			podIndent := namespaceIndent + treeIndent(lastPod)
+			for k, c := range containers {
+				var containerUsage treeUsage
+				containerUsage.add(c)
+				o.table.AddRow(o.treeRow(podIndent+treeBranch(k == len(containers)-1)+c.containerName, o.treeStatus(c.containerState), containerUsage, nf))
+			}
+		}
+	}
+}
+
+// treeBranch returns the branch to an entry, the last entry of a level closes the branch
+func treeBranch(last bool) string {
+	if last {
+		return constants.TreeLastBranch
+	}
+	return constants.TreeBranch
+}
+
+// treeIndent returns the indent of children of an entry
+func treeIndent(last bool) string {
+	if last {
+		return constants.TreeLastIndent
+	}
+	return constants.TreeIndent
+}
+
+// treeStatus returns an uncolored status
+// hack: avoid breaking column by escape char of colored node and pod status
+func (o *FreeOptions) treeStatus(s string) string {
+	if !o.nocolor {
+		util.DefaultColor(&s)
+	}
+	return s
+}
+
+// treeRow creates a table row of the tree
+func (o *FreeOptions) treeRow(name, status string, u treeUsage, nf nodeFree) []string {
+
+	row := []string{
+		name,
+		status,
+	}
+
+	// cpu
+	if !o.noMetrics {
+		row = append(row, o.toMilliUnitOrDash(u.cpuUsed)) // cpu used (from metrics)
+	}
+	row = append(
+		row,
+		o.toMilliUnitOrDash(u.cpuRequested), // cpu requested
+		o.toMilliUnitOrDash(u.cpuLimited),   // cpu limited
+	)
+	if !o.noMetrics {
+		row = append(row, o.toColorColumnPercent(cpuUseThreshold, util.GetPercentage(u.cpuUsed, nf.cpuAllocatable))) // cpu used %
+	}
+	row = append(row, o.toColorColumnPercent(cpuReqThreshold, util.GetPercentage(u.cpuRequested, nf.cpuAllocatable))) // cpu requested %
+
+	// mem
+	if !o.noMetrics {
+		row = append(row, o.toUnitOrDash(u.memUsed)) // mem used (from metrics)
+	}
+	row = append(
+		row,
+		o.toUnitOrDash(u.memRequested), // mem requested
+		o.toUnitOrDash(u.memLimited),   // mem limited
+	)
+	if !o.noMetrics {
+		row = append(row, o.toColorColumnPercent(memUseThreshold, util.GetPercentage(u.memUsed, nf.memAllocatable))) // mem used %
+	}
+	row = append(row, o.toColorColumnPercent(memReqThreshold, util.GetPercentage(u.memRequested, nf.memAllocatable))) // mem requested %
+
+	return row
+}
+
+// treeTableHeader defines table headers for -o tree
+func (o *FreeOptions) treeTableHeader() []string {
+
+	hStatus := "STATUS"
+	hCPUUseP := "CPU/use%"
+	hCPUReqP := "CPU/req%"
+	hMEMUseP := "MEM/use%"
+	hMEMReqP := "MEM/req%"
+
+	if !o.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hStatus)  // STATUS
+		util.DefaultColor(&hCPUUseP) // CPU/use%
+		util.DefaultColor(&hCPUReqP) // CPU/req%
+		util.DefaultColor(&hMEMUseP) // MEM/use%
+		util.DefaultColor(&hMEMReqP) // MEM/req%
+	}
+
+	header := []string{"NAME", hStatus}
+
+	if !o.noMetrics {
+		header = append(header, "CPU/use")
+	}
+	header = append(header, "CPU/req", "CPU/lim")
+	if !o.noMetrics {
+		header = append(header, hCPUUseP)
+	}
+	header = append(header, hCPUReqP)
+
+	if !o.noMetrics {
+		header = append(header, "MEM/use")
+	}
+	header = append(header, "MEM/req", "MEM/lim")
+	if !o.noMetrics {
+		header = append(header, hMEMUseP)
+	}
+	header = append(header, hMEMReqP)
+
+	return header
+}
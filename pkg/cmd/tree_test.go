@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestShowTree(t *testing.T) {
+	ctx := context.Background()
+
+	// completed pods are not part of the node subtotal
+	completed := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+			Containers: []v1.Container{
+				{Name: "job", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}},
+			},
+		},
+		Status: v1.PodStatus{Phase: v1.PodSucceeded},
+	}
+	withCompleted := append([]v1.Pod{completed}, testPods...)
+
+	var tests = []struct {
+		description string
+		nometrics   bool
+		pods        []v1.Pod
+		expected    []string
+	}{
+		{
+			"tree without metrics",
+			true,
+			testPods,
+			[]string{
+				"NAME                    STATUS  CPU/req CPU/lim CPU/req% MEM/req MEM/lim MEM/req%",
+				"node1                   Ready   1700m   2700m   42%      2K      3K      57%",
+				"├── awesome-ns                  200m    200m    5%       0K      0K      7%",
+				"│   └── pod3            Running 200m    200m    5%       0K      0K      7%",
+				"│       ├── container3a -       200m    200m    5%       0K      0K      7%",
+				"│       └── container3b -       -       -       0%       -       -       0%",
+				"└── default                     1500m   2500m   37%      2K      3K      50%",
+				"    ├── pod1            Running 1       2       25%      1K      2K      25%",
+				"    │   └── container1  -       1       2       25%      1K      2K      25%",
+				"    └── pod2            Running 500m    500m    12%      1K      1K      25%",
+				"        ├── container2a -       500m    500m    12%      1K      1K      25%",
+				"        └── container2b -       -       -       0%       -       -       0%",
+				"",
+			},
+		},
+		{
+			"completed pods",
+			true,
+			withCompleted,
+			[]string{
+				"NAME                    STATUS  CPU/req CPU/lim CPU/req% MEM/req MEM/lim MEM/req%",
+				"node1                   Ready   1700m   2700m   42%      2K      3K      57%",
+				"├── awesome-ns                  200m    200m    5%       0K      0K      7%",
+				"│   └── pod3            Running 200m    200m    5%       0K      0K      7%",
+				"│       ├── container3a -       200m    200m    5%       0K      0K      7%",
+				"│       └── container3b -       -       -       0%       -       -       0%",
+				"└── default                     1500m   2500m   37%      2K      3K      50%",
+				"    ├── pod1            Running 1       2       25%      1K      2K      25%",
+				"    │   └── container1  -       1       2       25%      1K      2K      25%",
+				"    └── pod2            Running 500m    500m    12%      1K      1K      25%",
+				"        ├── container2a -       500m    500m    12%      1K      1K      25%",
+				"        └── container2b -       -       -       0%       -       -       0%",
+				"",
+			},
+		},
+		{
+			"tree with metrics",
+			false,
+			testPods,
+			[]string{
+				"NAME                    STATUS  CPU/use CPU/req CPU/lim CPU/use% CPU/req% MEM/use MEM/req MEM/lim MEM/use% MEM/req%",
+				"node1                   Ready   100m    1700m   2700m   2%       42%      1K      2K      3K      25%      57%",
+				"├── awesome-ns                  -       200m    200m    0%       5%       -       0K      0K      0%       7%",
+				"│   └── pod3            Running -       200m    200m    0%       5%       -       0K      0K      0%       7%",
+				"│       ├── container3a -       -       200m    200m    0%       5%       -       0K      0K      0%       7%",
+				"│       └── container3b -       -       -       -       0%       0%       -       -       -       0%       0%",
+				"└── default                     10m     1500m   2500m   0%       37%      0K      2K      3K      0%       50%",
+				"    ├── pod1            Running 10m     1       2       0%       25%      0K      1K      2K      0%       25%",
+				"    │   └── container1  -       10m     1       2       0%       25%      0K      1K      2K      0%       25%",
+				"    └── pod2            Running -       500m    500m    0%       12%      -       1K      1K      0%       25%",
+				"        ├── container2a -       -       500m    500m    0%       12%      -       1K      1K      0%       25%",
+				"        └── container2b -       -       -       -       0%       0%       -       -       -       0%       0%",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:   true,
+				kByte:     true,
+				noMetrics: test.nometrics,
+				table:     table.NewOutputTable(buffer),
+				source:    newTestSource("", testNodes, test.pods),
+			}
+
+			if err := o.showTree(ctx, []v1.Node{testNodes[0]}); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(\n%s) differ (got: \n%s)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
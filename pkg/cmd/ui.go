@@ -0,0 +1,606 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+	"golang.org/x/term"
+	v1 "k8s.io/api/core/v1"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	// uiLong defines long description
+	uiLong = templates.LongDesc(`
+		Show resources of Kubernetes nodes in an interactive terminal UI.
+
+		Keys:
+		  up/down, k/j      move the cursor
+		  enter, right, l   show containers of the selected node
+		  esc, left, h      back to the node list
+		  s                 change sort key
+		  c                 toggle compact view of the container list
+		  i                 toggle image column of the container list
+		  p                 toggle pod columns of the node list
+		  u                 change unit (B, K, M, G)
+		  b                 toggle binary prefix
+		  /                 filter containers of the container list by namespace (exact name)
+		  r                 refresh
+		  q, ctrl-c         quit
+	`)
+
+	// uiExample defines command examples
+	uiExample = templates.Examples(`
+		# Show resources of all nodes in an interactive terminal UI.
+		kubectl free ui
+
+		# Show resources of nodes matching a label selector and refresh every 10 seconds.
+		kubectl free ui -l key=value --interval 10s
+
+		# Only show containers without memory limit.
+		kubectl free ui --where 'mem.lim == 0'
+	`)
+)
+
+const (
+	// terminal control sequences
+	altScreenOn  = "\033[?1049h\033[?25l"
+	altScreenOff = "\033[?25h\033[?1049l"
+
+	// keys of the terminal UI
+	keyUp        = "up"
+	keyDown      = "down"
+	keyLeft      = "left"
+	keyRight     = "right"
+	keyEnter     = "enter"
+	keyEsc       = "esc"
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl-c"
+)
+
+// uiView is a screen of the terminal UI
+type uiView int
+
+const (
+	uiNodeView uiView = iota
+	uiContainerView
+)
+
+// uiNodeSortKeys are the sort keys of the node list
+var uiNodeSortKeys = []string{"name", "cpu/use%", "mem/use%", "cpu/req%", "mem/req%"}
+
+// freeUI is an interactive terminal UI for nodes and containers
+type freeUI struct {
+	o    *FreeOptions
+	args []string
+
+	in  io.Reader
+	out io.Writer
+
+	// size returns the height of the terminal
+	size func() int
+
+	// state
+	view        uiView
+	cursor      int
+	offset      int
+	nodeSort    int
+	namespace   string
+	filterInput *string
+	err         error
+
+	// data
+	nodes        []v1.Node
+	nodeFrees    map[string]nodeFree
+	prevFrees    map[string]nodeFree
+	selectedNode string
+	containers   []podInfo
+	prevPodInfos map[string]podInfo
+}
+
+// NewCmdUI is a cobra command wrapping the terminal UI
+func NewCmdUI(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ui [NODE...]",
+		Short:   "Show resources of Kubernetes nodes in an interactive terminal UI.",
+		Long:    uiLong,
+		Example: uiExample,
+		Run: func(c *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunUI(args))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.whereText, "where", "", o.whereText, `Only show containers matching the expression, e.g. 'namespace =~ ^team- && mem.lim == 0'.`)
+
+	return cmd
+}
+
+// RunUI starts the terminal UI on the terminal of the IOStreams
+func (o *FreeOptions) RunUI(args []string) error {
+
+	in, ok := o.In.(*os.File)
+	if !ok || !term.IsTerminal(int(in.Fd())) {
+		return fmt.Errorf("the terminal UI requires a terminal")
+	}
+
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set terminal to raw mode: %v", err)
+	}
+	defer func() { _ = term.Restore(int(in.Fd()), state) }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := o.startCache(ctx); err != nil {
+		return err
+	}
+
+	u := newFreeUI(o, args, in, o.Out)
+	u.size = func() int {
+		_, h, err := term.GetSize(int(in.Fd()))
+		if err != nil {
+			return 24
+		}
+		return h
+	}
+
+	fmt.Fprint(o.Out, altScreenOn)
+	defer fmt.Fprint(o.Out, altScreenOff)
+
+	return u.run(ctx)
+}
+
+// newFreeUI is an instance of freeUI
+func newFreeUI(o *FreeOptions, args []string, in io.Reader, out io.Writer) *freeUI {
+	return &freeUI{
+		o:    o,
+		args: args,
+		in:   in,
+		out:  out,
+		size: func() int { return 24 },
+	}
+}
+
+// run handles keys and refreshes the data every watchInterval until quit or ctx is done
+func (u *freeUI) run(ctx context.Context) error {
+
+	keys := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			n, err := u.in.Read(buf)
+			for _, k := range parseKeys(buf[:n]) {
+				select {
+				case keys <- k:
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(u.o.watchInterval)
+	defer ticker.Stop()
+
+	u.refresh(ctx)
+	u.draw()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case k, ok := <-keys:
+			if !ok {
+				// input closed
+				return nil
+			}
+			if quit := u.handleKey(ctx, k); quit {
+				return nil
+			}
+			u.draw()
+		case <-ticker.C:
+			u.refresh(ctx)
+			u.draw()
+		}
+	}
+}
+
+// parseKeys splits terminal input into keys
+func parseKeys(b []byte) []string {
+	keys := []string{}
+
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case 0x1b:
+			// escape sequence of arrow keys
+			if i+2 < len(b) && b[i+1] == '[' {
+				switch b[i+2] {
+				case 'A':
+					keys = append(keys, keyUp)
+				case 'B':
+					keys = append(keys, keyDown)
+				case 'C':
+					keys = append(keys, keyRight)
+				case 'D':
+					keys = append(keys, keyLeft)
+				}
+				i += 2
+				continue
+			}
+			keys = append(keys, keyEsc)
+		case '\r', '\n':
+			keys = append(keys, keyEnter)
+		case 0x7f, 0x08:
+			keys = append(keys, keyBackspace)
+		case 0x03:
+			keys = append(keys, keyCtrlC)
+		default:
+			keys = append(keys, string(b[i]))
+		}
+	}
+
+	return keys
+}
+
+// handleKey changes the state of the UI and reports whether the UI should quit
+func (u *freeUI) handleKey(ctx context.Context, k string) bool {
+
+	if k == keyCtrlC {
+		return true
+	}
+
+	// namespace filter input
+	if u.filterInput != nil {
+		switch k {
+		case keyEnter:
+			u.namespace = *u.filterInput
+			u.filterInput = nil
+			u.cursor = 0
+		case keyEsc:
+			u.filterInput = nil
+		case keyBackspace:
+			if len(*u.filterInput) > 0 {
+				*u.filterInput = (*u.filterInput)[:len(*u.filterInput)-1]
+			}
+		default:
+			if len(k) == 1 {
+				*u.filterInput += k
+			}
+		}
+		return false
+	}
+
+	switch k {
+	case "q":
+		return true
+	case keyUp, "k":
+		u.cursor--
+	case keyDown, "j":
+		u.cursor++
+	case keyEnter, keyRight, "l":
+		nodes := u.sortedNodes()
+		if u.view == uiNodeView && len(nodes) > 0 {
+			u.selectedNode = nodes[u.clampedCursor(len(nodes))].name
+			u.view = uiContainerView
+			u.cursor = 0
+			u.containers = nil
+			u.prevPodInfos = nil
+			u.refresh(ctx)
+		}
+	case keyEsc, keyLeft, "h", keyBackspace:
+		if u.view == uiContainerView {
+			u.view = uiNodeView
+			u.cursor = 0
+		}
+	case "s":
+		if u.view == uiNodeView {
+			u.nodeSort = (u.nodeSort + 1) % len(uiNodeSortKeys)
+		} else if u.o.sortByResource == memorySortResource {
+			u.o.sortByResource = cpuSortResource
+		} else {
+			u.o.sortByResource = memorySortResource
+		}
+	case "c":
+		u.o.compactView = !u.o.compactView
+	case "i":
+		u.o.listContainerImage = !u.o.listContainerImage
+	case "p":
+		u.o.pod = !u.o.pod
+	case "u":
+		u.nextUnit()
+	case "b":
+		u.o.binPrefix = !u.o.binPrefix
+	case "/":
+		// only containers are filtered by namespace
+		if u.view == uiContainerView {
+			input := u.namespace
+			u.filterInput = &input
+		}
+	case "r":
+		u.refresh(ctx)
+	}
+
+	return false
+}
+
+// nextUnit changes the memory unit to the next bigger one
+func (u *freeUI) nextUnit() {
+	o := u.o
+	switch {
+	case o.gByte:
+		o.bytes, o.kByte, o.mByte, o.gByte = true, false, false, false
+	case o.mByte:
+		o.bytes, o.kByte, o.mByte, o.gByte = false, false, false, true
+	case o.kByte:
+		o.bytes, o.kByte, o.mByte, o.gByte = false, false, true, false
+	default:
+		o.bytes, o.kByte, o.mByte, o.gByte = false, true, false, false
+	}
+}
+
+// unitName returns the current memory unit
+func (u *freeUI) unitName() string {
+	o := u.o
+	if o.binPrefix {
+		_, s := util.GetBinUnit(o.bytes, o.kByte, o.mByte, o.gByte)
+		return s
+	}
+	_, s := util.GetSiUnit(o.bytes, o.kByte, o.mByte, o.gByte)
+	return s
+}
+
+// refresh fetches nodes and the containers of the selected node
+func (u *freeUI) refresh(ctx context.Context) {
+	o := u.o
+
+	rctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	u.err = nil
+
+	nodes, err := o.source.GetNodes(rctx, u.args, o.labelSelector)
+	if err != nil {
+		u.err = err
+		return
+	}
+
+	frees := map[string]nodeFree{}
+	for _, node := range nodes {
+		nf, err := o.getNodeFree(rctx, node)
+		if err != nil {
+			u.err = err
+			return
+		}
+		frees[nf.name] = nf
+	}
+	u.nodes = nodes
+	u.prevFrees = u.nodeFrees
+	u.nodeFrees = frees
+
+	if u.view != uiContainerView {
+		return
+	}
+
+	for _, node := range nodes {
+		if node.ObjectMeta.Name != u.selectedNode {
+			continue
+		}
+
+		infos, err := o.getPodInfos(rctx, node, o.getPodMetrics(rctx))
+		if err != nil {
+			u.err = err
+			return
+		}
+
+		// filter containers (--where)
+		containers := []podInfo{}
+		for _, info := range infos {
+			match, err := o.matchPod(info)
+			if err != nil {
+				u.err = err
+				return
+			}
+			if match {
+				containers = append(containers, info)
+			}
+		}
+
+		prev := map[string]podInfo{}
+		for _, c := range u.containers {
+			prev[c.key()] = c
+		}
+		if u.containers != nil {
+			u.prevPodInfos = prev
+		}
+		u.containers = containers
+	}
+}
+
+// sortedNodes returns the nodes in the order of the current sort key
+func (u *freeUI) sortedNodes() []nodeFree {
+	frees := make([]nodeFree, 0, len(u.nodes))
+	for _, node := range u.nodes {
+		if nf, ok := u.nodeFrees[node.ObjectMeta.Name]; ok {
+			frees = append(frees, nf)
+		}
+	}
+
+	key := uiNodeSortKeys[u.nodeSort]
+	slices.SortStableFunc(frees, func(a, b nodeFree) bool {
+		pa, pb := a.percentages(), b.percentages()
+		switch key {
+		case "cpu/use%":
+			return pa.cpuUsed > pb.cpuUsed
+		case "mem/use%":
+			return pa.memUsed > pb.memUsed
+		case "cpu/req%":
+			return pa.cpuRequested > pb.cpuRequested
+		case "mem/req%":
+			return pa.memRequested > pb.memRequested
+		default:
+			return a.name < b.name
+		}
+	})
+
+	return frees
+}
+
+// visibleContainers returns the containers in the namespace of the filter, highest usage first
+func (u *freeUI) visibleContainers() []podInfo {
+	containers := []podInfo{}
+	for _, c := range u.containers {
+		if u.namespace == "" || c.podNamespace == u.namespace {
+			containers = append(containers, c)
+		}
+	}
+
+	if !u.o.noMetrics {
+		containers = u.o.sortEntries(containers)
+		for i, j := 0, len(containers)-1; i < j; i, j = i+1, j-1 {
+			containers[i], containers[j] = containers[j], containers[i]
+		}
+	}
+
+	return containers
+}
+
+// clampedCursor keeps the cursor within n rows
+func (u *freeUI) clampedCursor(n int) int {
+	if u.cursor >= n {
+		u.cursor = n - 1
+	}
+	if u.cursor < 0 {
+		u.cursor = 0
+	}
+	return u.cursor
+}
+
+// draw renders the current view and redraws the terminal
+func (u *freeUI) draw() {
+	o := u.o
+
+	var (
+		title  string
+		header []string
+		rows   [][]string
+	)
+
+	switch u.view {
+	case uiNodeView:
+		o.prepareFreeTableHeader()
+		title = "Nodes"
+		header = o.freeTableHeaders
+		for _, nf := range u.sortedNodes() {
+			var prev *nodeFree
+			if p, ok := u.prevFrees[nf.name]; ok {
+				prev = &p
+			}
+			rows = append(rows, o.nodeFreeToRow(nf, prev))
+		}
+	case uiContainerView:
+		o.prepareListTableHeader()
+		title = "Containers on " + u.selectedNode
+		header = o.listTableHeaders
+		for _, c := range u.visibleContainers() {
+			var prev *podInfo
+			if p, ok := u.prevPodInfos[c.key()]; ok {
+				prev = &p
+			}
+			rows = append(rows, o.podInfoToRow(c, prev))
+		}
+	}
+
+	// scroll to keep the cursor visible
+	// title, blank line, header, blank line, status and help lines are always shown
+	cursor := u.clampedCursor(len(rows))
+	visible := u.size() - 6
+	if visible < 1 {
+		visible = 1
+	}
+	if cursor < u.offset {
+		u.offset = cursor
+	}
+	if cursor >= u.offset+visible {
+		u.offset = cursor - visible + 1
+	}
+	if u.offset > len(rows) {
+		u.offset = 0
+	}
+	end := u.offset + visible
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "kubectl free - %s\t%s\n\n", title, time.Now().Format(time.RFC1123))
+
+	t := table.NewOutputTable(buffer)
+	t.Header = append([]string{" "}, header...)
+	for i := u.offset; i < end; i++ {
+		marker := " "
+		if i == cursor {
+			marker = ">"
+		}
+		t.AddRow(append([]string{marker}, rows[i]...))
+	}
+	t.Print()
+
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, u.statusLine())
+	fmt.Fprint(buffer, u.helpLine())
+
+	// raw terminals do not return the carriage on newline
+	fmt.Fprint(u.out, clearScreen+strings.ReplaceAll(buffer.String(), "\n", "\r\n"))
+}
+
+// statusLine returns the current settings or the last error
+func (u *freeUI) statusLine() string {
+	if u.err != nil {
+		return "error: " + u.err.Error()
+	}
+
+	if u.view == uiNodeView {
+		return fmt.Sprintf("sort: %s  unit: %s", uiNodeSortKeys[u.nodeSort], u.unitName())
+	}
+
+	namespace := u.namespace
+	if namespace == "" {
+		namespace = "<all>"
+	}
+
+	return fmt.Sprintf("sort: %s  namespace: %s  unit: %s", u.o.sortByResource, namespace, u.unitName())
+}
+
+// helpLine returns the key bindings of the current view or the namespace filter input
+func (u *freeUI) helpLine() string {
+	if u.filterInput != nil {
+		return "namespace: " + *u.filterInput + "_"
+	}
+
+	if u.view == uiContainerView {
+		return "[esc]back [s]ort [c]ompact [i]mage [u]nit [b]inary [/]namespace [r]efresh [q]uit"
+	}
+	return "[enter]containers [s]ort [p]ods [u]nit [b]inary [r]efresh [q]uit"
+}
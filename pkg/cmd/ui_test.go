@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+)
+
+func TestParseKeys(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		input       string
+		expected    []string
+	}{
+		{"letters", "jkq", []string{"j", "k", "q"}},
+		{"arrows", "\x1b[A\x1b[B\x1b[C\x1b[D", []string{keyUp, keyDown, keyRight, keyLeft}},
+		{"control keys", "\r\x1b\x7f\x03", []string{keyEnter, keyEsc, keyBackspace, keyCtrlC}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := parseKeys([]byte(test.input))
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFreeUI(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		input       string
+		where       string
+		expected    []string
+		unexpected  []string
+	}{
+		{
+			"node list",
+			"q",
+			"",
+			[]string{
+				"kubectl free - Nodes",
+				"> node1",
+				"  node2",
+				"sort: name  unit: K",
+			},
+			[]string{"namespace", "[/]"},
+		},
+		{
+			"no namespace filter of nodes",
+			"/x",
+			"",
+			[]string{"kubectl free - Nodes", "sort: name  unit: K"},
+			[]string{"namespace: x"},
+		},
+		{
+			"sort nodes by cpu/req%",
+			"sss",
+			"",
+			[]string{
+				"> node1",
+				"sort: cpu/req%",
+			},
+			nil,
+		},
+		{
+			"select node",
+			"j\r",
+			"",
+			[]string{"kubectl free - Containers on node2"},
+			[]string{"container"},
+		},
+		{
+			"back to node list",
+			"\r\x1b",
+			"",
+			[]string{"kubectl free - Nodes"},
+			nil,
+		},
+		{
+			"containers of node",
+			"\r",
+			"",
+			[]string{
+				"kubectl free - Containers on node1",
+				"> node1     default   pod1     Running    Burstable -              container1  0        -           1       2       1K      2K",
+				"  node1     default   pod2     Running    Burstable -              container2a 0        -           500m    500m    1K      1K",
+			},
+			nil,
+		},
+		{
+			"filter by namespace",
+			"\r/awe\r",
+			"",
+			[]string{"namespace: awe "},
+			[]string{"container1", "container2a"},
+		},
+		{
+			"filter by exact namespace",
+			"\r/default\r",
+			"",
+			[]string{"namespace: default ", "container1", "container2a"},
+			nil,
+		},
+		{
+			"change unit",
+			"uu",
+			"",
+			[]string{"unit: G"},
+			nil,
+		},
+		{
+			"containers matching --where",
+			"\r",
+			"cpu.req < 1",
+			[]string{"container2a"},
+			[]string{"container1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:        true,
+				noMetrics:      true,
+				compactView:    true,
+				kByte:          true,
+				sortByResource: memorySortResource,
+				watchInterval:  time.Hour,
+				table:          table.NewOutputTable(buffer),
+				source:         newTestSource("", testNodes, testPods[:2]),
+			}
+			if test.where != "" {
+				where, err := parseWhere(test.where, podWhereFields(podInfo{}))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				o.where = where
+			}
+
+			u := newFreeUI(o, []string{}, strings.NewReader(test.input), buffer)
+			if err := u.run(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// last frame
+			frames := strings.Split(buffer.String(), clearScreen)
+			frame := strings.ReplaceAll(frames[len(frames)-1], "\r\n", "\n")
+
+			for _, e := range test.expected {
+				if !strings.Contains(frame, e) {
+					t.Errorf("[%s] expected(%s) not in frame (got: %s)", test.description, e, frame)
+				}
+			}
+			for _, e := range test.unexpected {
+				if strings.Contains(frame, e) {
+					t.Errorf("[%s] unexpected(%s) in frame (got: %s)", test.description, e, frame)
+				}
+			}
+		})
+	}
+}
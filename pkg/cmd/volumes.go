@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// betaStorageClassAnnotation is the storage class of claims created before spec.storageClassName
+const betaStorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+var (
+	// volumesLong defines long description
+	volumesLong = templates.LongDesc(`
+		Show usage of persistent volume claims mounted by pods on Kubernetes nodes.
+
+		Usage is read from the kubelet Summary API through the api server proxy.
+	`)
+
+	// volumesExample defines command examples
+	volumesExample = templates.Examples(`
+		# Show usage of persistent volume claims on all nodes (all namespaces).
+		kubectl free volumes
+
+		# Show usage of persistent volume claims in a namespace on specific nodes.
+		kubectl free volumes -n default node1 node2
+
+		# Show usage of persistent volume claims of storage classes.
+		kubectl free volumes --storage-class standard,ssd
+	`)
+)
+
+// volumeInfo is the usage of a persistent volume claim mounted by a pod
+type volumeInfo struct {
+	nodeName     string
+	namespace    string
+	podName      string
+	pvcName      string
+	storageClass string
+	capacity     int64
+	used         int64
+	available    int64
+	inodesUsed   int64
+	inodes       int64
+}
+
+// NewCmdVolumes is a cobra command wrapping volumes
+func NewCmdVolumes(f cmdutil.Factory, o *FreeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "volumes [NODE...]",
+		Short:   "Show usage of persistent volume claims mounted by pods on Kubernetes nodes.",
+		Long:    volumesLong,
+		Example: volumesExample,
+		Run: func(c *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, c, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunVolumes(args))
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&o.storageClasses, "storage-class", "", o.storageClasses, `Only show claims of these storage classes.`)
+
+	return cmd
+}
+
+// RunVolumes prints usage of persistent volume claims
+func (o *FreeOptions) RunVolumes(args []string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	nodes, err := o.source.GetNodes(ctx, args, o.labelSelector)
+	if err != nil {
+		return err
+	}
+
+	return o.showVolumes(ctx, nodes)
+}
+
+// showVolumes prints usage of persistent volume claims mounted by pods on nodes
+func (o *FreeOptions) showVolumes(ctx context.Context, nodes []v1.Node) error {
+
+	volumes, err := o.getVolumeInfos(ctx, nodes)
+	if err != nil {
+		return err
+	}
+
+	if !o.noHeaders {
+		o.table.Header = o.volumesTableHeader()
+	}
+
+	for _, info := range volumes {
+		o.table.AddRow(o.volumeInfoToRow(info))
+	}
+	o.table.Print()
+
+	return nil
+}
+
+// getVolumeInfos returns usage of persistent volume claims mounted by pods on nodes
+// A warning is printed for each node without stats (e.g. unreachable kubelets, forbidden nodes/proxy)
+// and an error is returned if no node has stats, rather than an empty table.
+func (o *FreeOptions) getVolumeInfos(ctx context.Context, nodes []v1.Node) ([]volumeInfo, error) {
+
+	statsSource, err := o.statsSource()
+	if err != nil {
+		return nil, err
+	}
+
+	storageClasses, err := o.getStorageClasses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []volumeInfo{}
+	var failed int
+	var lastErr error
+	for _, node := range nodes {
+		stats, err := statsSource.GetNodeStats(ctx, node.ObjectMeta.Name)
+		if err != nil {
+			fmt.Fprintf(o.ErrOut, "warning: skipping volumes of node %s: %v\n", node.ObjectMeta.Name, err)
+			failed++
+			lastErr = err
+			continue
+		}
+
+		// only volumes of pods selected by namespaces and pod filters (--pod-selector, --exclude-namespace, ...)
+		pods, err := o.source.GetPods(ctx, node.ObjectMeta.Name)
+		if err != nil {
+			return nil, err
+		}
+		selected := map[string]bool{}
+		for _, pod := range pods.Items {
+			selected[pod.Namespace+"/"+pod.Name] = true
+		}
+
+		for _, v := range stats.Volumes {
+			if v.PVCName == "" || !selected[v.Namespace+"/"+v.PodName] {
+				continue
+			}
+
+			storageClass := storageClasses[v.Namespace+"/"+v.PVCName]
+			if len(o.storageClasses) > 0 && !slices.Contains(o.storageClasses, storageClass) {
+				continue
+			}
+
+			volumes = append(volumes, volumeInfo{
+				nodeName:     node.ObjectMeta.Name,
+				namespace:    v.Namespace,
+				podName:      v.PodName,
+				pvcName:      v.PVCName,
+				storageClass: storageClass,
+				capacity:     v.Capacity,
+				used:         v.Used,
+				available:    v.Available,
+				inodesUsed:   v.InodesUsed,
+				inodes:       v.Inodes,
+			})
+		}
+	}
+
+	if failed > 0 && failed == len(nodes) {
+		return nil, fmt.Errorf("failed to get volume usage of all nodes: %v", lastErr)
+	}
+
+	return volumes, nil
+}
+
+// statsSource returns the source of volume usage
+// The kubelet Summary API is used even if usage of cpu/memory comes from another --metrics-source.
+func (o *FreeOptions) statsSource() (source.StatsSource, error) {
+	if s, ok := o.metrics.(source.StatsSource); ok {
+		return s, nil
+	}
+	if o.client == nil {
+		return nil, fmt.Errorf("volume usage requires access to the cluster")
+	}
+	return source.NewKubeletSource(o.client, o.namespace), nil
+}
+
+// getStorageClasses returns storage classes of persistent volume claims by namespace/name
+func (o *FreeOptions) getStorageClasses(ctx context.Context) (map[string]string, error) {
+
+	storageClasses := map[string]string{}
+	if o.client == nil {
+		return storageClasses, nil
+	}
+
+	pvcs, err := o.client.CoreV1().PersistentVolumeClaims(o.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volume claims: %v", err)
+	}
+
+	for _, pvc := range pvcs.Items {
+		storageClass := pvc.ObjectMeta.Annotations[betaStorageClassAnnotation]
+		if pvc.Spec.StorageClassName != nil {
+			storageClass = *pvc.Spec.StorageClassName
+		}
+		storageClasses[pvc.ObjectMeta.Namespace+"/"+pvc.ObjectMeta.Name] = storageClass
+	}
+
+	return storageClasses, nil
+}
+
+// volumeInfoToRow creates a table row of a persistent volume claim
+func (o *FreeOptions) volumeInfoToRow(info volumeInfo) []string {
+
+	storageClass := info.storageClass
+	if storageClass == "" {
+		storageClass = "-"
+	}
+
+	usedPercent := util.GetPercentage(info.used, info.capacity)
+	inodesPercent := util.GetPercentage(info.inodesUsed, info.inodes)
+
+	return []string{
+		info.nodeName,                      // node name
+		info.namespace,                     // namespace
+		info.podName,                       // pod name
+		info.pvcName,                       // pvc name
+		storageClass,                       // storage class
+		o.toUnitOrDash(info.capacity),      // capacity
+		o.toUnitOrDash(info.used),          // used
+		o.toUnitOrDash(info.available),     // available
+		o.toColorPercent(usedPercent),      // used %
+		fmt.Sprintf("%d", info.inodesUsed), // inodes used
+		o.toColorPercent(inodesPercent),    // inodes used %
+	}
+}
+
+// volumesTableHeader defines table headers for volumes
+func (o *FreeOptions) volumesTableHeader() []string {
+
+	hUseP := "USE%"
+	hInodesUseP := "INODES/use%"
+
+	if !o.nocolor {
+		// hack: avoid breaking column by escape char
+		util.DefaultColor(&hUseP)       // USE%
+		util.DefaultColor(&hInodesUseP) // INODES/use%
+	}
+
+	return []string{
+		"NODE NAME",
+		"NAMESPACE",
+		"POD NAME",
+		"PVC",
+		"STORAGECLASS",
+		"CAPACITY",
+		"USED",
+		"AVAILABLE",
+		hUseP,
+		"INODES/use",
+		hInodesUseP,
+	}
+}
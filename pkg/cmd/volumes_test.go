@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShowVolumes(t *testing.T) {
+	ctx := context.Background()
+
+	standard := "standard"
+	pvcs := []runtime.Object{
+		&v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data-pod1", Namespace: "default"},
+			Spec:       v1.PersistentVolumeClaimSpec{StorageClassName: &standard},
+		},
+		&v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "data-pod3",
+				Namespace:   "awesome-ns",
+				Annotations: map[string]string{betaStorageClassAnnotation: "ssd"},
+			},
+		},
+	}
+
+	stats := &testStatsSource{
+		stats: map[string]*source.NodeStats{
+			"node1": {
+				Volumes: []source.VolumeStats{
+					{Namespace: "default", PodName: "pod1", Name: "data", PVCName: "data-pod1", Capacity: 10000, Used: 9500, Available: 500, InodesUsed: 100, Inodes: 1000},
+					{Namespace: "default", PodName: "pod1", Name: "kube-api-access"},
+					{Namespace: "awesome-ns", PodName: "pod3", Name: "data", PVCName: "data-pod3", Capacity: 4000, Used: 1000, Available: 3000, InodesUsed: 10, Inodes: 1000},
+				},
+			},
+			"node2": {
+				Volumes: []source.VolumeStats{
+					{Namespace: "default", PodName: "pod4", Name: "cache", PVCName: "unknown", Capacity: 2000, Used: 1000, Available: 1000},
+				},
+			},
+		},
+	}
+
+	// volumes of pods not served by the source (e.g. filtered) are not shown
+	pods := append([]v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod4", Namespace: "default"},
+			Spec:       v1.PodSpec{NodeName: "node2"},
+		},
+	}, testPods...)
+
+	excludeAwesome, err := source.NewPodFilter("", "", nil, []string{"awesome-ns"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var tests = []struct {
+		description    string
+		storageClasses []string
+		podFilter      *source.PodFilter
+		expected       []string
+	}{
+		{
+			"all volumes",
+			[]string{},
+			nil,
+			[]string{
+				"node1 default    pod1 data-pod1 standard 10K 9K 0K 95% 100 10%",
+				"node1 awesome-ns pod3 data-pod3 ssd      4K  1K 3K 25% 10  1%",
+				"node2 default    pod4 unknown   -        2K  1K 1K 50% 0   0%",
+				"",
+			},
+		},
+		{
+			"storage class",
+			[]string{"ssd"},
+			nil,
+			[]string{
+				"node1 awesome-ns pod3 data-pod3 ssd 4K 1K 3K 25% 10 1%",
+				"",
+			},
+		},
+		{
+			"excluded namespace",
+			[]string{},
+			excludeAwesome,
+			[]string{
+				"node1 default pod1 data-pod1 standard 10K 9K 0K 95% 100 10%",
+				"node2 default pod4 unknown   -        2K  1K 1K 50% 0   0%",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:        true,
+				noHeaders:      true,
+				kByte:          true,
+				table:          table.NewOutputTable(buffer),
+				client:         fake.NewSimpleClientset(pvcs...),
+				metrics:        stats,
+				storageClasses: test.storageClasses,
+				podFilter:      test.podFilter,
+			}
+			o.source = o.withPodFilter(newTestSource("", testNodes, pods))
+
+			if err := o.showVolumes(ctx, testNodes); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+
+	t.Run("nodes without stats", func(t *testing.T) {
+
+		buffer := &bytes.Buffer{}
+		errOut := &bytes.Buffer{}
+		o := &FreeOptions{
+			nocolor:   true,
+			noHeaders: true,
+			kByte:     true,
+			table:     table.NewOutputTable(buffer),
+			client:    fake.NewSimpleClientset(pvcs...),
+			metrics:   &testStatsSource{stats: map[string]*source.NodeStats{"node2": stats.stats["node2"]}},
+			source:    newTestSource("", testNodes, pods),
+		}
+		o.ErrOut = errOut
+
+		if err := o.showVolumes(ctx, testNodes); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+
+		expected := "node2 default pod4 unknown - 2K 1K 1K 50% 0 0%\n"
+		if buffer.String() != expected {
+			t.Errorf("expected(%q) differ (got: %q)", expected, buffer.String())
+		}
+		if !strings.Contains(errOut.String(), "warning: skipping volumes of node node1") {
+			t.Errorf("expected warning of node1 (got: %q)", errOut.String())
+		}
+
+		// no node has stats
+		o.metrics = &testStatsSource{}
+		if err := o.showVolumes(ctx, testNodes); err == nil {
+			t.Errorf("unexpected error: should return err")
+		}
+	})
+
+	t.Run("no cluster access", func(t *testing.T) {
+		o := &FreeOptions{table: table.NewOutputTable(&bytes.Buffer{})}
+		if err := o.showVolumes(ctx, testNodes); err == nil {
+			t.Errorf("unexpected error: should return err")
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// vpaResource is the VerticalPodAutoscaler CRD
+var vpaResource = schema.GroupVersionResource{Group: "autoscaling.k8s.io", Version: "v1", Resource: "verticalpodautoscalers"}
+
+// vpaObject is the part of a VerticalPodAutoscaler used to compare recommendations with requests
+type vpaObject struct {
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec struct {
+		TargetRef *autoscalingv1.CrossVersionObjectReference `json:"targetRef"`
+	} `json:"spec"`
+
+	Status struct {
+		Recommendation *struct {
+			ContainerRecommendations []vpaContainerRecommendation `json:"containerRecommendations"`
+		} `json:"recommendation"`
+	} `json:"status"`
+}
+
+// vpaContainerRecommendation is the recommendation of a container
+type vpaContainerRecommendation struct {
+	ContainerName string          `json:"containerName"`
+	Target        v1.ResourceList `json:"target"`
+	LowerBound    v1.ResourceList `json:"lowerBound"`
+	UpperBound    v1.ResourceList `json:"upperBound"`
+}
+
+// vpaRecommendation is the recommendation of a container with cpu in milli units and memory in bytes
+type vpaRecommendation struct {
+	cpuTarget int64
+	cpuLower  int64
+	cpuUpper  int64
+	memTarget int64
+	memLower  int64
+	memUpper  int64
+}
+
+// vpaKey identifies the recommendation of a container of a workload, e.g. default/Deployment/app/nginx
+func vpaKey(namespace, workload, container string) string {
+	return namespace + "/" + workload + "/" + container
+}
+
+// getVPARecommendations returns recommendations of VerticalPodAutoscalers by vpaKey (--vpa)
+// Without access to the cluster or the CRD, a warning is printed and the list is shown without recommendations.
+func (o *FreeOptions) getVPARecommendations(ctx context.Context) map[string]vpaRecommendation {
+
+	recommendations := map[string]vpaRecommendation{}
+
+	if o.dynamicClient == nil {
+		fmt.Fprintf(o.ErrOut, "warning: VerticalPodAutoscaler recommendations require access to the cluster\n")
+		return recommendations
+	}
+
+	list, err := o.dynamicClient.Resource(vpaResource).Namespace(o.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			fmt.Fprintf(o.ErrOut, "warning: VerticalPodAutoscaler CRD (%s) is not installed\n", vpaResource.GroupVersion())
+		} else {
+			fmt.Fprintf(o.ErrOut, "warning: failed to list VerticalPodAutoscalers: %v\n", err)
+		}
+		return recommendations
+	}
+
+	for _, item := range list.Items {
+		vpa := vpaObject{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &vpa); err != nil {
+			fmt.Fprintf(o.ErrOut, "warning: failed to read VerticalPodAutoscaler %s/%s: %v\n", item.GetNamespace(), item.GetName(), err)
+			continue
+		}
+		if vpa.Spec.TargetRef == nil || vpa.Status.Recommendation == nil {
+			continue
+		}
+
+		workload := vpa.Spec.TargetRef.Kind + "/" + vpa.Spec.TargetRef.Name
+		for _, r := range vpa.Status.Recommendation.ContainerRecommendations {
+			recommendations[vpaKey(vpa.Namespace, workload, r.ContainerName)] = vpaRecommendation{
+				cpuTarget: r.Target.Cpu().MilliValue(),
+				cpuLower:  r.LowerBound.Cpu().MilliValue(),
+				cpuUpper:  r.UpperBound.Cpu().MilliValue(),
+				memTarget: r.Target.Memory().Value(),
+				memLower:  r.LowerBound.Memory().Value(),
+				memUpper:  r.UpperBound.Memory().Value(),
+			}
+		}
+	}
+
+	return recommendations
+}
+
+// getVPADivergences returns requests of a container differing from the target by more than --vpa-divergence percent
+// e.g. ["cpu:+300%", "mem:-60%"]
+func (o *FreeOptions) getVPADivergences(info podInfo) []string {
+
+	divergences := []string{}
+	if info.vpa == nil {
+		return divergences
+	}
+
+	check := func(name string, requested, target int64) {
+		if target == 0 {
+			return
+		}
+		p := util.GetPercentage(requested-target, target)
+		if p > o.vpaDivergence || -p > o.vpaDivergence {
+			divergences = append(divergences, fmt.Sprintf("%s:%+d%%", name, p))
+		}
+	}
+	check("cpu", info.containerCPURequested, info.vpa.cpuTarget)
+	check("mem", info.containerMemoryRequested, info.vpa.memTarget)
+
+	return divergences
+}
+
+// vpaStatus returns the VPA column of a container: divergences, "ok" or "-" without recommendation
+func (o *FreeOptions) vpaStatus(info podInfo) string {
+
+	if info.vpa == nil {
+		return "-"
+	}
+
+	divergences := o.getVPADivergences(info)
+	if len(divergences) == 0 {
+		return "ok"
+	}
+
+	return strings.Join(divergences, ",")
+}
+
+// vpaCPUColumns returns the cpu target, lower and upper bound of a container
+func (o *FreeOptions) vpaCPUColumns(info podInfo) []string {
+	if info.vpa == nil {
+		return []string{"-", "-", "-"}
+	}
+	return []string{
+		o.toMilliUnitOrDash(info.vpa.cpuTarget), // cpu target
+		o.toMilliUnitOrDash(info.vpa.cpuLower),  // cpu lower bound
+		o.toMilliUnitOrDash(info.vpa.cpuUpper),  // cpu upper bound
+	}
+}
+
+// vpaMemoryColumns returns the memory target, lower and upper bound of a container
+func (o *FreeOptions) vpaMemoryColumns(info podInfo) []string {
+	if info.vpa == nil {
+		return []string{"-", "-", "-"}
+	}
+	return []string{
+		o.toUnitOrDash(info.vpa.memTarget), // memory target
+		o.toUnitOrDash(info.vpa.memLower),  // memory lower bound
+		o.toUnitOrDash(info.vpa.memUpper),  // memory upper bound
+	}
+}
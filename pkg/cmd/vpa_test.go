@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/source"
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func newTestVPAClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{vpaResource: "VerticalPodAutoscalerList"},
+		objects...,
+	)
+}
+
+func TestShowPodsOnNodeVPA(t *testing.T) {
+	ctx := context.Background()
+
+	resources := func(cpu, mem int64) v1.ResourceList {
+		return v1.ResourceList{
+			v1.ResourceCPU:    *resource.NewMilliQuantity(cpu, resource.DecimalSI),
+			v1.ResourceMemory: *resource.NewQuantity(mem, resource.DecimalSI),
+		}
+	}
+
+	controller := true
+	pod := func(name, ownerKind, ownerName string, containers ...v1.Container) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				Labels:          map[string]string{"pod-template-hash": "abc"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}},
+			},
+			Spec:   v1.PodSpec{NodeName: "node1", Containers: containers},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		}
+	}
+	container := func(name string, cpu, mem int64) v1.Container {
+		return v1.Container{Name: name, Resources: v1.ResourceRequirements{Requests: resources(cpu, mem)}}
+	}
+
+	pods := []v1.Pod{
+		pod("app-abc-1", "ReplicaSet", "app-abc", container("app", 100, 100), container("sidecar", 50, 64)),
+		pod("db-0", "StatefulSet", "db", container("db", 500, 1000)),
+	}
+
+	podMetrics := []metricsapiv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-abc-1", Namespace: "default"},
+			Containers: []metricsapiv1beta1.ContainerMetrics{{Name: "app", Usage: resources(150, 90)}},
+		},
+	}
+
+	vpa := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "autoscaling.k8s.io/v1",
+		"kind":       "VerticalPodAutoscaler",
+		"metadata":   map[string]interface{}{"name": "app", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"targetRef": map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "app"},
+		},
+		"status": map[string]interface{}{
+			"recommendation": map[string]interface{}{
+				"containerRecommendations": []interface{}{
+					map[string]interface{}{
+						"containerName": "app",
+						"target":        map[string]interface{}{"cpu": "400m", "memory": "100"},
+						"lowerBound":    map[string]interface{}{"cpu": "200m", "memory": "80"},
+						"upperBound":    map[string]interface{}{"cpu": "800m", "memory": "200"},
+					},
+					map[string]interface{}{
+						"containerName": "sidecar",
+						"target":        map[string]interface{}{"cpu": "50m", "memory": "64"},
+						"lowerBound":    map[string]interface{}{"cpu": "25m", "memory": "32"},
+						"upperBound":    map[string]interface{}{"cpu": "100m", "memory": "128"},
+					},
+				},
+			},
+		},
+	}}
+
+	notFound := newTestVPAClient()
+	notFound.PrependReactor("list", vpaResource.Resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(vpaResource.GroupResource(), "")
+	})
+
+	var tests = []struct {
+		description string
+		client      dynamic.Interface
+		expected    []string
+		expectedErr string
+	}{
+		{
+			"recommendations",
+			newTestVPAClient(vpa),
+			[]string{
+				"NODE NAME NAMESPACE POD NAME  POD STATUS QOS       PRIORITY CLASS CONTAINER RESTARTS LAST REASON CPU/use CPU/req CPU/vpa CPU/vpa-lo CPU/vpa-hi MEM/use MEM/req MEM/vpa MEM/vpa-lo MEM/vpa-hi VPA",
+				"node1     default   app-abc-1 Running    Burstable -              app       0        -           150m    100m    400m    200m       800m       90B     100B    100B    80B        200B       cpu:-75%",
+				"node1     default   app-abc-1 Running    Burstable -              sidecar   0        -           -       50m     50m     25m        100m       -       64B     64B     32B        128B       ok",
+				"node1     default   db-0      Running    Burstable -              db        0        -           -       500m    -       -          -          -       1000B   -       -          -          -",
+				"",
+			},
+			"",
+		},
+		{
+			"crd not installed",
+			notFound,
+			[]string{
+				"NODE NAME NAMESPACE POD NAME  POD STATUS QOS       PRIORITY CLASS CONTAINER RESTARTS LAST REASON CPU/use CPU/req CPU/vpa CPU/vpa-lo CPU/vpa-hi MEM/use MEM/req MEM/vpa MEM/vpa-lo MEM/vpa-hi VPA",
+				"node1     default   app-abc-1 Running    Burstable -              app       0        -           150m    100m    -       -          -          90B     100B    -       -          -          -",
+				"node1     default   app-abc-1 Running    Burstable -              sidecar   0        -           -       50m     -       -          -          -       64B     -       -          -          -",
+				"node1     default   db-0      Running    Burstable -              db        0        -           -       500m    -       -          -          -       1000B   -       -          -          -",
+				"",
+			},
+			"warning: VerticalPodAutoscaler CRD (autoscaling.k8s.io/v1) is not installed\n",
+		},
+		{
+			"no cluster access",
+			nil,
+			[]string{
+				"NODE NAME NAMESPACE POD NAME  POD STATUS QOS       PRIORITY CLASS CONTAINER RESTARTS LAST REASON CPU/use CPU/req CPU/vpa CPU/vpa-lo CPU/vpa-hi MEM/use MEM/req MEM/vpa MEM/vpa-lo MEM/vpa-hi VPA",
+				"node1     default   app-abc-1 Running    Burstable -              app       0        -           150m    100m    -       -          -          90B     100B    -       -          -          -",
+				"node1     default   app-abc-1 Running    Burstable -              sidecar   0        -           -       50m     -       -          -          -       64B     -       -          -          -",
+				"node1     default   db-0      Running    Burstable -              db        0        -           -       500m    -       -          -          -       1000B   -       -          -          -",
+				"",
+			},
+			"warning: VerticalPodAutoscaler recommendations require access to the cluster\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			buffer := &bytes.Buffer{}
+			errBuffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:       true,
+				bytes:         true,
+				compactView:   true,
+				vpa:           true,
+				vpaDivergence: 50,
+				table:         table.NewOutputTable(buffer),
+				source:        source.NewStaticSource("", testNodes, pods, nil, podMetrics),
+				dynamicClient: test.client,
+			}
+			o.ErrOut = errBuffer
+			o.prepareListTableHeader()
+
+			if err := o.showPodsOnNode(ctx, testNodes); err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(\n%s) differ (got: \n%s)", test.description, expected, buffer.String())
+			}
+			if errBuffer.String() != test.expectedErr {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, test.expectedErr, errBuffer.String())
+			}
+		})
+	}
+}
+
+func TestGetVPADivergences(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		info        podInfo
+		expected    []string
+	}{
+		{
+			"within divergence",
+			podInfo{containerCPURequested: 140, containerMemoryRequested: 60, vpa: &vpaRecommendation{cpuTarget: 100, memTarget: 100}},
+			[]string{},
+		},
+		{
+			"above and below target",
+			podInfo{containerCPURequested: 400, containerMemoryRequested: 40, vpa: &vpaRecommendation{cpuTarget: 100, memTarget: 100}},
+			[]string{"cpu:+300%", "mem:-60%"},
+		},
+		{
+			"without requests",
+			podInfo{vpa: &vpaRecommendation{cpuTarget: 100}},
+			[]string{"cpu:-100%"},
+		},
+		{
+			"without recommendation",
+			podInfo{containerCPURequested: 400},
+			[]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			o := &FreeOptions{vpaDivergence: 50}
+			actual := o.getVPADivergences(test.info)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
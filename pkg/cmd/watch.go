@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+)
+
+// clearScreen moves the cursor to the top left corner and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// runWatch prints the output every watchInterval until ctx is done
+// Nodes and pods are served from a cache, so refreshing doesn't list them again.
+// Every output is rendered completely before the terminal gets redrawn to avoid flickering.
+func (o *FreeOptions) runWatch(ctx context.Context, args []string) error {
+
+	if err := o.startCache(ctx); err != nil {
+		return err
+	}
+
+	out := o.table.Output
+
+	ticker := time.NewTicker(o.watchInterval)
+	defer ticker.Stop()
+
+	for {
+		buffer := &bytes.Buffer{}
+		o.table = table.NewOutputTable(buffer)
+
+		if !o.noHeaders {
+			fmt.Fprintf(buffer, "Every %s: kubectl free %s\t%s\n\n", o.watchInterval, strings.Join(args, " "), time.Now().Format(time.RFC1123))
+		}
+
+		rctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		if err := o.runOnce(rctx, args); err != nil {
+			// keep watching, the api server might be back with the next sample
+			fmt.Fprintf(buffer, "error: %v\n", err)
+		}
+		cancel()
+
+		fmt.Fprint(out, clearScreen+buffer.String())
+
+		select {
+		case <-ctx.Done():
+			o.table = table.NewOutputTable(out)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestShowFreeDelta(t *testing.T) {
+	ctx := context.Background()
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:   true,
+		table:     table.NewOutputTable(buffer),
+		noHeaders: true,
+		source:    newTestSource("default", testNodes[:1], testPods[:1]),
+		prevNodeFree: map[string]nodeFree{
+			"node1": {
+				name:           "node1",
+				cpuUsed:        400,
+				cpuRequested:   1000,
+				cpuLimited:     1000,
+				cpuAllocatable: 4000,
+				memUsed:        0,
+				memRequested:   1000,
+				memLimited:     4000,
+				memAllocatable: 4000,
+			},
+		},
+	}
+
+	if err := o.showFree(ctx, []v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := "node1 Ready 100m 1 2 4 2%▼ 25% 50%▲ 1K 1K 2K 4K 25%▲ 25% 50%▼\n"
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		return
+	}
+
+	if _, ok := o.prevNodeFree["node1"]; !ok {
+		t.Errorf("expected sample of node1 to be remembered")
+	}
+}
+
+func TestShowPodsOnNodeDelta(t *testing.T) {
+	ctx := context.Background()
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:     true,
+		compactView: true,
+		table:       table.NewOutputTable(buffer),
+		noHeaders:   true,
+		source:      newTestSource("default", testNodes[:1], testPods[:1]),
+		prevContainers: map[string]podInfo{
+			"node1/default/pod1/container1": {
+				containerCPUUsed:    resource.NewMilliQuantity(5, resource.DecimalSI),
+				containerMemoryUsed: resource.NewQuantity(20, resource.DecimalSI),
+			},
+		},
+	}
+
+	if err := o.showPodsOnNode(ctx, []v1.Node{testNodes[0]}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := "node1 default pod1 Running Burstable - container1 0 - 10m▲ 0K▼\n"
+	if buffer.String() != expected {
+		t.Errorf("expected(%s) differ (got: %s)", expected, buffer.String())
+		return
+	}
+}
+
+func TestRunWatch(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	o := &FreeOptions{
+		nocolor:       true,
+		noMetrics:     true,
+		noHeaders:     true,
+		table:         table.NewOutputTable(buffer),
+		watch:         true,
+		watchInterval: time.Hour,
+		source:        newTestSource("default", testNodes[:1], testPods[:1]),
+	}
+
+	// stop after the first output
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := o.runWatch(ctx, []string{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := strings.Join([]string{
+		clearScreen + "node1 Ready 1 2 4 25% 50% 1K 2K 4K 25% 50%",
+		"",
+	}, "\n")
+	if buffer.String() != expected {
+		t.Errorf("expected(%q) differ (got: %q)", expected, buffer.String())
+		return
+	}
+
+	if o.table.Output != buffer {
+		t.Errorf("expected table output to be restored")
+	}
+}
@@ -0,0 +1,455 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// whereFieldKind is the type of a field of --where
+// It defines how values of comparisons are parsed.
+type whereFieldKind int
+
+const (
+	whereString   whereFieldKind = iota // e.g. Ready
+	whereMilli                          // cpu, e.g. 500m or 2
+	whereBytes                          // memory, e.g. 512Mi or 1G
+	wherePercent                        // e.g. 80 or 80%
+	whereNumber                         // e.g. 3
+	whereDuration                       // e.g. 7d or 12h
+)
+
+// whereField is the value of a field of a row
+type whereField struct {
+	kind whereFieldKind
+	s    string
+	n    int64
+}
+
+// whereExpr is a parsed --where expression
+type whereExpr interface {
+	eval(fields map[string]whereField) (bool, error)
+}
+
+// whereAnd is <expr> && <expr>
+type whereAnd struct {
+	left, right whereExpr
+}
+
+func (e whereAnd) eval(fields map[string]whereField) (bool, error) {
+	ok, err := e.left.eval(fields)
+	if err != nil || !ok {
+		return false, err
+	}
+	return e.right.eval(fields)
+}
+
+// whereOr is <expr> || <expr>
+type whereOr struct {
+	left, right whereExpr
+}
+
+func (e whereOr) eval(fields map[string]whereField) (bool, error) {
+	ok, err := e.left.eval(fields)
+	if err != nil || ok {
+		return ok, err
+	}
+	return e.right.eval(fields)
+}
+
+// whereNot is !<expr>
+type whereNot struct {
+	expr whereExpr
+}
+
+func (e whereNot) eval(fields map[string]whereField) (bool, error) {
+	ok, err := e.expr.eval(fields)
+	return !ok, err
+}
+
+// whereComparison is <field> <operator> <value>, e.g. mem.req% > 80
+type whereComparison struct {
+	field    string
+	operator string
+	value    string
+	re       *regexp.Regexp
+}
+
+func (e whereComparison) eval(fields map[string]whereField) (bool, error) {
+
+	f, ok := fields[e.field]
+	if !ok {
+		names := maps.Keys(fields)
+		slices.Sort(names)
+		return false, fmt.Errorf("unknown field %q of --where, fields are %s", e.field, strings.Join(names, ", "))
+	}
+
+	// regular expressions
+	if e.re != nil {
+		if f.kind != whereString {
+			return false, fmt.Errorf("operator %s of --where can't be used with field %q", e.operator, e.field)
+		}
+		return e.re.MatchString(f.s) == (e.operator == "=~"), nil
+	}
+
+	if f.kind == whereString {
+		switch e.operator {
+		case "==":
+			return f.s == e.value, nil
+		case "!=":
+			return f.s != e.value, nil
+		default:
+			return false, fmt.Errorf("operator %s of --where can't be used with field %q", e.operator, e.field)
+		}
+	}
+
+	v, err := parseWhereValue(f.kind, e.value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value of field %q of --where: %v", e.field, err)
+	}
+
+	switch e.operator {
+	case "==":
+		return f.n == v, nil
+	case "!=":
+		return f.n != v, nil
+	case ">":
+		return f.n > v, nil
+	case ">=":
+		return f.n >= v, nil
+	case "<":
+		return f.n < v, nil
+	default:
+		return f.n <= v, nil
+	}
+}
+
+// parseWhereValue parses the value of a comparison with a numeric field
+func parseWhereValue(kind whereFieldKind, s string) (int64, error) {
+	switch kind {
+	case whereMilli:
+		q, err := resource.ParseQuantity(s)
+		if err != nil {
+			return 0, err
+		}
+		return q.MilliValue(), nil
+	case whereBytes:
+		q, err := resource.ParseQuantity(s)
+		if err != nil {
+			return 0, err
+		}
+		return q.Value(), nil
+	case wherePercent:
+		return strconv.ParseInt(strings.TrimSuffix(s, "%"), 10, 64)
+	case whereDuration:
+		// days are not supported by time.ParseDuration
+		if days, ok := strings.CutSuffix(s, "d"); ok {
+			d, err := strconv.ParseInt(days, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return int64(time.Duration(d) * 24 * time.Hour), nil
+		}
+		d, err := time.ParseDuration(s)
+		return int64(d), err
+	default:
+		return strconv.ParseInt(s, 10, 64)
+	}
+}
+
+// whereToken is a token of a --where expression
+type whereToken struct {
+	operator bool
+	text     string
+}
+
+// whereOperators are the operators of --where, longer operators first
+var whereOperators = []string{"&&", "||", "==", "!=", ">=", "<=", "=~", "!~", ">", "<", "!", "(", ")"}
+
+// tokenizeWhere splits a --where expression into words, quoted strings and operators
+func tokenizeWhere(s string) ([]whereToken, error) {
+
+	tokens := []whereToken{}
+	for i := 0; i < len(s); {
+
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		// quoted string
+		if s[i] == '"' || s[i] == '\'' {
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end < 0 {
+				return nil, fmt.Errorf("missing closing quote in --where at %d", i)
+			}
+			tokens = append(tokens, whereToken{text: s[i+1 : i+1+end]})
+			i += end + 2
+			continue
+		}
+
+		// operator
+		operator := ""
+		for _, op := range whereOperators {
+			if strings.HasPrefix(s[i:], op) {
+				operator = op
+				break
+			}
+		}
+		if operator != "" {
+			tokens = append(tokens, whereToken{operator: true, text: operator})
+			i += len(operator)
+			continue
+		}
+
+		// word
+		start := i
+		for i < len(s) && !strings.ContainsRune(" \t\"'()!&|=<>~", rune(s[i])) {
+			i++
+		}
+		if start == i {
+			return nil, fmt.Errorf("unexpected %q in --where at %d", s[i], i)
+		}
+		tokens = append(tokens, whereToken{text: s[start:i]})
+	}
+
+	return tokens, nil
+}
+
+// whereParser is a recursive descent parser of --where expressions
+//
+//	expr       = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" expr ")" | comparison
+//	comparison = field operator value
+type whereParser struct {
+	tokens []whereToken
+	pos    int
+	fields map[string]whereField
+}
+
+// parseWhere parses a --where expression, e.g. "mem.req% > 80 && status == Ready"
+// Comparisons are checked against fields (of nodes or containers), so a typo is an error even
+// if the comparison is never evaluated, e.g. with no rows or on the short-circuited side of ||.
+func parseWhere(s string, fields map[string]whereField) (whereExpr, error) {
+
+	tokens, err := tokenizeWhere(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty --where expression")
+	}
+
+	p := &whereParser{tokens: tokens, fields: fields}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in --where", p.tokens[p.pos].text)
+	}
+
+	return expr, nil
+}
+
+// accept consumes the next token if it is the operator
+func (p *whereParser) accept(operator string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].operator && p.tokens[p.pos].text == operator {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// next consumes the next token
+func (p *whereParser) next() (whereToken, error) {
+	if p.pos >= len(p.tokens) {
+		return whereToken{}, fmt.Errorf("unexpected end of --where")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *whereParser) parseOr() (whereExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = whereOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseAnd() (whereExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = whereAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *whereParser) parseUnary() (whereExpr, error) {
+	if p.accept("!") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return whereNot{expr: expr}, nil
+	}
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ) in --where")
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *whereParser) parseComparison() (whereExpr, error) {
+
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if field.operator {
+		return nil, fmt.Errorf("expected field in --where, got %q", field.text)
+	}
+
+	operator, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if !operator.operator || !slices.Contains([]string{"==", "!=", ">=", "<=", "=~", "!~", ">", "<"}, operator.text) {
+		return nil, fmt.Errorf("expected comparison operator after %q in --where, got %q", field.text, operator.text)
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if value.operator {
+		return nil, fmt.Errorf("expected value after %q in --where, got %q", field.text+" "+operator.text, value.text)
+	}
+
+	c := whereComparison{field: field.text, operator: operator.text, value: value.text}
+	if c.operator == "=~" || c.operator == "!~" {
+		if c.re, err = regexp.Compile(c.value); err != nil {
+			return nil, fmt.Errorf("invalid regular expression in --where: %v", err)
+		}
+	}
+
+	// unknown fields, operators of other kinds of fields and invalid values
+	if _, err := c.eval(p.fields); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// nodeWhereFields returns the fields of a node for --where
+func nodeWhereFields(nf nodeFree) map[string]whereField {
+	p := nf.percentages()
+	return map[string]whereField{
+		"name":       {kind: whereString, s: nf.name},
+		"status":     {kind: whereString, s: nf.status},
+		"cpu.use":    {kind: whereMilli, n: nf.cpuUsed},
+		"cpu.req":    {kind: whereMilli, n: nf.cpuRequested},
+		"cpu.lim":    {kind: whereMilli, n: nf.cpuLimited},
+		"cpu.alloc":  {kind: whereMilli, n: nf.cpuAllocatable},
+		"cpu.use%":   {kind: wherePercent, n: p.cpuUsed},
+		"cpu.req%":   {kind: wherePercent, n: p.cpuRequested},
+		"cpu.lim%":   {kind: wherePercent, n: p.cpuLimited},
+		"mem.use":    {kind: whereBytes, n: nf.memUsed},
+		"mem.req":    {kind: whereBytes, n: nf.memRequested},
+		"mem.lim":    {kind: whereBytes, n: nf.memLimited},
+		"mem.alloc":  {kind: whereBytes, n: nf.memAllocatable},
+		"mem.use%":   {kind: wherePercent, n: p.memUsed},
+		"mem.req%":   {kind: wherePercent, n: p.memRequested},
+		"mem.lim%":   {kind: wherePercent, n: p.memLimited},
+		"pods":       {kind: whereNumber, n: int64(nf.podCount)},
+		"containers": {kind: whereNumber, n: int64(nf.containerCount)},
+		"oom":        {kind: whereNumber, n: int64(nf.oomCount)},
+	}
+}
+
+// podWhereFields returns the fields of a container (or a pod with --per-pod) for --where
+// Usage without metrics is 0.
+func podWhereFields(info podInfo) map[string]whereField {
+
+	var cpuUsed, memUsed, age int64
+	if info.containerCPUUsed != nil {
+		cpuUsed = info.containerCPUUsed.MilliValue()
+	}
+	if info.containerMemoryUsed != nil {
+		memUsed = info.containerMemoryUsed.Value()
+	}
+	if !info.podCreated.IsZero() {
+		age = int64(time.Since(info.podCreated))
+	}
+
+	return map[string]whereField{
+		"node":          {kind: whereString, s: info.nodeName},
+		"namespace":     {kind: whereString, s: info.podNamespace},
+		"pod":           {kind: whereString, s: info.podName},
+		"status":        {kind: whereString, s: info.podStatus},
+		"phase":         {kind: whereString, s: info.podPhase},
+		"qos":           {kind: whereString, s: info.podQOSClass},
+		"priorityclass": {kind: whereString, s: info.podPriorityClass},
+		"priority":      {kind: whereNumber, n: int64(info.podPriority)},
+		"age":           {kind: whereDuration, n: age},
+		"container":     {kind: whereString, s: info.containerName},
+		"state":         {kind: whereString, s: info.containerState},
+		"restarts":      {kind: whereNumber, n: int64(info.containerRestarts)},
+		"reason":        {kind: whereString, s: info.containerLastReason},
+		"image":         {kind: whereString, s: info.containerImage},
+		"cpu.use":       {kind: whereMilli, n: cpuUsed},
+		"cpu.req":       {kind: whereMilli, n: info.containerCPURequested},
+		"cpu.lim":       {kind: whereMilli, n: info.containerCPULimit},
+		"mem.use":       {kind: whereBytes, n: memUsed},
+		"mem.req":       {kind: whereBytes, n: info.containerMemoryRequested},
+		"mem.lim":       {kind: whereBytes, n: info.containerMemoryLimit},
+		"mem.lim%":      {kind: wherePercent, n: util.GetPercentage(memUsed, info.containerMemoryLimit)},
+	}
+}
+
+// matchNode reports whether a node matches --where
+func (o *FreeOptions) matchNode(nf nodeFree) (bool, error) {
+	if o.where == nil {
+		return true, nil
+	}
+	return o.where.eval(nodeWhereFields(nf))
+}
+
+// matchPod reports whether a container (or a pod with --per-pod) matches --where
+func (o *FreeOptions) matchPod(info podInfo) (bool, error) {
+	if o.where == nil {
+		return true, nil
+	}
+	return o.where.eval(podWhereFields(info))
+}
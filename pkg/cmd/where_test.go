@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/table"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseWhere(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		where       string
+		expected    string
+	}{
+		{"empty", " ", "empty --where expression"},
+		{"missing operator", "status Ready", `expected comparison operator after "status" in --where, got "Ready"`},
+		{"missing value", "status ==", "unexpected end of --where"},
+		{"missing field", "== Ready", `expected field in --where, got "=="`},
+		{"missing paren", "(status == Ready", "missing ) in --where"},
+		{"unexpected token", "status == Ready)", `unexpected ")" in --where`},
+		{"missing quote", "namespace == 'team-a", "missing closing quote in --where at 13"},
+		{"invalid regexp", "namespace =~ '^team-('", "invalid regular expression in --where: error parsing regexp: missing closing ): `^team-(`"},
+		{"unknown field", "disk.use > 1", `unknown field "disk.use" of --where, fields are `},
+		{"unknown field never evaluated", "status != Ready || cpu.reqq > 70", `unknown field "cpu.reqq" of --where, fields are `},
+		{"regexp of number", "restarts =~ 3", `operator =~ of --where can't be used with field "restarts"`},
+		{"order of string", "status > Running", `operator > of --where can't be used with field "status"`},
+		{"invalid value", "mem.lim > lots", `invalid value of field "mem.lim" of --where: quantities must match the regular expression`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			_, err := parseWhere(test.where, podWhereFields(podInfo{}))
+			if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+				t.Errorf("[%s] expected(%s) differ (got: %v)", test.description, test.expected, err)
+			}
+		})
+	}
+}
+
+func TestWhereEval(t *testing.T) {
+
+	memUsed := resource.MustParse("900Mi")
+	info := podInfo{
+		nodeName:             "node1",
+		podNamespace:         "team-a",
+		podName:              "pod1",
+		podStatus:            "Running",
+		podCreated:           time.Now().Add(-10 * 24 * time.Hour),
+		containerName:        "app",
+		containerRestarts:    3,
+		containerCPULimit:    500,
+		containerMemoryUsed:  &memUsed,
+		containerMemoryLimit: 1024 * 1024 * 1024,
+	}
+
+	var tests = []struct {
+		description string
+		where       string
+		expected    bool
+	}{
+		{"string", "status == Running", true},
+		{"quoted string", `namespace != "team-a"`, false},
+		{"regexp", "namespace =~ ^team-", true},
+		{"not regexp", "pod !~ ^pod", false},
+		{"cpu", "cpu.lim >= 0.5", true},
+		{"cpu milli", "cpu.lim > 500m", false},
+		{"memory", "mem.lim == 1Gi", true},
+		{"memory without limit", "mem.req == 0", true},
+		{"percent of limit", "mem.lim% > 80%", true},
+		{"number", "restarts >= 3", true},
+		{"age days", "age > 7d", true},
+		{"age duration", "age < 240h", false},
+		{"and", "status == Running && restarts > 5", false},
+		{"or", "status == Pending || restarts > 2", true},
+		{"not and parens", "!(status == Pending || restarts > 5) && container == app", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			where, err := parseWhere(test.where, podWhereFields(podInfo{}))
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual, err := where.eval(podWhereFields(info))
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestShowWhere(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		description string
+		list        bool
+		where       string
+		expected    []string
+	}{
+		{
+			"nodes",
+			false,
+			"cpu.req% > 40 && status == Ready",
+			[]string{
+				"node1 Ready 1700m 2700m 4 42% 67% 2K 3K 4K 57% 82%",
+				"",
+			},
+		},
+		{
+			"containers",
+			true,
+			"namespace =~ ^default$ && mem.lim == 0",
+			[]string{
+				"node1 default pod2 Running Burstable - container2b 0 - - - - -",
+				"",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			fields := nodeWhereFields(nodeFree{})
+			if test.list {
+				fields = podWhereFields(podInfo{})
+			}
+			where, err := parseWhere(test.where, fields)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			buffer := &bytes.Buffer{}
+			o := &FreeOptions{
+				nocolor:     true,
+				noHeaders:   true,
+				noMetrics:   true,
+				kByte:       true,
+				listAll:     true,
+				compactView: true,
+				where:       where,
+				table:       table.NewOutputTable(buffer),
+				source:      newTestSource("", testNodes, testPods),
+			}
+
+			if test.list {
+				err = o.showPodsOnNode(ctx, testNodes)
+			} else {
+				err = o.showFree(ctx, testNodes)
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			expected := strings.Join(test.expected, "\n")
+			if buffer.String() != expected {
+				t.Errorf("[%s] expected(%q) differ (got: %q)", test.description, expected, buffer.String())
+			}
+		})
+	}
+}
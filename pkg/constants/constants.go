@@ -77,4 +77,62 @@ const (
 
 	// EmojiPodUnknown is unknown emoji for pod status
 	EmojiPodUnknown = "❓"
+
+	// EmojiPodCrashLoopBackOff is crash loop emoji for pod status
+	EmojiPodCrashLoopBackOff = "🔁"
+
+	// EmojiPodOOMKilled is out of memory emoji for pod status
+	EmojiPodOOMKilled = "💥"
+
+	// EmojiPodTerminating is terminating emoji for pod status
+	EmojiPodTerminating = "⏳"
+
+	//
+	// Delta
+	//
+
+	// DeltaUp is arrow for increased values since the previous sample
+	DeltaUp = "▲"
+
+	// DeltaDown is arrow for decreased values since the previous sample
+	DeltaDown = "▼"
+
+	//
+	// History
+	//
+
+	// SparklineBars are bars of sparklines from low to high
+	SparklineBars = "▁▂▃▄▅▆▇█"
+
+	//
+	// Tree
+	//
+
+	// TreeBranch is the branch to an entry of the tree
+	TreeBranch = "├── "
+
+	// TreeLastBranch is the branch to the last entry of a level of the tree
+	TreeLastBranch = "└── "
+
+	// TreeIndent is the indent of children of an entry of the tree
+	TreeIndent = "│   "
+
+	// TreeLastIndent is the indent of children of the last entry of a level of the tree
+	TreeLastIndent = "    "
+
+	//
+	// Overhead classes of pods (--overhead)
+	//
+
+	// PodClassStatic is a static pod of the kubelet (mirror pod)
+	PodClassStatic = "static"
+
+	// PodClassDaemonSet is a pod of a DaemonSet
+	PodClassDaemonSet = "daemonset"
+
+	// PodClassSystem is another pod in the kube-system namespace
+	PodClassSystem = "system"
+
+	// PodClassOther is any other pod
+	PodClassOther = "other"
 )
@@ -0,0 +1,223 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// nodeNameIndex is the name of the pod index by spec.nodeName
+const nodeNameIndex = "nodeName"
+
+// CacheSource serves nodes and pods from shared informers and polls metrics periodically
+// It is meant for long running modes like --watch, so repeated calls don't hit the api server.
+type CacheSource struct {
+	factory     informers.SharedInformerFactory
+	nodeLister  corelisters.NodeLister
+	podInformer cache.SharedIndexInformer
+
+	metricsClient metrics.Interface
+	namespace     string
+	interval      time.Duration
+
+	mu          sync.RWMutex
+	nodeMetrics map[string]*metricsapiv1beta1.NodeMetrics
+	podMetrics  *metricsapiv1beta1.PodMetricsList
+	metricsErr  error
+}
+
+// NewCacheSource is an instance of CacheSource
+// Pods are watched in namespace (empty for all namespaces) and metrics are polled every interval.
+// metricsClient can be nil if metrics are not available.
+func NewCacheSource(client kubernetes.Interface, metricsClient metrics.Interface, namespace string, interval time.Duration) *CacheSource {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(namespace))
+
+	s := &CacheSource{
+		factory:       factory,
+		nodeLister:    factory.Core().V1().Nodes().Lister(),
+		podInformer:   factory.Core().V1().Pods().Informer(),
+		metricsClient: metricsClient,
+		namespace:     namespace,
+		interval:      interval,
+		metricsErr:    fmt.Errorf("no metrics polled yet"),
+	}
+
+	return s
+}
+
+// Start starts the informers and the metrics poller and waits up to syncTimeout for the first data
+// The cache is kept up to date until ctx is done. An unreachable or forbidden api server
+// is an error after syncTimeout rather than waiting until ctx is done.
+func (s *CacheSource) Start(ctx context.Context, syncTimeout time.Duration) error {
+
+	if err := s.podInformer.AddIndexers(cache.Indexers{nodeNameIndex: indexByNodeName}); err != nil {
+		return fmt.Errorf("failed to add pod index: %v", err)
+	}
+
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	s.factory.Start(ctx.Done())
+	for informer, synced := range s.factory.WaitForCacheSync(syncCtx.Done()) {
+		if !synced {
+			return fmt.Errorf("failed to list %v within %s, check access to the api server", informer, syncTimeout)
+		}
+	}
+
+	// first metrics are available right after start
+	s.pollMetrics(syncCtx)
+	go wait.UntilWithContext(ctx, s.pollMetrics, s.interval)
+
+	return nil
+}
+
+// indexByNodeName indexes pods by spec.nodeName
+func indexByNodeName(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return []string{}, nil
+	}
+	return []string{pod.Spec.NodeName}, nil
+}
+
+// pollMetrics fetches metrics of all nodes and pods
+func (s *CacheSource) pollMetrics(ctx context.Context) {
+	if s.metricsClient == nil {
+		return
+	}
+
+	nodeMetrics := map[string]*metricsapiv1beta1.NodeMetrics{}
+	nml, err := s.metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err == nil {
+		for i := range nml.Items {
+			nodeMetrics[nml.Items[i].ObjectMeta.Name] = &nml.Items[i]
+		}
+	}
+
+	pml, perr := s.metricsClient.MetricsV1beta1().PodMetricses(s.namespace).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		err = perr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// keep the last metrics on errors
+	s.metricsErr = err
+	if err == nil {
+		s.nodeMetrics = nodeMetrics
+		s.podMetrics = pml
+	}
+}
+
+// GetNodes returns the given nodes or all nodes matching the label selector
+func (s *CacheSource) GetNodes(_ context.Context, names []string, labelSelector string) ([]v1.Node, error) {
+	nodes := []v1.Node{}
+
+	if len(names) > 0 {
+		for _, name := range names {
+			n, err := s.nodeLister.Get(name)
+			if err != nil {
+				return nodes, fmt.Errorf("failed to get node: %v", err)
+			}
+			nodes = append(nodes, *n)
+		}
+		return nodes, nil
+	}
+
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nodes, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	na, err := s.nodeLister.List(selector)
+	if err != nil {
+		return nodes, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	for _, n := range na {
+		nodes = append(nodes, *n)
+	}
+
+	// same order as the api server
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ObjectMeta.Name < nodes[j].ObjectMeta.Name
+	})
+
+	return nodes, nil
+}
+
+// GetPods returns pods on a node
+func (s *CacheSource) GetPods(_ context.Context, nodeName string) (*v1.PodList, error) {
+	objs, err := s.podInformer.GetIndexer().ByIndex(nodeNameIndex, nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods: %s", err)
+	}
+
+	pods := &v1.PodList{}
+	for _, obj := range objs {
+		if pod, ok := obj.(*v1.Pod); ok {
+			pods.Items = append(pods.Items, *pod)
+		}
+	}
+
+	// same order as the api server
+	sort.Slice(pods.Items, func(i, j int) bool {
+		a, b := pods.Items[i].ObjectMeta, pods.Items[j].ObjectMeta
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	return pods, nil
+}
+
+// GetNodeMetrics returns usage of a node from the last poll
+func (s *CacheSource) GetNodeMetrics(_ context.Context, nodeName string) (*metricsapiv1beta1.NodeMetrics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.metricsClient == nil {
+		return nil, fmt.Errorf("no metrics client")
+	}
+
+	m, ok := s.nodeMetrics[nodeName]
+	if !ok {
+		if s.metricsErr != nil {
+			return nil, s.metricsErr
+		}
+		return nil, fmt.Errorf("no metrics of node %q", nodeName)
+	}
+
+	return m, nil
+}
+
+// GetPodMetrics returns usage of all pods from the last poll
+func (s *CacheSource) GetPodMetrics(_ context.Context) (*metricsapiv1beta1.PodMetricsList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.metricsClient == nil {
+		return nil, fmt.Errorf("no metrics client")
+	}
+
+	if s.podMetrics == nil {
+		return nil, s.metricsErr
+	}
+
+	return s.podMetrics, nil
+}
@@ -0,0 +1,127 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	fake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	fakemetrics "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestCacheSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := fake.NewSimpleClientset(&testNodes[0], &testNodes[1], &testPods[0], &testPods[1], &testPods[2])
+	metricsClient := prepareTestMetricsClient()
+
+	s := NewCacheSource(client, metricsClient, "", time.Hour)
+	if err := s.Start(ctx, time.Minute); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	t.Run("get nodes", func(t *testing.T) {
+		nodes, err := s.GetNodes(ctx, []string{}, "hostname=node1")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if actual := nodeNames(nodes); !reflect.DeepEqual(actual, []string{"node1"}) {
+			t.Errorf("expected([node1]) differ (got: %v)", actual)
+		}
+	})
+
+	t.Run("get unknown node", func(t *testing.T) {
+		if _, err := s.GetNodes(ctx, []string{"foobar"}, ""); err == nil {
+			t.Errorf("unexpected error: should return err")
+		}
+	})
+
+	t.Run("get pods by node name", func(t *testing.T) {
+		pods, err := s.GetPods(ctx, "node1")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		// sorted by namespace and name
+		if actual := podNames(pods); !reflect.DeepEqual(actual, []string{"pod2", "pod1"}) {
+			t.Errorf("expected([pod2 pod1]) differ (got: %v)", actual)
+		}
+	})
+
+	t.Run("get polled metrics", func(t *testing.T) {
+		m, err := s.GetNodeMetrics(ctx, "node1")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if m.Usage.Cpu().MilliValue() != 100 {
+			t.Errorf("expected(100) differ (got: %d)", m.Usage.Cpu().MilliValue())
+		}
+
+		pm, err := s.GetPodMetrics(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(pm.Items) != 2 {
+			t.Errorf("expected(2) differ (got: %d)", len(pm.Items))
+		}
+	})
+}
+
+func TestCacheSourceWithoutMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := fake.NewSimpleClientset(&testNodes[0])
+
+	s := NewCacheSource(client, nil, "", time.Hour)
+	if err := s.Start(ctx, time.Minute); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if _, err := s.GetNodeMetrics(ctx, "node1"); err == nil {
+		t.Errorf("unexpected error: should return err")
+	}
+	if _, err := s.GetPodMetrics(ctx); err == nil {
+		t.Errorf("unexpected error: should return err")
+	}
+}
+
+func TestCacheSourceSyncTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// listing nodes is forbidden, the informer retries until ctx is done
+	client := fake.NewSimpleClientset(&testNodes[0])
+	client.PrependReactor("list", "nodes", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, fmt.Errorf("nodes is forbidden")
+	})
+
+	s := NewCacheSource(client, nil, "", time.Hour)
+	if err := s.Start(ctx, 100*time.Millisecond); err == nil {
+		t.Errorf("unexpected error: should return err")
+	}
+}
+
+// prepareTestMetricsClient returns a metrics client listing the test metrics
+// The object tracker of the fake metrics client doesn't map metrics to their resources.
+func prepareTestMetricsClient() *fakemetrics.Clientset {
+	fakeMetricsClient := &fakemetrics.Clientset{}
+	fakeMetricsClient.AddReactor("list", "nodes", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &metricsapiv1beta1.NodeMetricsList{Items: testNodeMetrics}, nil
+	})
+	fakeMetricsClient.AddReactor("list", "pods", func(action core.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &metricsapiv1beta1.PodMetricsList{Items: testPodMetrics}, nil
+	})
+	return fakeMetricsClient
+}
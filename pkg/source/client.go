@@ -0,0 +1,64 @@
+package source
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thirdeyenick/kubectl-free/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsv1beta1 "k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1"
+)
+
+// ClientSource requests all data from the api server on every call
+type ClientSource struct {
+	nodeClient        clientv1.NodeInterface
+	podClient         clientv1.PodInterface
+	metricsNodeClient metricsv1beta1.NodeMetricsInterface
+	metricsPodClient  metricsv1beta1.PodMetricsInterface
+}
+
+// NewClientSource is an instance of ClientSource
+// Metrics clients can be nil if metrics are not available.
+func NewClientSource(
+	nodeClient clientv1.NodeInterface,
+	podClient clientv1.PodInterface,
+	metricsNodeClient metricsv1beta1.NodeMetricsInterface,
+	metricsPodClient metricsv1beta1.PodMetricsInterface,
+) *ClientSource {
+	return &ClientSource{
+		nodeClient:        nodeClient,
+		podClient:         podClient,
+		metricsNodeClient: metricsNodeClient,
+		metricsPodClient:  metricsPodClient,
+	}
+}
+
+// GetNodes returns the given nodes or all nodes matching the label selector
+func (s *ClientSource) GetNodes(ctx context.Context, names []string, labelSelector string) ([]v1.Node, error) {
+	return util.GetNodes(ctx, s.nodeClient, names, labelSelector)
+}
+
+// GetPods returns pods on a node
+func (s *ClientSource) GetPods(ctx context.Context, nodeName string) (*v1.PodList, error) {
+	return util.GetPods(ctx, s.podClient, nodeName)
+}
+
+// GetNodeMetrics returns usage of a node
+func (s *ClientSource) GetNodeMetrics(ctx context.Context, nodeName string) (*metricsapiv1beta1.NodeMetrics, error) {
+	if s.metricsNodeClient == nil {
+		return nil, fmt.Errorf("no metrics client")
+	}
+	return s.metricsNodeClient.Get(ctx, nodeName, metav1.GetOptions{})
+}
+
+// GetPodMetrics returns usage of all pods
+func (s *ClientSource) GetPodMetrics(ctx context.Context) (*metricsapiv1beta1.PodMetricsList, error) {
+	if s.metricsPodClient == nil {
+		return nil, fmt.Errorf("no metrics client")
+	}
+	return s.metricsPodClient.List(ctx, metav1.ListOptions{})
+}
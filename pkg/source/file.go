@@ -0,0 +1,186 @@
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// listItemKinds are kinds of items in typed lists which usually don't set the kind of each item
+var listItemKinds = map[string]string{
+	"NodeList":        "Node",
+	"PodList":         "Pod",
+	"NodeMetricsList": "NodeMetrics",
+	"PodMetricsList":  "PodMetrics",
+	"List":            "",
+}
+
+// fileLoader adds objects of dump files to a StaticSource
+type fileLoader struct {
+	s *StaticSource
+
+	// seen are kind/namespace/name of added objects
+	seen map[string]bool
+}
+
+// NewFileSource is an instance of StaticSource serving objects from dump files
+// Paths are json or yaml files of nodes, pods, node metrics and pod metrics, for example
+// the output of "kubectl get nodes,pods -o json" or "kubectl get --raw /apis/metrics.k8s.io/v1beta1/nodes".
+// Directories (e.g. must-gather) are searched recursively for json and yaml files, files which
+// can't be decoded are skipped with a warning to errOut. Objects found twice (e.g. in a list
+// and in a file of their own) are added once.
+func NewFileSource(namespace string, paths []string, errOut io.Writer) (*StaticSource, error) {
+	s := NewStaticSource(namespace, nil, nil, nil, nil)
+	l := &fileLoader{s: s, seen: map[string]bool{}}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+
+		if !info.IsDir() {
+			if err := l.loadFile(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(p)) {
+			case ".json", ".yaml", ".yml":
+				if err := l.loadFile(p); err != nil {
+					fmt.Fprintf(errOut, "warning: skipping %v\n", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// loadFile adds all objects of a json or yaml file
+func (l *fileLoader) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	defer f.Close()
+
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to decode %s: %v", path, err)
+		}
+
+		// skip empty documents
+		if len(raw) == 0 || string(raw) == "null" {
+			continue
+		}
+
+		if err := l.addObject(raw, ""); err != nil {
+			return fmt.Errorf("failed to decode %s: %v", path, err)
+		}
+	}
+}
+
+// addObject adds a node, pod, metrics or a list of them
+// defaultKind is used if the object doesn't set its kind (items of typed lists).
+// Objects of other kinds are ignored.
+func (l *fileLoader) addObject(raw json.RawMessage, defaultKind string) error {
+	var tm metav1.TypeMeta
+	if err := json.Unmarshal(raw, &tm); err != nil {
+		return err
+	}
+
+	kind := tm.Kind
+	if kind == "" {
+		kind = defaultKind
+	}
+
+	if itemKind, ok := listItemKinds[kind]; ok {
+		var list struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			if err := l.addObject(item, itemKind); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch kind {
+	case "Node":
+		var node v1.Node
+		if err := json.Unmarshal(raw, &node); err != nil {
+			return err
+		}
+		if l.add(kind, node.ObjectMeta) {
+			l.s.nodes = append(l.s.nodes, node)
+		}
+	case "Pod":
+		var pod v1.Pod
+		if err := json.Unmarshal(raw, &pod); err != nil {
+			return err
+		}
+		if l.add(kind, pod.ObjectMeta) {
+			l.s.pods = append(l.s.pods, pod)
+		}
+	case "NodeMetrics":
+		var m metricsapiv1beta1.NodeMetrics
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		if l.add(kind, m.ObjectMeta) {
+			l.s.nodeMetrics = append(l.s.nodeMetrics, m)
+		}
+	case "PodMetrics":
+		var m metricsapiv1beta1.PodMetrics
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		if l.add(kind, m.ObjectMeta) {
+			l.s.podMetrics = append(l.s.podMetrics, m)
+		}
+	}
+
+	return nil
+}
+
+// add returns true if an object of the kind, namespace and name was not added before
+func (l *fileLoader) add(kind string, meta metav1.ObjectMeta) bool {
+	key := kind + "/" + meta.Namespace + "/" + meta.Name
+	if l.seen[key] {
+		return false
+	}
+	l.seen[key] = true
+	return true
+}
@@ -0,0 +1,121 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewFileSource(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		description     string
+		namespace       string
+		paths           []string
+		expectedNodes   []string
+		expectedPods    []string
+		expectedMetrics bool
+		expectedWarning string
+		expectedErr     bool
+	}{
+		{
+			"list without metrics",
+			"",
+			[]string{"testdata/cluster.json"},
+			[]string{"node1"},
+			[]string{"pod1"},
+			false,
+			"",
+			false,
+		},
+		{
+			"list with metrics",
+			"",
+			[]string{"testdata/cluster.json", "testdata/node-metrics.json", "testdata/pod-metrics.json"},
+			[]string{"node1"},
+			[]string{"pod1"},
+			true,
+			"",
+			false,
+		},
+		{
+			"must-gather directory",
+			"",
+			[]string{"testdata/must-gather"},
+			[]string{"node2"},
+			[]string{"pod2", "pod3"},
+			false,
+			"events.yaml",
+			false,
+		},
+		{
+			"must-gather directory in namespace",
+			"default",
+			[]string{"testdata/must-gather"},
+			[]string{"node2"},
+			[]string{"pod2"},
+			false,
+			"events.yaml",
+			false,
+		},
+		{
+			"objects found twice",
+			"",
+			[]string{"testdata/cluster.json", "testdata/cluster.json", "testdata/node-metrics.json", "testdata/node-metrics.json"},
+			[]string{"node1"},
+			[]string{"pod1"},
+			true,
+			"",
+			false,
+		},
+		{"missing file", "", []string{"testdata/missing.json"}, nil, nil, false, "", true},
+		{"broken file", "", []string{"testdata/broken.yaml"}, nil, nil, false, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			errOut := &bytes.Buffer{}
+			s, err := NewFileSource(test.namespace, test.paths, errOut)
+			if test.expectedErr {
+				if err == nil {
+					t.Errorf("[%s] unexpected error: should return err", test.description)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			if test.expectedWarning != "" && !strings.Contains(errOut.String(), test.expectedWarning) {
+				t.Errorf("[%s] expected warning(%s) differ (got: %s)", test.description, test.expectedWarning, errOut.String())
+			}
+			if test.expectedWarning == "" && errOut.Len() > 0 {
+				t.Errorf("[%s] unexpected warning: %s", test.description, errOut.String())
+			}
+
+			nodes, _ := s.GetNodes(ctx, []string{}, "")
+			actualNodes := nodeNames(nodes)
+			if !reflect.DeepEqual(actualNodes, test.expectedNodes) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expectedNodes, actualNodes)
+			}
+
+			actualPods := []string{}
+			for _, n := range test.expectedNodes {
+				pods, _ := s.GetPods(ctx, n)
+				actualPods = append(actualPods, podNames(pods)...)
+			}
+			if !reflect.DeepEqual(actualPods, test.expectedPods) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expectedPods, actualPods)
+			}
+
+			_, merr := s.GetNodeMetrics(ctx, test.expectedNodes[0])
+			if (merr == nil) != test.expectedMetrics {
+				t.Errorf("[%s] expected metrics(%t) differ (got err: %v)", test.description, test.expectedMetrics, merr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodFilter selects pods by labels, fields and namespaces
+type PodFilter struct {
+	labelSelector     labels.Selector
+	fieldSelector     fields.Selector
+	namespaces        []string
+	excludeNamespaces []string
+}
+
+// NewPodFilter is an instance of PodFilter
+// Empty selectors and namespaces select all pods.
+func NewPodFilter(labelSelector, fieldSelector string, namespaces, excludeNamespaces []string) (*PodFilter, error) {
+
+	ls, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pod selector: %v", err)
+	}
+
+	fs, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pod field selector: %v", err)
+	}
+
+	// other fields would never match, the api server rejects them
+	supported := podFields(v1.Pod{})
+	for _, r := range fs.Requirements() {
+		if _, ok := supported[r.Field]; !ok {
+			names := maps.Keys(supported)
+			slices.Sort(names)
+			return nil, fmt.Errorf("unsupported field %q of pod field selector, fields are %s", r.Field, strings.Join(names, ", "))
+		}
+	}
+
+	return &PodFilter{
+		labelSelector:     ls,
+		fieldSelector:     fs,
+		namespaces:        namespaces,
+		excludeNamespaces: excludeNamespaces,
+	}, nil
+}
+
+// Matches reports whether a pod is selected by the filter
+func (f *PodFilter) Matches(pod v1.Pod) bool {
+
+	if !f.MatchesNamespace(pod.Namespace) {
+		return false
+	}
+
+	return f.labelSelector.Matches(labels.Set(pod.Labels)) && f.fieldSelector.Matches(podFields(pod))
+}
+
+// MatchesNamespace reports whether pods of a namespace can be selected by the filter
+func (f *PodFilter) MatchesNamespace(namespace string) bool {
+
+	if len(f.namespaces) > 0 && !slices.Contains(f.namespaces, namespace) {
+		return false
+	}
+
+	return !slices.Contains(f.excludeNamespaces, namespace)
+}
+
+// podFields returns the fields of a pod supported by field selectors of the api server
+func podFields(pod v1.Pod) fields.Set {
+	return fields.Set{
+		"metadata.name":            pod.Name,
+		"metadata.namespace":       pod.Namespace,
+		"spec.nodeName":            pod.Spec.NodeName,
+		"spec.restartPolicy":       string(pod.Spec.RestartPolicy),
+		"spec.schedulerName":       pod.Spec.SchedulerName,
+		"spec.serviceAccountName":  pod.Spec.ServiceAccountName,
+		"spec.hostNetwork":         strconv.FormatBool(pod.Spec.HostNetwork),
+		"status.phase":             string(pod.Status.Phase),
+		"status.podIP":             pod.Status.PodIP,
+		"status.nominatedNodeName": pod.Status.NominatedNodeName,
+	}
+}
+
+// podFilterSource serves pods of a Source matching a PodFilter
+type podFilterSource struct {
+	Source
+	filter *PodFilter
+}
+
+// WithPodFilter returns a Source serving only pods of s matching the filter
+func WithPodFilter(s Source, filter *PodFilter) Source {
+	return &podFilterSource{
+		Source: s,
+		filter: filter,
+	}
+}
+
+// GetPods returns pods on a node matching the filter
+func (s *podFilterSource) GetPods(ctx context.Context, nodeName string) (*v1.PodList, error) {
+
+	pods, err := s.Source.GetPods(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := &v1.PodList{}
+	for _, pod := range pods.Items {
+		if s.filter.Matches(pod) {
+			filtered.Items = append(filtered.Items, pod)
+		}
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,74 @@
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithPodFilter(t *testing.T) {
+	ctx := context.Background()
+
+	pod := func(name, namespace, team string, phase v1.PodPhase) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"team": team}},
+			Spec:       v1.PodSpec{NodeName: "node1"},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+	pods := []v1.Pod{
+		pod("a", "team-a", "x", v1.PodRunning),
+		pod("b", "team-b", "y", v1.PodSucceeded),
+		pod("c", "kube-system", "x", v1.PodRunning),
+	}
+	s := NewStaticSource("", testNodes, pods, nil, nil)
+
+	var tests = []struct {
+		description       string
+		labelSelector     string
+		fieldSelector     string
+		namespaces        []string
+		excludeNamespaces []string
+		expected          []string
+		expectedErr       bool
+	}{
+		{"no filter", "", "", nil, nil, []string{"a", "b", "c"}, false},
+		{"label selector", "team=x", "", nil, nil, []string{"a", "c"}, false},
+		{"field selector", "", "status.phase=Running", nil, nil, []string{"a", "c"}, false},
+		{"namespaces", "", "", []string{"team-a", "team-b"}, nil, []string{"a", "b"}, false},
+		{"exclude namespaces", "team=x", "", nil, []string{"kube-system"}, []string{"a"}, false},
+		{"field selector of namespace", "", "metadata.namespace!=kube-system", nil, nil, []string{"a", "b"}, false},
+		{"invalid label selector", "team==x==y", "", nil, nil, nil, true},
+		{"invalid field selector", "", "status.phase", nil, nil, nil, true},
+		{"unsupported field", "", "spec.priorityClassName=high", nil, nil, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+
+			filter, err := NewPodFilter(test.labelSelector, test.fieldSelector, test.namespaces, test.excludeNamespaces)
+			if test.expectedErr {
+				if err == nil {
+					t.Errorf("[%s] unexpected error: should return err", test.description)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual, err := WithPodFilter(s, filter).GetPods(ctx, "node1")
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if !reflect.DeepEqual(podNames(actual), test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, podNames(actual))
+			}
+		})
+	}
+}
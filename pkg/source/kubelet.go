@@ -0,0 +1,349 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// summaryTTL is how long a summary of a node is reused by calls for the same output
+const summaryTTL = time.Second
+
+// NodeStats is usage of a node which is not available from metrics.k8s.io
+type NodeStats struct {
+	FsUsed        int64
+	FsCapacity    int64
+	EphemeralUsed int64
+	NetworkRx     int64
+	NetworkTx     int64
+	Volumes       []VolumeStats
+}
+
+// VolumeStats is usage of a volume of a pod
+// PVCName is empty if the volume is not a persistent volume claim.
+type VolumeStats struct {
+	Namespace  string
+	PodName    string
+	Name       string
+	PVCName    string
+	Used       int64
+	Capacity   int64
+	Available  int64
+	InodesUsed int64
+	Inodes     int64
+}
+
+// StatsSource provides usage of nodes beyond cpu and memory
+type StatsSource interface {
+	// GetNodeStats returns filesystem, ephemeral storage, network and volume usage of a node
+	GetNodeStats(ctx context.Context, nodeName string) (*NodeStats, error)
+}
+
+// summary is the response of the kubelet Summary API (stats/summary)
+type summary struct {
+	Node summaryNode  `json:"node"`
+	Pods []summaryPod `json:"pods"`
+}
+
+// summaryNode is the node of the kubelet Summary API
+type summaryNode struct {
+	NodeName string          `json:"nodeName"`
+	CPU      *summaryCPU     `json:"cpu"`
+	Memory   *summaryMemory  `json:"memory"`
+	Network  *summaryNetwork `json:"network"`
+	Fs       *summaryFs      `json:"fs"`
+}
+
+// summaryPod is a pod of the kubelet Summary API
+type summaryPod struct {
+	PodRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"podRef"`
+	Containers       []summaryContainer `json:"containers"`
+	Volumes          []summaryVolume    `json:"volume"`
+	EphemeralStorage *summaryFs         `json:"ephemeral-storage"`
+}
+
+// summaryContainer is a container of the kubelet Summary API
+type summaryContainer struct {
+	Name   string         `json:"name"`
+	CPU    *summaryCPU    `json:"cpu"`
+	Memory *summaryMemory `json:"memory"`
+}
+
+// summaryVolume is a volume of a pod of the kubelet Summary API
+type summaryVolume struct {
+	summaryFs
+	Name   string `json:"name"`
+	PVCRef *struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"pvcRef"`
+}
+
+// summaryCPU is cpu usage of the kubelet Summary API
+type summaryCPU struct {
+	Time           metav1.Time `json:"time"`
+	UsageNanoCores *uint64     `json:"usageNanoCores"`
+}
+
+// summaryMemory is memory usage of the kubelet Summary API
+type summaryMemory struct {
+	Time            metav1.Time `json:"time"`
+	WorkingSetBytes *uint64     `json:"workingSetBytes"`
+}
+
+// summaryNetwork is network usage of the kubelet Summary API
+type summaryNetwork struct {
+	RxBytes *uint64 `json:"rxBytes"`
+	TxBytes *uint64 `json:"txBytes"`
+}
+
+// summaryFs is filesystem usage of the kubelet Summary API
+type summaryFs struct {
+	UsedBytes      *uint64 `json:"usedBytes"`
+	CapacityBytes  *uint64 `json:"capacityBytes"`
+	AvailableBytes *uint64 `json:"availableBytes"`
+	InodesUsed     *uint64 `json:"inodesUsed"`
+	Inodes         *uint64 `json:"inodes"`
+}
+
+// cachedSummary is a summary of a node and when it was fetched
+type cachedSummary struct {
+	summary *summary
+	time    time.Time
+}
+
+// KubeletSource serves usage of nodes and pods from the kubelet Summary API through the api server proxy
+type KubeletSource struct {
+	client    kubernetes.Interface
+	namespace string
+
+	mu        sync.Mutex
+	summaries map[string]cachedSummary
+	nodes     []string
+}
+
+// NewKubeletSource is an instance of KubeletSource
+// Only pods in namespace are served, an empty namespace serves pods of all namespaces.
+func NewKubeletSource(client kubernetes.Interface, namespace string) *KubeletSource {
+	return &KubeletSource{
+		client:    client,
+		namespace: namespace,
+		summaries: map[string]cachedSummary{},
+	}
+}
+
+// SetNodes limits GetPodMetrics to pods on the given nodes
+func (s *KubeletSource) SetNodes(names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nodes = names
+}
+
+// getSummary returns the summary of a node
+// The lock is not held while fetching, so summaries of several nodes are fetched in parallel.
+func (s *KubeletSource) getSummary(ctx context.Context, nodeName string) (*summary, error) {
+
+	s.mu.Lock()
+	c, ok := s.summaries[nodeName]
+	s.mu.Unlock()
+	if ok && time.Since(c.time) <= summaryTTL {
+		return c.summary, nil
+	}
+
+	b, err := s.client.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix("stats/summary").
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary of node %q: %v", nodeName, err)
+	}
+
+	sum := &summary{}
+	if err := json.Unmarshal(b, sum); err != nil {
+		return nil, fmt.Errorf("failed to decode summary of node %q: %v", nodeName, err)
+	}
+
+	s.mu.Lock()
+	s.summaries[nodeName] = cachedSummary{summary: sum, time: time.Now()}
+	s.mu.Unlock()
+
+	return sum, nil
+}
+
+// getNodeNames returns the nodes given by SetNodes, all nodes if none were given
+func (s *KubeletSource) getNodeNames(ctx context.Context) ([]string, error) {
+
+	s.mu.Lock()
+	names := s.nodes
+	s.mu.Unlock()
+	if names != nil {
+		return names, nil
+	}
+
+	nodes, err := s.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	names = make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		names = append(names, node.ObjectMeta.Name)
+	}
+
+	return names, nil
+}
+
+// GetNodeMetrics returns usage of a node
+func (s *KubeletSource) GetNodeMetrics(ctx context.Context, nodeName string) (*metricsapiv1beta1.NodeMetrics, error) {
+
+	sum, err := s.getSummary(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &metricsapiv1beta1.NodeMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Usage:      v1.ResourceList{},
+	}
+	setUsage(m.Usage, &m.Timestamp, sum.Node.CPU, sum.Node.Memory)
+
+	return m, nil
+}
+
+// GetPodMetrics returns usage of pods on the nodes given by SetNodes, or of all pods
+// Summaries are fetched in parallel, nodes without a summary (e.g. unreachable kubelets) are skipped.
+func (s *KubeletSource) GetPodMetrics(ctx context.Context) (*metricsapiv1beta1.PodMetricsList, error) {
+
+	names, err := s.getNodeNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*summary, len(names))
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			summaries[i], errs[i] = s.getSummary(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	list := &metricsapiv1beta1.PodMetricsList{}
+
+	var lastErr error
+	for i, sum := range summaries {
+		if errs[i] != nil {
+			lastErr = errs[i]
+			continue
+		}
+
+		for _, pod := range sum.Pods {
+			if s.namespace != "" && pod.PodRef.Namespace != s.namespace {
+				continue
+			}
+
+			pm := metricsapiv1beta1.PodMetrics{
+				ObjectMeta: metav1.ObjectMeta{Name: pod.PodRef.Name, Namespace: pod.PodRef.Namespace},
+			}
+			for _, c := range pod.Containers {
+				cm := metricsapiv1beta1.ContainerMetrics{Name: c.Name, Usage: v1.ResourceList{}}
+				setUsage(cm.Usage, &pm.Timestamp, c.CPU, c.Memory)
+				pm.Containers = append(pm.Containers, cm)
+			}
+			list.Items = append(list.Items, pm)
+		}
+	}
+
+	if len(list.Items) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return list, nil
+}
+
+// GetNodeStats returns filesystem, ephemeral storage, network and volume usage of a node
+func (s *KubeletSource) GetNodeStats(ctx context.Context, nodeName string) (*NodeStats, error) {
+
+	sum, err := s.getSummary(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &NodeStats{Volumes: []VolumeStats{}}
+
+	if sum.Node.Fs != nil {
+		stats.FsUsed = toInt64(sum.Node.Fs.UsedBytes)
+		stats.FsCapacity = toInt64(sum.Node.Fs.CapacityBytes)
+	}
+	if sum.Node.Network != nil {
+		stats.NetworkRx = toInt64(sum.Node.Network.RxBytes)
+		stats.NetworkTx = toInt64(sum.Node.Network.TxBytes)
+	}
+
+	for _, pod := range sum.Pods {
+		// ephemeral storage of all pods is used on the node
+		if pod.EphemeralStorage != nil {
+			stats.EphemeralUsed += toInt64(pod.EphemeralStorage.UsedBytes)
+		}
+
+		if s.namespace != "" && pod.PodRef.Namespace != s.namespace {
+			continue
+		}
+		for _, v := range pod.Volumes {
+			vs := VolumeStats{
+				Namespace:  pod.PodRef.Namespace,
+				PodName:    pod.PodRef.Name,
+				Name:       v.Name,
+				Used:       toInt64(v.UsedBytes),
+				Capacity:   toInt64(v.CapacityBytes),
+				Available:  toInt64(v.AvailableBytes),
+				InodesUsed: toInt64(v.InodesUsed),
+				Inodes:     toInt64(v.Inodes),
+			}
+			if v.PVCRef != nil {
+				vs.PVCName = v.PVCRef.Name
+			}
+			stats.Volumes = append(stats.Volumes, vs)
+		}
+	}
+
+	return stats, nil
+}
+
+// setUsage sets cpu and memory usage of the summary and the time of the sample
+func setUsage(usage v1.ResourceList, timestamp *metav1.Time, cpu *summaryCPU, memory *summaryMemory) {
+	if cpu != nil && cpu.UsageNanoCores != nil {
+		usage[v1.ResourceCPU] = *resource.NewScaledQuantity(int64(*cpu.UsageNanoCores), resource.Nano)
+		*timestamp = cpu.Time
+	}
+	if memory != nil && memory.WorkingSetBytes != nil {
+		usage[v1.ResourceMemory] = *resource.NewQuantity(int64(*memory.WorkingSetBytes), resource.BinarySI)
+		*timestamp = memory.Time
+	}
+}
+
+// toInt64 returns the value of a summary counter, 0 if it is not available
+func toInt64(v *uint64) int64 {
+	if v == nil {
+		return 0
+	}
+	return int64(*v)
+}
@@ -0,0 +1,213 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// kubeletStub is a stub of the api server serving recorded kubelet summaries
+type kubeletStub struct {
+	mu       sync.Mutex
+	requests int
+}
+
+// ServeHTTP answers node lists and summaries of node1 and node2, node3 is unreachable
+func (k *kubeletStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/api/v1/nodes" {
+		fmt.Fprint(w, `{"kind":"NodeList","apiVersion":"v1","items":[{"metadata":{"name":"node1"}},{"metadata":{"name":"node2"}},{"metadata":{"name":"node3"}}]}`)
+		return
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/nodes/"), "/proxy/stats/summary")
+
+	k.mu.Lock()
+	k.requests++
+	k.mu.Unlock()
+
+	b, err := os.ReadFile("testdata/kubelet/" + name + ".json")
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"no endpoints available","code":503}`)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+// prepareTestKubeletSource returns a KubeletSource requesting the stub
+func prepareTestKubeletSource(t *testing.T, namespace string) (*KubeletSource, *kubeletStub) {
+	stub := &kubeletStub{}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return NewKubeletSource(client, namespace), stub
+}
+
+func TestKubeletSourceGetNodeMetrics(t *testing.T) {
+	ctx := context.Background()
+	s, stub := prepareTestKubeletSource(t, "")
+
+	var tests = []struct {
+		description string
+		nodeName    string
+		expectedCPU int64
+		expectedMem int64
+		expectedErr bool
+	}{
+		{"node1", "node1", 250, 1073741824, false},
+		{"node2", "node2", 500, 2147483648, false},
+		{"unreachable node", "node3", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			m, err := s.GetNodeMetrics(ctx, test.nodeName)
+			if test.expectedErr {
+				if err == nil {
+					t.Errorf("[%s] unexpected error: should return err", test.description)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := []int64{m.Usage.Cpu().MilliValue(), m.Usage.Memory().Value()}
+			expected := []int64{test.expectedCPU, test.expectedMem}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, expected, actual)
+			}
+		})
+	}
+
+	// summaries are reused
+	if _, err := s.GetNodeMetrics(ctx, "node1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if stub.requests != 3 {
+		t.Errorf("expected(3) differ (got: %d)", stub.requests)
+	}
+}
+
+func TestKubeletSourceGetPodMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		description      string
+		namespace        string
+		nodes            []string
+		expected         []string
+		expectedRequests int
+	}{
+		{"all namespaces", "", nil, []string{"default/pod1/container1", "awesome-ns/pod2/container2"}, 3},
+		{"default namespace", "default", nil, []string{"default/pod1/container1"}, 3},
+		{"given nodes", "", []string{"node1"}, []string{"default/pod1/container1", "awesome-ns/pod2/container2"}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s, stub := prepareTestKubeletSource(t, test.namespace)
+			if test.nodes != nil {
+				s.SetNodes(test.nodes)
+			}
+
+			m, err := s.GetPodMetrics(ctx)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := []string{}
+			for _, p := range m.Items {
+				for _, c := range p.Containers {
+					actual = append(actual, p.Namespace+"/"+p.Name+"/"+c.Name)
+				}
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+				return
+			}
+			if stub.requests != test.expectedRequests {
+				t.Errorf("[%s] expected(%d) differ (got: %d)", test.description, test.expectedRequests, stub.requests)
+			}
+
+			c := m.Items[0].Containers[0]
+			if c.Usage.Cpu().MilliValue() != 10 || c.Usage.Memory().Value() != 2048 {
+				t.Errorf("[%s] unexpected usage: %v", test.description, c.Usage)
+			}
+		})
+	}
+}
+
+func TestKubeletSourceGetNodeStats(t *testing.T) {
+	ctx := context.Background()
+	s, _ := prepareTestKubeletSource(t, "default")
+
+	stats, err := s.GetNodeStats(ctx, "node1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	expected := &NodeStats{
+		FsUsed:        40000000000,
+		FsCapacity:    100000000000,
+		EphemeralUsed: 2000,
+		NetworkRx:     1000000,
+		NetworkTx:     2000000,
+		Volumes: []VolumeStats{
+			{Namespace: "default", PodName: "pod1", Name: "data", PVCName: "data-pod1", Used: 3000000000, Capacity: 10000000000, Available: 7000000000, InodesUsed: 55360, Inodes: 655360},
+			{Namespace: "default", PodName: "pod1", Name: "kube-api-access", Used: 1000, Capacity: 2000, Available: 1000},
+		},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected(%+v) differ (got: %+v)", expected, stats)
+	}
+
+	// node without filesystem stats
+	stats, err = s.GetNodeStats(ctx, "node2")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if stats.FsCapacity != 0 || len(stats.Volumes) != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestWithMetricsNodeScope(t *testing.T) {
+	ctx := context.Background()
+	kubelet, stub := prepareTestKubeletSource(t, "")
+
+	s := WithMetrics(NewStaticSource("", testNodes, testPods, nil, nil), kubelet)
+
+	// only summaries of the selected nodes are fetched
+	if _, err := s.GetNodes(ctx, nil, "hostname=node1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if _, err := s.GetPodMetrics(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if stub.requests != 1 {
+		t.Errorf("expected(1) differ (got: %d)", stub.requests)
+	}
+}
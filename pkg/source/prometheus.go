@@ -0,0 +1,341 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+const (
+	// usage statistics over a window (--usage-stat)
+	UsageStatAvg = "avg"
+	UsageStatMin = "min"
+	UsageStatMax = "max"
+	UsageStatP50 = "p50"
+	UsageStatP90 = "p90"
+	UsageStatP95 = "p95"
+	UsageStatP99 = "p99"
+
+	// rateWindow is the range of cpu counters to calculate the cpu usage
+	rateWindow = "5m"
+
+	// nodeMetricsTTL is how long usage of all nodes is reused for GetNodeMetrics of single nodes
+	nodeMetricsTTL = time.Second
+
+	// DefaultNodeLabel is the label of the node name of cAdvisor metrics
+	DefaultNodeLabel = "node"
+
+	// instanceLabel is the fallback of the node name, e.g. of cAdvisor metrics scraped without relabeling
+	instanceLabel = "instance"
+)
+
+// UsageStats are the supported statistics of usage over a window
+var UsageStats = []string{UsageStatAvg, UsageStatMin, UsageStatMax, UsageStatP50, UsageStatP90, UsageStatP95, UsageStatP99}
+
+// PrometheusSource serves usage of nodes and pods from a Prometheus compatible HTTP API
+// Usage is calculated from cAdvisor metrics labeled with node, namespace, pod and container.
+type PrometheusSource struct {
+	url       string
+	namespace string
+	window    time.Duration
+	stat      string
+	nodeLabel string
+	client    *http.Client
+
+	mu          sync.Mutex
+	nodeMetrics map[string]*metricsapiv1beta1.NodeMetrics
+	nodeTime    time.Time
+}
+
+// NewPrometheusSource is an instance of PrometheusSource
+// Only pods in namespace are served, an empty namespace serves pods of all namespaces.
+// If window is 0, the current usage is served, otherwise stat (e.g. p95) of the usage over window.
+// Nodes are identified by nodeLabel (DefaultNodeLabel if empty), or by the instance label without port.
+func NewPrometheusSource(url, namespace string, window time.Duration, stat, nodeLabel string, client *http.Client) *PrometheusSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if nodeLabel == "" {
+		nodeLabel = DefaultNodeLabel
+	}
+	return &PrometheusSource{
+		url:       strings.TrimSuffix(url, "/"),
+		namespace: namespace,
+		window:    window,
+		stat:      stat,
+		nodeLabel: nodeLabel,
+		client:    client,
+	}
+}
+
+// GetNodeMetrics returns usage of a node
+func (s *PrometheusSource) GetNodeMetrics(ctx context.Context, nodeName string) (*metricsapiv1beta1.NodeMetrics, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// query all nodes at once, nodes are requested one by one
+	if s.nodeMetrics == nil || time.Since(s.nodeTime) > nodeMetricsTTL {
+		nodeMetrics, err := s.queryNodeMetrics(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.nodeMetrics = nodeMetrics
+		s.nodeTime = time.Now()
+	}
+
+	m, ok := s.nodeMetrics[nodeName]
+	if !ok {
+		return nil, fmt.Errorf("no metrics of node %q", nodeName)
+	}
+	return m, nil
+}
+
+// queryNodeMetrics returns usage of all nodes by name
+// An error is returned if no sample has a node name.
+func (s *PrometheusSource) queryNodeMetrics(ctx context.Context) (map[string]*metricsapiv1beta1.NodeMetrics, error) {
+
+	selector := `id="/"`
+	by := s.nodeLabel
+	if by != instanceLabel {
+		by += ", " + instanceLabel
+	}
+
+	cpu, err := s.query(ctx, s.aggregate(fmt.Sprintf(`sum by (%s) (rate(container_cpu_usage_seconds_total{%s}[%s]))`, by, selector, rateWindow)))
+	if err != nil {
+		return nil, err
+	}
+	mem, err := s.query(ctx, s.aggregate(fmt.Sprintf(`sum by (%s) (container_memory_working_set_bytes{%s})`, by, selector)))
+	if err != nil {
+		return nil, err
+	}
+
+	// series of a node, e.g. scraped by several instances, are summed up
+	cpuByNode := map[string]float64{}
+	for _, sample := range cpu {
+		if name := s.getNodeName(sample.Metric); name != "" {
+			cpuByNode[name] += sample.value
+		}
+	}
+	memByNode := map[string]float64{}
+	for _, sample := range mem {
+		if name := s.getNodeName(sample.Metric); name != "" {
+			memByNode[name] += sample.value
+		}
+	}
+	if len(cpuByNode) == 0 && len(memByNode) == 0 {
+		return nil, fmt.Errorf("no usage of nodes in prometheus, metrics need a %q or %q label", s.nodeLabel, instanceLabel)
+	}
+
+	nodeMetrics := map[string]*metricsapiv1beta1.NodeMetrics{}
+	get := func(name string) *metricsapiv1beta1.NodeMetrics {
+		m, ok := nodeMetrics[name]
+		if !ok {
+			m = &metricsapiv1beta1.NodeMetrics{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Timestamp:  metav1.Now(),
+				Window:     metav1.Duration{Duration: s.window},
+				Usage:      v1.ResourceList{},
+			}
+			nodeMetrics[name] = m
+		}
+		return m
+	}
+
+	for name, cores := range cpuByNode {
+		get(name).Usage[v1.ResourceCPU] = cpuQuantity(cores)
+	}
+	for name, bytes := range memByNode {
+		get(name).Usage[v1.ResourceMemory] = memoryQuantity(bytes)
+	}
+
+	return nodeMetrics, nil
+}
+
+// getNodeName returns the node name of a sample, the instance without port if the node label is missing
+func (s *PrometheusSource) getNodeName(metric map[string]string) string {
+	if name := metric[s.nodeLabel]; name != "" {
+		return name
+	}
+
+	instance := metric[instanceLabel]
+	if host, _, err := net.SplitHostPort(instance); err == nil {
+		return host
+	}
+	return instance
+}
+
+// GetPodMetrics returns usage of all pods
+func (s *PrometheusSource) GetPodMetrics(ctx context.Context) (*metricsapiv1beta1.PodMetricsList, error) {
+
+	selector := `container!="",container!="POD"`
+	if s.namespace != "" {
+		selector += fmt.Sprintf(`,namespace=%q`, s.namespace)
+	}
+
+	cpu, err := s.query(ctx, s.aggregate(fmt.Sprintf(`sum by (namespace, pod, container) (rate(container_cpu_usage_seconds_total{%s}[%s]))`, selector, rateWindow)))
+	if err != nil {
+		return nil, err
+	}
+	mem, err := s.query(ctx, s.aggregate(fmt.Sprintf(`sum by (namespace, pod, container) (container_memory_working_set_bytes{%s})`, selector)))
+	if err != nil {
+		return nil, err
+	}
+
+	pods := map[string]*metricsapiv1beta1.PodMetrics{}
+	containers := map[string]v1.ResourceList{}
+	order := []string{}
+
+	get := func(m map[string]string) v1.ResourceList {
+		podKey := m["namespace"] + "/" + m["pod"]
+		pod, ok := pods[podKey]
+		if !ok {
+			pod = &metricsapiv1beta1.PodMetrics{
+				ObjectMeta: metav1.ObjectMeta{Name: m["pod"], Namespace: m["namespace"]},
+				Timestamp:  metav1.Now(),
+				Window:     metav1.Duration{Duration: s.window},
+			}
+			pods[podKey] = pod
+			order = append(order, podKey)
+		}
+
+		containerKey := podKey + "/" + m["container"]
+		usage, ok := containers[containerKey]
+		if !ok {
+			usage = v1.ResourceList{}
+			containers[containerKey] = usage
+			pod.Containers = append(pod.Containers, metricsapiv1beta1.ContainerMetrics{Name: m["container"], Usage: usage})
+		}
+		return usage
+	}
+
+	for _, sample := range cpu {
+		get(sample.Metric)[v1.ResourceCPU] = cpuQuantity(sample.value)
+	}
+	for _, sample := range mem {
+		get(sample.Metric)[v1.ResourceMemory] = memoryQuantity(sample.value)
+	}
+
+	list := &metricsapiv1beta1.PodMetricsList{}
+	for _, podKey := range order {
+		list.Items = append(list.Items, *pods[podKey])
+	}
+
+	return list, nil
+}
+
+// aggregate wraps an instant query with the usage statistic over the window
+func (s *PrometheusSource) aggregate(query string) string {
+	if s.window <= 0 {
+		return query
+	}
+
+	// evaluate 60 points of the window, but not more often than every second
+	step := s.window / 60
+	if step < time.Second {
+		step = time.Second
+	}
+	subquery := fmt.Sprintf("(%s)[%ds:%ds]", query, int64(s.window.Seconds()), int64(step.Seconds()))
+
+	switch s.stat {
+	case UsageStatMin:
+		return "min_over_time(" + subquery + ")"
+	case UsageStatMax:
+		return "max_over_time(" + subquery + ")"
+	case UsageStatP50:
+		return "quantile_over_time(0.5, " + subquery + ")"
+	case UsageStatP90:
+		return "quantile_over_time(0.9, " + subquery + ")"
+	case UsageStatP95:
+		return "quantile_over_time(0.95, " + subquery + ")"
+	case UsageStatP99:
+		return "quantile_over_time(0.99, " + subquery + ")"
+	default:
+		return "avg_over_time(" + subquery + ")"
+	}
+}
+
+// prometheusResponse is the response of the Prometheus query API
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string             `json:"resultType"`
+		Result     []prometheusSample `json:"result"`
+	} `json:"data"`
+}
+
+// prometheusSample is a sample of an instant vector
+type prometheusSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+	value  float64
+}
+
+// query runs an instant query and returns the samples of the resulting vector
+func (s *PrometheusSource) query(ctx context.Context, query string) ([]prometheusSample, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/api/v1/query?"+url.Values{"query": {query}}.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prometheus: %v", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prometheus: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode prometheus response (status:%s): %v", resp.Status, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("failed to query prometheus: %s", result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("failed to query prometheus: unexpected result type %q", result.Data.ResultType)
+	}
+
+	samples := []prometheusSample{}
+	for _, sample := range result.Data.Result {
+		if len(sample.Value) != 2 {
+			continue
+		}
+		v, ok := sample.Value[1].(string)
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			continue
+		}
+		sample.value = f
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// cpuQuantity converts cores to a quantity
+func cpuQuantity(cores float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(math.Round(cores*1000)), resource.DecimalSI)
+}
+
+// memoryQuantity converts bytes to a quantity
+func memoryQuantity(bytes float64) resource.Quantity {
+	return *resource.NewQuantity(int64(math.Round(bytes)), resource.BinarySI)
+}
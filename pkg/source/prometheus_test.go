@@ -0,0 +1,267 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// prometheusStub is a stub of the Prometheus query API
+type prometheusStub struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+// ServeHTTP answers queries of node and container usage
+func (p *prometheusStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("query")
+
+	p.mu.Lock()
+	p.queries = append(p.queries, query)
+	p.mu.Unlock()
+
+	if r.URL.Path != "/api/v1/query" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var result string
+	switch {
+	case strings.Contains(query, "invalid"):
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		return
+	case strings.Contains(query, `id="/"`) && strings.Contains(query, "cpu"):
+		result = `{"metric":{"node":"node1"},"value":[1700000000,"0.25"]},{"metric":{"node":"node2"},"value":[1700000000,"NaN"]}`
+	case strings.Contains(query, `id="/"`):
+		result = `{"metric":{"node":"node1"},"value":[1700000000,"2048"]},{"metric":{"node":"node2"},"value":[1700000000,"4096"]}`
+	case strings.Contains(query, "cpu"):
+		result = `{"metric":{"namespace":"default","pod":"pod1","container":"container1"},"value":[1700000000,"0.0104"]}`
+	default:
+		result = `{"metric":{"namespace":"default","pod":"pod1","container":"container1"},"value":[1700000000,"1024"]},` +
+			`{"metric":{"namespace":"default","pod":"pod1","container":"sidecar"},"value":[1700000000,"512"]}`
+	}
+
+	fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, result)
+}
+
+func TestPrometheusSourceGetNodeMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	stub := &prometheusStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	s := NewPrometheusSource(server.URL+"/", "", 0, UsageStatAvg, "", server.Client())
+
+	m, err := s.GetNodeMetrics(ctx, "node1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if m.Usage.Cpu().MilliValue() != 250 {
+		t.Errorf("expected(250) differ (got: %d)", m.Usage.Cpu().MilliValue())
+	}
+	if m.Usage.Memory().Value() != 2048 {
+		t.Errorf("expected(2048) differ (got: %d)", m.Usage.Memory().Value())
+	}
+
+	// NaN cpu is skipped
+	m, err = s.GetNodeMetrics(ctx, "node2")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if _, ok := m.Usage["cpu"]; ok {
+		t.Errorf("expected no cpu usage (got: %v)", m.Usage.Cpu())
+	}
+
+	if _, err := s.GetNodeMetrics(ctx, "node3"); err == nil {
+		t.Errorf("unexpected error: should return err")
+	}
+
+	// usage of all nodes is reused
+	if len(stub.queries) != 2 {
+		t.Errorf("expected(2) differ (got: %d)", len(stub.queries))
+	}
+}
+
+func TestPrometheusSourceNodeLabel(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		description string
+		nodeLabel   string
+		result      string
+		expectedCPU int64
+		expectedErr bool
+	}{
+		{
+			"instance without node label",
+			"",
+			`{"metric":{"instance":"node1:10250"},"value":[1700000000,"0.25"]}`,
+			250,
+			false,
+		},
+		{
+			"custom node label",
+			"kubernetes_io_hostname",
+			`{"metric":{"kubernetes_io_hostname":"node1","instance":"10.0.0.1:10250"},"value":[1700000000,"0.5"]}`,
+			500,
+			false,
+		},
+		{
+			"no node",
+			"",
+			`{"metric":{},"value":[1700000000,"0.25"]}`,
+			0,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, test.result)
+			}))
+			defer server.Close()
+
+			s := NewPrometheusSource(server.URL, "", 0, UsageStatAvg, test.nodeLabel, server.Client())
+
+			m, err := s.GetNodeMetrics(ctx, "node1")
+			if test.expectedErr {
+				if err == nil {
+					t.Errorf("[%s] unexpected error: should return err", test.description)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+			if m.Usage.Cpu().MilliValue() != test.expectedCPU {
+				t.Errorf("[%s] expected(%d) differ (got: %d)", test.description, test.expectedCPU, m.Usage.Cpu().MilliValue())
+			}
+		})
+	}
+}
+
+func TestPrometheusSourceGetPodMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	stub := &prometheusStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	s := NewPrometheusSource(server.URL, "default", 0, UsageStatAvg, "", server.Client())
+
+	m, err := s.GetPodMetrics(ctx)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(m.Items) != 1 || len(m.Items[0].Containers) != 2 {
+		t.Errorf("unexpected pod metrics: %+v", m.Items)
+		return
+	}
+
+	c := m.Items[0].Containers[0]
+	if c.Name != "container1" || c.Usage.Cpu().MilliValue() != 10 || c.Usage.Memory().Value() != 1024 {
+		t.Errorf("unexpected container metrics: %+v", c)
+	}
+
+	for _, q := range stub.queries {
+		if !strings.Contains(q, `namespace="default"`) {
+			t.Errorf("expected query in namespace (got: %s)", q)
+		}
+	}
+}
+
+func TestPrometheusSourceError(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(&prometheusStub{})
+	defer server.Close()
+
+	var tests = []struct {
+		description string
+		url         string
+	}{
+		{"query error", server.URL},
+		{"not found", server.URL + "/prometheus"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s := NewPrometheusSource(test.url, "invalid", 0, UsageStatAvg, "", server.Client())
+			if _, err := s.GetPodMetrics(ctx); err == nil {
+				t.Errorf("[%s] unexpected error: should return err", test.description)
+			}
+		})
+	}
+}
+
+func TestPrometheusSourceAggregate(t *testing.T) {
+
+	query := `sum(container_memory_working_set_bytes)`
+
+	var tests = []struct {
+		description string
+		window      time.Duration
+		stat        string
+		expected    string
+	}{
+		{"current usage", 0, UsageStatP95, query},
+		{"avg over 1h", time.Hour, UsageStatAvg, "avg_over_time((" + query + ")[3600s:60s])"},
+		{"max over 1h", time.Hour, UsageStatMax, "max_over_time((" + query + ")[3600s:60s])"},
+		{"p95 over 1h", time.Hour, UsageStatP95, "quantile_over_time(0.95, (" + query + ")[3600s:60s])"},
+		{"min step", 30 * time.Second, UsageStatMin, "min_over_time((" + query + ")[30s:1s])"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s := NewPrometheusSource("http://localhost", "", test.window, test.stat, "", nil)
+			actual := s.aggregate(query)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(&prometheusStub{})
+	defer server.Close()
+
+	s := WithMetrics(
+		NewStaticSource("", testNodes, testPods, testNodeMetrics, testPodMetrics),
+		NewPrometheusSource(server.URL, "", 0, UsageStatAvg, "", server.Client()),
+	)
+
+	// nodes and pods of the static source
+	pods, err := s.GetPods(ctx, "node1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(pods.Items) != 2 {
+		t.Errorf("expected(2) differ (got: %d)", len(pods.Items))
+	}
+
+	// usage of prometheus
+	m, err := s.GetNodeMetrics(ctx, "node1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if m.Usage.Cpu().MilliValue() != 250 {
+		t.Errorf("expected(250) differ (got: %d)", m.Usage.Cpu().MilliValue())
+	}
+}
@@ -0,0 +1,78 @@
+// Package source provides nodes, pods and metrics of a cluster
+package source
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// Source is the data layer used to calculate resources of nodes and containers
+type Source interface {
+	// GetNodes returns the given nodes or all nodes matching the label selector
+	GetNodes(ctx context.Context, names []string, labelSelector string) ([]v1.Node, error)
+
+	// GetPods returns pods on a node
+	GetPods(ctx context.Context, nodeName string) (*v1.PodList, error)
+
+	MetricsSource
+}
+
+// MetricsSource provides usage of nodes and pods
+type MetricsSource interface {
+	// GetNodeMetrics returns usage of a node
+	GetNodeMetrics(ctx context.Context, nodeName string) (*metricsapiv1beta1.NodeMetrics, error)
+
+	// GetPodMetrics returns usage of all pods
+	GetPodMetrics(ctx context.Context) (*metricsapiv1beta1.PodMetricsList, error)
+}
+
+// NodeScope is implemented by metrics sources fetching usage node by node
+type NodeScope interface {
+	// SetNodes limits GetPodMetrics to pods on the given nodes
+	SetNodes(names []string)
+}
+
+// metricsOverride serves nodes and pods of a Source with usage of another MetricsSource
+type metricsOverride struct {
+	Source
+	metrics MetricsSource
+}
+
+// WithMetrics returns a Source serving usage from metrics instead of s
+func WithMetrics(s Source, metrics MetricsSource) Source {
+	return &metricsOverride{
+		Source:  s,
+		metrics: metrics,
+	}
+}
+
+// GetNodes returns nodes of the Source, usage of pods is limited to them if supported by the MetricsSource
+func (s *metricsOverride) GetNodes(ctx context.Context, names []string, labelSelector string) ([]v1.Node, error) {
+
+	nodes, err := s.Source.GetNodes(ctx, names, labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	if scope, ok := s.metrics.(NodeScope); ok {
+		nodeNames := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			nodeNames = append(nodeNames, node.ObjectMeta.Name)
+		}
+		scope.SetNodes(nodeNames)
+	}
+
+	return nodes, nil
+}
+
+// GetNodeMetrics returns usage of a node
+func (s *metricsOverride) GetNodeMetrics(ctx context.Context, nodeName string) (*metricsapiv1beta1.NodeMetrics, error) {
+	return s.metrics.GetNodeMetrics(ctx, nodeName)
+}
+
+// GetPodMetrics returns usage of all pods
+func (s *metricsOverride) GetPodMetrics(ctx context.Context) (*metricsapiv1beta1.PodMetricsList, error) {
+	return s.metrics.GetPodMetrics(ctx)
+}
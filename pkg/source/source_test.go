@@ -0,0 +1,109 @@
+package source
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// test node object
+var testNodes = []v1.Node{
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{"hostname": "node1"},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node2",
+			Labels: map[string]string{"hostname": "node2"},
+		},
+	},
+}
+
+// test pod object
+var testPods = []v1.Pod{
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "default",
+		},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod2",
+			Namespace: "awesome-ns",
+		},
+		Spec: v1.PodSpec{
+			NodeName: "node1",
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod3",
+			Namespace: "default",
+		},
+		Spec: v1.PodSpec{
+			NodeName: "node2",
+		},
+	},
+}
+
+var testNodeMetrics = []metricsapiv1beta1.NodeMetrics{
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node1",
+		},
+		Usage: v1.ResourceList{
+			v1.ResourceCPU:    *resource.NewMilliQuantity(100, resource.DecimalSI),
+			v1.ResourceMemory: *resource.NewQuantity(1024, resource.DecimalSI),
+		},
+	},
+}
+
+var testPodMetrics = []metricsapiv1beta1.PodMetrics{
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "default",
+		},
+		Containers: []metricsapiv1beta1.ContainerMetrics{
+			{
+				Name: "container1",
+				Usage: v1.ResourceList{
+					v1.ResourceCPU:    *resource.NewMilliQuantity(10, resource.DecimalSI),
+					v1.ResourceMemory: *resource.NewQuantity(10, resource.DecimalSI),
+				},
+			},
+		},
+	},
+	{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod2",
+			Namespace: "awesome-ns",
+		},
+	},
+}
+
+// podNames returns names of pods
+func podNames(pods *v1.PodList) []string {
+	names := []string{}
+	for _, p := range pods.Items {
+		names = append(names, p.ObjectMeta.Name)
+	}
+	return names
+}
+
+// nodeNames returns names of nodes
+func nodeNames(nodes []v1.Node) []string {
+	names := []string{}
+	for _, n := range nodes {
+		names = append(names, n.ObjectMeta.Name)
+	}
+	return names
+}
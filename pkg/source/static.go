@@ -0,0 +1,117 @@
+package source
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// StaticSource serves a fixed set of nodes, pods and metrics
+type StaticSource struct {
+	namespace   string
+	nodes       []v1.Node
+	pods        []v1.Pod
+	nodeMetrics []metricsapiv1beta1.NodeMetrics
+	podMetrics  []metricsapiv1beta1.PodMetrics
+}
+
+// NewStaticSource is an instance of StaticSource
+// Only pods in namespace are served, an empty namespace serves pods of all namespaces.
+func NewStaticSource(
+	namespace string,
+	nodes []v1.Node,
+	pods []v1.Pod,
+	nodeMetrics []metricsapiv1beta1.NodeMetrics,
+	podMetrics []metricsapiv1beta1.PodMetrics,
+) *StaticSource {
+	return &StaticSource{
+		namespace:   namespace,
+		nodes:       nodes,
+		pods:        pods,
+		nodeMetrics: nodeMetrics,
+		podMetrics:  podMetrics,
+	}
+}
+
+// GetNodes returns the given nodes or all nodes matching the label selector
+func (s *StaticSource) GetNodes(_ context.Context, names []string, labelSelector string) ([]v1.Node, error) {
+	nodes := []v1.Node{}
+
+	if len(names) > 0 {
+		for _, name := range names {
+			n, err := s.getNode(name)
+			if err != nil {
+				return nodes, fmt.Errorf("failed to get node: %v", err)
+			}
+			nodes = append(nodes, *n)
+		}
+		return nodes, nil
+	}
+
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nodes, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	for _, n := range s.nodes {
+		if selector.Matches(labels.Set(n.ObjectMeta.Labels)) {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes, nil
+}
+
+// getNode returns a node by name
+func (s *StaticSource) getNode(name string) (*v1.Node, error) {
+	for i := range s.nodes {
+		if s.nodes[i].ObjectMeta.Name == name {
+			return &s.nodes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("nodes %q not found", name)
+}
+
+// GetPods returns pods on a node
+func (s *StaticSource) GetPods(_ context.Context, nodeName string) (*v1.PodList, error) {
+	pods := &v1.PodList{}
+
+	for _, p := range s.pods {
+		if p.Spec.NodeName != nodeName {
+			continue
+		}
+		if s.namespace != "" && p.ObjectMeta.Namespace != s.namespace {
+			continue
+		}
+		pods.Items = append(pods.Items, p)
+	}
+
+	return pods, nil
+}
+
+// GetNodeMetrics returns usage of a node
+func (s *StaticSource) GetNodeMetrics(_ context.Context, nodeName string) (*metricsapiv1beta1.NodeMetrics, error) {
+	for i := range s.nodeMetrics {
+		if s.nodeMetrics[i].ObjectMeta.Name == nodeName {
+			return &s.nodeMetrics[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no metrics of node %q", nodeName)
+}
+
+// GetPodMetrics returns usage of all pods
+func (s *StaticSource) GetPodMetrics(_ context.Context) (*metricsapiv1beta1.PodMetricsList, error) {
+	metrics := &metricsapiv1beta1.PodMetricsList{}
+
+	for _, m := range s.podMetrics {
+		if s.namespace != "" && m.ObjectMeta.Namespace != s.namespace {
+			continue
+		}
+		metrics.Items = append(metrics.Items, m)
+	}
+
+	return metrics, nil
+}
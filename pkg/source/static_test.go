@@ -0,0 +1,112 @@
+package source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStaticSourceGetNodes(t *testing.T) {
+	ctx := context.Background()
+	s := NewStaticSource("", testNodes, testPods, testNodeMetrics, testPodMetrics)
+
+	var tests = []struct {
+		description string
+		names       []string
+		label       string
+		expected    []string
+		expectedErr bool
+	}{
+		{"no args and no labels", []string{}, "", []string{"node1", "node2"}, false},
+		{"no args with valid labels", []string{}, "hostname=node2", []string{"node2"}, false},
+		{"no args with invalid labels", []string{}, "foo=bar", []string{}, false},
+		{"one arg", []string{"node2"}, "", []string{"node2"}, false},
+		{"one arg but invalid node", []string{"foobar"}, "", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			nodes, err := s.GetNodes(ctx, test.names, test.label)
+			if test.expectedErr {
+				if err == nil {
+					t.Errorf("[%s] unexpected error: should return err", test.description)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := nodeNames(nodes)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestStaticSourceGetPods(t *testing.T) {
+	ctx := context.Background()
+
+	var tests = []struct {
+		description string
+		namespace   string
+		nodeName    string
+		expected    []string
+	}{
+		{"all namespaces", "", "node1", []string{"pod1", "pod2"}},
+		{"default namespace", "default", "node1", []string{"pod1"}},
+		{"other node", "", "node2", []string{"pod3"}},
+		{"unknown node", "", "node3", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s := NewStaticSource(test.namespace, testNodes, testPods, testNodeMetrics, testPodMetrics)
+			pods, err := s.GetPods(ctx, test.nodeName)
+			if err != nil {
+				t.Errorf("[%s] unexpected error: %v", test.description, err)
+				return
+			}
+
+			actual := podNames(pods)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestStaticSourceGetMetrics(t *testing.T) {
+	ctx := context.Background()
+	s := NewStaticSource("default", testNodes, testPods, testNodeMetrics, testPodMetrics)
+
+	t.Run("node metrics", func(t *testing.T) {
+		m, err := s.GetNodeMetrics(ctx, "node1")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if m.Usage.Cpu().MilliValue() != 100 {
+			t.Errorf("expected(100) differ (got: %d)", m.Usage.Cpu().MilliValue())
+		}
+	})
+
+	t.Run("missing node metrics", func(t *testing.T) {
+		if _, err := s.GetNodeMetrics(ctx, "node2"); err == nil {
+			t.Errorf("unexpected error: should return err")
+		}
+	})
+
+	t.Run("pod metrics in namespace", func(t *testing.T) {
+		m, err := s.GetPodMetrics(ctx)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if len(m.Items) != 1 {
+			t.Errorf("expected(1) differ (got: %d)", len(m.Items))
+		}
+	})
+}
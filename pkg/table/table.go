@@ -3,8 +3,6 @@ package table
 import (
 	"fmt"
 	"io"
-	"os"
-
 	"text/tabwriter"
 
 	"github.com/thirdeyenick/kubectl-free/pkg/util"
@@ -28,7 +26,7 @@ func NewOutputTable(o io.Writer) *OutputTable {
 func (t *OutputTable) Print() {
 
 	// get printer
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	w := tabwriter.NewWriter(t.Output, 0, 0, 1, ' ', 0)
 
 	// write header
 	if len(t.Header) > 0 {
@@ -30,8 +30,8 @@ func TestPrint(t *testing.T) {
 		rows        []string
 		expected    string
 	}{
-		{"1 row", []string{"1\t2"}, "a     b\n1     2\n"},
-		{"2 rows", []string{"1\t2", "3\t4"}, "a     b\n1     2\n3     4\n"},
+		{"1 row", []string{"1\t2"}, "a b\n1 2\n"},
+		{"2 rows", []string{"1\t2", "3\t4"}, "a b\n1 2\n3 4\n"},
 	}
 
 	for _, test := range tests {
@@ -6,10 +6,13 @@ import (
 	"strings"
 
 	color "github.com/gookit/color"
+	"golang.org/x/exp/slices"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/kubectl/pkg/util/qos"
 	metricsapiv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 
 	"github.com/thirdeyenick/kubectl-free/pkg/constants"
@@ -111,65 +114,211 @@ func SetNodeStatusColor(status *string, nocolor bool) {
 
 }
 
+// podStatusesFailed are statuses of pods and containers which don't recover without a change
+var podStatusesFailed = []string{
+	string(v1.PodFailed),
+	"Error",
+	"Evicted",
+	"ErrImagePull",
+	"ImagePullBackOff",
+	"InvalidImageName",
+	"CreateContainerError",
+	"CreateContainerConfigError",
+	"ContainerStatusUnknown",
+	"RunContainerError",
+	"StartError",
+}
+
+// podStatusesPending are statuses of pods and containers which are about to run
+var podStatusesPending = []string{
+	string(v1.PodPending),
+	"ContainerCreating",
+	"PodInitializing",
+	"NotReady",
+}
+
 // GetPodStatus defined pod status with color
+// status is the phase of a pod or the reason returned by GetPodStatusReason.
 func GetPodStatus(status string, nocolor, emoji bool) string {
 
-	var s string
+	// status without emoji and color
+	s := status
+	if s == "" {
+		s = "Unknown"
+	}
 
-	switch status {
-	case string(v1.PodRunning):
-		if emoji {
-			s = constants.EmojiPodRunning
-		} else {
-			s = string(v1.PodRunning)
-		}
+	e := constants.EmojiPodUnknown
+	paint := DefaultColor
+
+	// init containers are shown as "Init:<reason>" or "Init:<done>/<all>"
+	reason := strings.TrimPrefix(status, "Init:")
+
+	switch {
+	case status == string(v1.PodRunning):
+		e, paint = constants.EmojiPodRunning, Green
+	case status == string(v1.PodSucceeded) || status == "Completed":
+		e, paint = constants.EmojiPodSucceeded, Green
+	case status == "Terminating":
+		e, paint = constants.EmojiPodTerminating, Yellow
+	case reason == "CrashLoopBackOff":
+		e, paint = constants.EmojiPodCrashLoopBackOff, Red
+	case reason == "OOMKilled":
+		e, paint = constants.EmojiPodOOMKilled, Red
+	case slices.Contains(podStatusesFailed, reason) || strings.HasPrefix(reason, "ExitCode:") || strings.HasPrefix(reason, "Signal:"):
+		e, paint = constants.EmojiPodFailed, Red
+	case slices.Contains(podStatusesPending, reason) || reason != status:
+		e, paint = constants.EmojiPodPending, Yellow
+	}
 
-		if !nocolor {
-			Green(&s)
-		}
-	case string(v1.PodSucceeded):
-		if emoji {
-			s = constants.EmojiPodSucceeded
-		} else {
-			s = string(v1.PodSucceeded)
-		}
+	if emoji {
+		s = e
+	}
 
-		if !nocolor {
-			Green(&s)
-		}
-	case string(v1.PodPending):
-		if emoji {
-			s = constants.EmojiPodPending
-		} else {
-			s = string(v1.PodPending)
+	if !nocolor {
+		paint(&s)
+	}
+
+	return s
+}
+
+// GetPodStatusReason returns the status of a pod shown by kubectl get pods
+// e.g. "Running", "CrashLoopBackOff", "Init:0/1", "Completed", "Evicted" or "Terminating".
+func GetPodStatusReason(pod v1.Pod) string {
+
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+
+	initializing := false
+	for i, container := range pod.Status.InitContainerStatuses {
+		switch {
+		case container.State.Terminated != nil && container.State.Terminated.ExitCode == 0:
+			continue
+		case container.State.Terminated != nil:
+			reason = "Init:" + terminatedReason(container.State.Terminated)
+		case container.State.Waiting != nil && container.State.Waiting.Reason != "" && container.State.Waiting.Reason != "PodInitializing":
+			reason = "Init:" + container.State.Waiting.Reason
+		default:
+			reason = fmt.Sprintf("Init:%d/%d", i, len(pod.Spec.InitContainers))
 		}
+		initializing = true
+		break
+	}
 
-		if !nocolor {
-			Yellow(&s)
+	if !initializing {
+		hasRunning := false
+		for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+			container := pod.Status.ContainerStatuses[i]
+
+			switch {
+			case container.State.Waiting != nil && container.State.Waiting.Reason != "":
+				reason = container.State.Waiting.Reason
+			case container.State.Terminated != nil:
+				reason = terminatedReason(container.State.Terminated)
+			case container.Ready && container.State.Running != nil:
+				hasRunning = true
+			}
 		}
-	case string(v1.PodFailed):
-		if emoji {
-			s = constants.EmojiPodFailed
-		} else {
-			s = string(v1.PodFailed)
+
+		// the pod is still running if one of its containers is running
+		if reason == "Completed" && hasRunning {
+			reason = "NotReady"
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
+					reason = string(v1.PodRunning)
+				}
+			}
 		}
+	}
 
-		if !nocolor {
-			Red(&s)
+	if pod.ObjectMeta.DeletionTimestamp != nil {
+		if pod.Status.Reason == nodeUnreachablePodReason {
+			return "Unknown"
 		}
+		return "Terminating"
+	}
+
+	return reason
+}
+
+// nodeUnreachablePodReason is the reason of pods on unreachable nodes
+const nodeUnreachablePodReason = "NodeLost"
+
+// terminatedReason returns the reason of a terminated container
+// Containers terminated without a reason are shown by their signal or exit code.
+func terminatedReason(state *v1.ContainerStateTerminated) string {
+	switch {
+	case state.Reason != "":
+		return state.Reason
+	case state.Signal != 0:
+		return fmt.Sprintf("Signal:%d", state.Signal)
 	default:
-		if emoji {
-			s = constants.EmojiPodUnknown
-		} else {
-			s = "Unknown"
+		return fmt.Sprintf("ExitCode:%d", state.ExitCode)
+	}
+}
+
+// GetContainerState returns the current state of a container
+// e.g. "Running", "CrashLoopBackOff" or "OOMKilled", "-" if the state is not known.
+func GetContainerState(status v1.ContainerStatus) string {
+	switch {
+	case status.State.Running != nil:
+		return "Running"
+	case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+		return status.State.Waiting.Reason
+	case status.State.Waiting != nil:
+		return "Waiting"
+	case status.State.Terminated != nil:
+		return terminatedReason(status.State.Terminated)
+	default:
+		return "-"
+	}
+}
+
+// GetContainerLastReason returns why a container was terminated the last time, "-" if it never was
+func GetContainerLastReason(status v1.ContainerStatus) string {
+	if status.LastTerminationState.Terminated != nil {
+		return terminatedReason(status.LastTerminationState.Terminated)
+	}
+	if status.State.Terminated != nil {
+		return terminatedReason(status.State.Terminated)
+	}
+	return "-"
+}
+
+// IsOOMKilled reports whether a container is or was terminated for running out of memory
+func IsOOMKilled(status v1.ContainerStatus) bool {
+	for _, state := range []v1.ContainerState{status.State, status.LastTerminationState} {
+		if state.Terminated != nil && state.Terminated.Reason == oomKilledReason {
+			return true
 		}
+	}
+	return false
+}
+
+// oomKilledReason is the reason of containers terminated for running out of memory
+const oomKilledReason = "OOMKilled"
 
-		if !nocolor {
-			DefaultColor(&s)
+// GetOOMKilledCount returns count of containers which are or were terminated for running out of memory
+func GetOOMKilledCount(pods v1.PodList) int {
+	var c int
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if IsOOMKilled(status) {
+				c++
+			}
 		}
 	}
+	return c
+}
 
-	return s
+// GetPodQOSClass returns the QoS class of a pod
+// The class is calculated from requests and limits if the api server didn't set it (e.g. dump files).
+func GetPodQOSClass(pod v1.Pod) string {
+	if pod.Status.QOSClass != "" {
+		return string(pod.Status.QOSClass)
+	}
+	return string(qos.GetPodQOS(&pod))
 }
 
 // GetNodes returns node objects
@@ -206,17 +355,23 @@ func GetNodeStatus(node v1.Node, emoji bool) (string, error) {
 	}
 
 	if emoji {
-		switch status {
-		case "Ready":
-			status = constants.EmojiReady
-		case "NotReady":
-			status = constants.EmojiNotReady
-		}
+		status = GetNodeStatusEmoji(status)
 	}
 
 	return status, nil
 }
 
+// GetNodeStatusEmoji returns emoji for node status
+func GetNodeStatusEmoji(status string) string {
+	switch status {
+	case "Ready":
+		return constants.EmojiReady
+	case "NotReady":
+		return constants.EmojiNotReady
+	}
+	return status
+}
+
 // GetPods returns node objects
 func GetPods(ctx context.Context, c clientv1.PodInterface, nodeName string) (*v1.PodList, error) {
 
@@ -250,6 +405,24 @@ func GetContainerMetrics(metrics *metricsapiv1beta1.PodMetricsList, podName, con
 	return c, m
 }
 
+// GetPodContainerMetrics returns container metrics usage of a pod in a namespace
+// Pods of the same name in other namespaces are not matched, unlike GetContainerMetrics.
+func GetPodContainerMetrics(metrics *metricsapiv1beta1.PodMetricsList, namespace, podName, containerName string) (cpu *resource.Quantity, mem *resource.Quantity) {
+
+	for _, pod := range metrics.Items {
+		if pod.ObjectMeta.Namespace != namespace || pod.ObjectMeta.Name != podName {
+			continue
+		}
+		for _, container := range pod.Containers {
+			if container.Name == containerName {
+				return container.Usage.Cpu(), container.Usage.Memory()
+			}
+		}
+	}
+
+	return nil, nil
+}
+
 // GetPodResources returns sum of requested/limit resources
 func GetPodResources(pods v1.PodList) (int64, int64, int64, int64) {
 	var rc, rm, lc, lm int64
@@ -272,6 +445,47 @@ func GetPodResources(pods v1.PodList) (int64, int64, int64, int64) {
 	return rc, rm, lc, lm
 }
 
+// GetPodOverheadClass returns whether a pod is a static pod, a DaemonSet pod, another kube-system pod or any other pod
+func GetPodOverheadClass(pod v1.Pod) string {
+
+	// mirror pods of static pods are owned by the node
+	if _, ok := pod.Annotations[v1.MirrorPodAnnotationKey]; ok {
+		return constants.PodClassStatic
+	}
+	for _, owner := range pod.OwnerReferences {
+		switch owner.Kind {
+		case "Node":
+			return constants.PodClassStatic
+		case "DaemonSet":
+			return constants.PodClassDaemonSet
+		}
+	}
+
+	if pod.Namespace == metav1.NamespaceSystem {
+		return constants.PodClassSystem
+	}
+
+	return constants.PodClassOther
+}
+
+// GetPodOwner returns the workload of a pod as kind/name, e.g. Deployment/app
+// Pods of ReplicaSets with a pod-template-hash belong to a Deployment, pods without owner are their own workload.
+func GetPodOwner(pod v1.Pod) string {
+
+	owner := metav1.GetControllerOfNoCopy(&pod)
+	if owner == nil {
+		return "Pod/" + pod.Name
+	}
+
+	if hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok && owner.Kind == "ReplicaSet" {
+		if name, found := strings.CutSuffix(owner.Name, "-"+hash); found {
+			return "Deployment/" + name
+		}
+	}
+
+	return owner.Kind + "/" + owner.Name
+}
+
 // GetPodCount returns count of pods
 func GetPodCount(pods v1.PodList) int {
 	return len(pods.Items)
@@ -295,6 +509,63 @@ func GetPercentage(a, b int64) int64 {
 	return (a * 100) / b
 }
 
+// GetStats returns min, avg, max and 95th percentile (nearest rank) of values
+func GetStats(values []int64) (min, avg, max, p95 int64) {
+	if len(values) == 0 {
+		return 0, 0, 0, 0
+	}
+
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	slices.Sort(sorted)
+
+	var sum int64
+	for _, v := range sorted {
+		sum += v
+	}
+
+	// nearest rank: ceil(0.95 * n)
+	rank := (95*len(sorted) + 99) / 100
+
+	return sorted[0], sum / int64(len(sorted)), sorted[len(sorted)-1], sorted[rank-1]
+}
+
+// GetSparkline returns a sparkline of values scaled between their min and max
+// If there are more values than width, values are averaged into width buckets.
+func GetSparkline(values []int64, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+
+	// average values into buckets
+	buckets := values
+	if len(values) > width {
+		buckets = make([]int64, width)
+		for i := range buckets {
+			from := i * len(values) / width
+			to := (i + 1) * len(values) / width
+			var sum int64
+			for _, v := range values[from:to] {
+				sum += v
+			}
+			buckets[i] = sum / int64(to-from)
+		}
+	}
+
+	min, _, max, _ := GetStats(buckets)
+	bars := []rune(constants.SparklineBars)
+
+	var b strings.Builder
+	for _, v := range buckets {
+		i := 0
+		if max > min {
+			i = int((v - min) * int64(len(bars)-1) / (max - min))
+		}
+		b.WriteRune(bars[i])
+	}
+	return b.String()
+}
+
 // DefaultColor set default color
 func DefaultColor(s *string) {
 	// add dummy escape code
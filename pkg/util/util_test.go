@@ -2,6 +2,8 @@ package util
 
 import (
 	"bytes"
+	"context"
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -356,13 +358,27 @@ func TestGetPodStatus(t *testing.T) {
 		{"pod succeeded", string(v1.PodSucceeded), false, false, color.Green.Sprint("Succeeded")},
 		{"pod pending", string(v1.PodPending), false, false, color.Yellow.Sprint("Pending")},
 		{"pod failed", string(v1.PodFailed), false, false, color.Red.Sprint("Failed")},
-		{"pod other status", "other", false, false, color.FgDefault.Render("Unknown")},
+		{"pod other status", "other", false, false, color.FgDefault.Render("other")},
+		{"pod without status", "", false, false, color.FgDefault.Render("Unknown")},
+		{"pod crash loop", "CrashLoopBackOff", false, false, color.Red.Sprint("CrashLoopBackOff")},
+		{"pod oom killed", "OOMKilled", false, false, color.Red.Sprint("OOMKilled")},
+		{"pod completed", "Completed", false, false, color.Green.Sprint("Completed")},
+		{"pod evicted", "Evicted", false, false, color.Red.Sprint("Evicted")},
+		{"pod terminating", "Terminating", false, false, color.Yellow.Sprint("Terminating")},
+		{"pod exit code", "ExitCode:1", false, false, color.Red.Sprint("ExitCode:1")},
+		{"pod initializing", "Init:0/1", false, false, color.Yellow.Sprint("Init:0/1")},
+		{"pod init crash loop", "Init:CrashLoopBackOff", false, false, color.Red.Sprint("Init:CrashLoopBackOff")},
+		{"pod image pull", "ImagePullBackOff", false, false, color.Red.Sprint("ImagePullBackOff")},
+		{"pod container creating", "ContainerCreating", false, false, color.Yellow.Sprint("ContainerCreating")},
 		{"pod running but nocolor", string(v1.PodRunning), true, false, "Running"},
 		{"pod running and emoji", string(v1.PodRunning), false, true, color.Green.Sprint("✅")},
 		{"pod succeeded and emoji", string(v1.PodSucceeded), false, true, color.Green.Sprint("⭕")},
 		{"pod pending and emoji", string(v1.PodPending), false, true, color.Yellow.Sprint("🚫")},
 		{"pod failed and emoji", string(v1.PodFailed), false, true, color.Red.Sprint("❌")},
 		{"pod unknown and emoji", "other", false, true, color.FgDefault.Render("❓")},
+		{"pod crash loop and emoji", "CrashLoopBackOff", false, true, color.Red.Sprint("🔁")},
+		{"pod oom killed and emoji", "OOMKilled", false, true, color.Red.Sprint("💥")},
+		{"pod terminating and emoji", "Terminating", false, true, color.Yellow.Sprint("⏳")},
 	}
 
 	for _, test := range tests {
@@ -383,13 +399,255 @@ func TestGetPodStatus(t *testing.T) {
 	}
 }
 
+func TestGetPodStatusReason(t *testing.T) {
+
+	now := metav1.Now()
+
+	var tests = []struct {
+		description string
+		pod         v1.Pod
+		expected    string
+	}{
+		{
+			"phase",
+			v1.Pod{Status: v1.PodStatus{Phase: v1.PodPending}},
+			"Pending",
+		},
+		{
+			"evicted",
+			v1.Pod{Status: v1.PodStatus{Phase: v1.PodFailed, Reason: "Evicted"}},
+			"Evicted",
+		},
+		{
+			"crash loop",
+			v1.Pod{Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "a", Ready: true, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+					{Name: "b", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+				},
+			}},
+			"CrashLoopBackOff",
+		},
+		{
+			"oom killed",
+			v1.Pod{Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "a", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}}},
+				},
+			}},
+			"OOMKilled",
+		},
+		{
+			"exit code",
+			v1.Pod{Status: v1.PodStatus{
+				Phase: v1.PodFailed,
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "a", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 2}}},
+				},
+			}},
+			"ExitCode:2",
+		},
+		{
+			"completed",
+			v1.Pod{Status: v1.PodStatus{
+				Phase: v1.PodSucceeded,
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "a", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Completed"}}},
+				},
+			}},
+			"Completed",
+		},
+		{
+			"completed sidecar of a ready pod",
+			v1.Pod{Status: v1.PodStatus{
+				Phase:      v1.PodRunning,
+				Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "a", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Completed"}}},
+					{Name: "b", Ready: true, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+				},
+			}},
+			"Running",
+		},
+		{
+			"init containers",
+			v1.Pod{
+				Spec: v1.PodSpec{InitContainers: []v1.Container{{Name: "init1"}, {Name: "init2"}}},
+				Status: v1.PodStatus{
+					Phase: v1.PodPending,
+					InitContainerStatuses: []v1.ContainerStatus{
+						{Name: "init1", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Completed"}}},
+						{Name: "init2", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+					},
+				},
+			},
+			"Init:1/2",
+		},
+		{
+			"init container crash loop",
+			v1.Pod{
+				Spec: v1.PodSpec{InitContainers: []v1.Container{{Name: "init1"}}},
+				Status: v1.PodStatus{
+					Phase: v1.PodPending,
+					InitContainerStatuses: []v1.ContainerStatus{
+						{Name: "init1", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+					},
+				},
+			},
+			"Init:CrashLoopBackOff",
+		},
+		{
+			"terminating",
+			v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Status:     v1.PodStatus{Phase: v1.PodRunning},
+			},
+			"Terminating",
+		},
+		{
+			"node lost",
+			v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Status:     v1.PodStatus{Phase: v1.PodRunning, Reason: "NodeLost"},
+			},
+			"Unknown",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetPodStatusReason(test.pod)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetContainerState(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		status      v1.ContainerStatus
+		state       string
+		lastReason  string
+	}{
+		{
+			"no status",
+			v1.ContainerStatus{},
+			"-",
+			"-",
+		},
+		{
+			"running",
+			v1.ContainerStatus{State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+			"Running",
+			"-",
+		},
+		{
+			"crash loop after oom kill",
+			v1.ContainerStatus{
+				State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+			},
+			"CrashLoopBackOff",
+			"OOMKilled",
+		},
+		{
+			"terminated by signal",
+			v1.ContainerStatus{State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Signal: 9}}},
+			"Signal:9",
+			"Signal:9",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			if actual := GetContainerState(test.status); actual != test.state {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.state, actual)
+			}
+			if actual := GetContainerLastReason(test.status); actual != test.lastReason {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.lastReason, actual)
+			}
+		})
+	}
+}
+
+func TestGetOOMKilledCount(t *testing.T) {
+
+	oomKilled := &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}
+	pods := v1.PodList{
+		Items: []v1.Pod{
+			{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{
+				{Name: "current", State: v1.ContainerState{Terminated: oomKilled}},
+				{Name: "last", LastTerminationState: v1.ContainerState{Terminated: oomKilled}},
+			}}},
+			{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{
+				{Name: "error", LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Error"}}},
+				{Name: "running", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+			}}},
+		},
+	}
+
+	expected := 2
+	if actual := GetOOMKilledCount(pods); actual != expected {
+		t.Errorf("expected(%d) differ (got: %d)", expected, actual)
+	}
+}
+
+func TestGetPodQOSClass(t *testing.T) {
+
+	guaranteed := v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(100, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(1000, resource.DecimalSI),
+	}
+
+	var tests = []struct {
+		description string
+		pod         v1.Pod
+		expected    string
+	}{
+		{
+			"qos class of the api server",
+			v1.Pod{Status: v1.PodStatus{QOSClass: v1.PodQOSGuaranteed}},
+			"Guaranteed",
+		},
+		{
+			"guaranteed",
+			v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: guaranteed, Limits: guaranteed}}}}},
+			"Guaranteed",
+		},
+		{
+			"burstable",
+			v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: guaranteed}}}}},
+			"Burstable",
+		},
+		{
+			"best effort",
+			v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{}}}},
+			"BestEffort",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetPodQOSClass(test.pod)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
 func TestGetNodes(t *testing.T) {
 	fakeClient := fake.NewSimpleClientset(&testNodes[0], &testNodes[1])
 	fakenode := fakeClient.CoreV1().Nodes()
 
 	// no args and no labels
 	t.Run("no args and no labels", func(t *testing.T) {
-		nodes, err := GetNodes(fakenode, []string{}, "")
+		nodes, err := GetNodes(context.Background(), fakenode, []string{}, "")
 
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -405,7 +663,7 @@ func TestGetNodes(t *testing.T) {
 
 	// no args with valid labels
 	t.Run("no args with valid labels", func(t *testing.T) {
-		nodes, err := GetNodes(fakenode, []string{}, "hostname=node1")
+		nodes, err := GetNodes(context.Background(), fakenode, []string{}, "hostname=node1")
 
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -425,7 +683,7 @@ func TestGetNodes(t *testing.T) {
 
 	// no args with invalid labels
 	t.Run("no args with invalid labels", func(t *testing.T) {
-		nodes, err := GetNodes(fakenode, []string{}, "foo=bar")
+		nodes, err := GetNodes(context.Background(), fakenode, []string{}, "foo=bar")
 
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -441,7 +699,7 @@ func TestGetNodes(t *testing.T) {
 
 	// one arg
 	t.Run("one arg", func(t *testing.T) {
-		nodes, err := GetNodes(fakenode, []string{"node2"}, "")
+		nodes, err := GetNodes(context.Background(), fakenode, []string{"node2"}, "")
 
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -461,7 +719,7 @@ func TestGetNodes(t *testing.T) {
 
 	// one arg but invalid node
 	t.Run("one arg but invalid node", func(t *testing.T) {
-		_, err := GetNodes(fakenode, []string{"foobar"}, "")
+		_, err := GetNodes(context.Background(), fakenode, []string{"foobar"}, "")
 
 		if err == nil {
 			t.Errorf("unexpected error: should return err")
@@ -515,7 +773,7 @@ func TestGetPods(t *testing.T) {
 
 		// FieldSelector on fakeclient doesn't work well
 		// https://github.com/kubernetes/client-go/issues/326
-		pods, err := GetPods(fakepod, "dummy")
+		pods, err := GetPods(context.Background(), fakepod, "dummy")
 
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
@@ -591,7 +849,51 @@ func TestGetContainerMetrics(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run("[GetContainerMetrics] cpu and mem", func(t *testing.T) {
-			actualCPU, actualMEM := GetContainerMetrics(testMetrics, test.podName, test.containerName)
+			cpu, mem := GetContainerMetrics(testMetrics, test.podName, test.containerName)
+			var actualCPU, actualMEM int64
+			if cpu != nil {
+				actualCPU = cpu.MilliValue()
+			}
+			if mem != nil {
+				actualMEM = mem.Value()
+			}
+			if actualCPU != test.expectedCPU {
+				t.Errorf("[%s cpu] expected(%d) differ (got: %d)", test.description, test.expectedCPU, actualCPU)
+				return
+			}
+			if actualMEM != test.expectedMEM {
+				t.Errorf("[%s mem] expected(%d) differ (got: %d)", test.description, test.expectedMEM, actualMEM)
+				return
+			}
+		})
+	}
+}
+
+func TestGetPodContainerMetrics(t *testing.T) {
+
+	var tests = []struct {
+		description   string
+		namespace     string
+		podName       string
+		containerName string
+		expectedCPU   int64
+		expectedMEM   int64
+	}{
+		{"10 and 10", "default", "pod1", "container1", 10, 10},
+		{"other namespace", "awesome-ns", "pod1", "container1", 0, 0},
+		{"0 and 0", "default", "pod999", "container999", 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cpu, mem := GetPodContainerMetrics(testMetrics, test.namespace, test.podName, test.containerName)
+			var actualCPU, actualMEM int64
+			if cpu != nil {
+				actualCPU = cpu.MilliValue()
+			}
+			if mem != nil {
+				actualMEM = mem.Value()
+			}
 			if actualCPU != test.expectedCPU {
 				t.Errorf("[%s cpu] expected(%d) differ (got: %d)", test.description, test.expectedCPU, actualCPU)
 				return
@@ -691,6 +993,54 @@ func TestGetPercentage(t *testing.T) {
 
 }
 
+func TestGetStats(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		values      []int64
+		expected    []int64
+	}{
+		{"no values", []int64{}, []int64{0, 0, 0, 0}},
+		{"one value", []int64{5}, []int64{5, 5, 5, 5}},
+		{"unsorted values", []int64{3, 1, 2}, []int64{1, 2, 3, 3}},
+		{"20 values", []int64{20, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}, []int64{1, 10, 20, 19}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			min, avg, max, p95 := GetStats(test.values)
+			actual := []int64{min, avg, max, p95}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("[%s] expected(%v) differ (got: %v)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetSparkline(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		values      []int64
+		width       int
+		expected    string
+	}{
+		{"no values", []int64{}, 10, ""},
+		{"flat", []int64{3, 3, 3}, 10, "▁▁▁"},
+		{"rising", []int64{0, 1, 2, 3, 4, 5, 6, 7}, 10, "▁▂▃▄▅▆▇█"},
+		{"averaged into buckets", []int64{0, 0, 7, 7}, 2, "▁█"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetSparkline(test.values, test.width)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
 func TestColor(t *testing.T) {
 
 	t.Run("default color", func(t *testing.T) {
@@ -753,3 +1103,95 @@ func TestColor(t *testing.T) {
 		}
 	})
 }
+
+func TestGetPodOverheadClass(t *testing.T) {
+
+	owner := func(kind string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: "owner"}}
+	}
+
+	var tests = []struct {
+		description string
+		pod         v1.Pod
+		expected    string
+	}{
+		{
+			"mirror pod",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Annotations: map[string]string{v1.MirrorPodAnnotationKey: "hash"}}},
+			"static",
+		},
+		{
+			"owned by node",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", OwnerReferences: owner("Node")}},
+			"static",
+		},
+		{
+			"daemonset",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", OwnerReferences: owner("DaemonSet")}},
+			"daemonset",
+		},
+		{
+			"kube-system",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", OwnerReferences: owner("ReplicaSet")}},
+			"system",
+		},
+		{
+			"other",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}},
+			"other",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetPodOverheadClass(test.pod)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetPodOwner(t *testing.T) {
+
+	controller := true
+	owner := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+	}
+
+	var tests = []struct {
+		description string
+		pod         v1.Pod
+		expected    string
+	}{
+		{
+			"deployment",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-5d8f7c-x2k4q", Labels: map[string]string{"pod-template-hash": "5d8f7c"}, OwnerReferences: owner("ReplicaSet", "app-5d8f7c")}},
+			"Deployment/app",
+		},
+		{
+			"replicaset",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-x2k4q", OwnerReferences: owner("ReplicaSet", "app")}},
+			"ReplicaSet/app",
+		},
+		{
+			"statefulset",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-0", OwnerReferences: owner("StatefulSet", "db")}},
+			"StatefulSet/db",
+		},
+		{
+			"without controller",
+			v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", OwnerReferences: []metav1.OwnerReference{{Kind: "ConfigMap", Name: "cm"}}}},
+			"Pod/debug",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			actual := GetPodOwner(test.pod)
+			if actual != test.expected {
+				t.Errorf("[%s] expected(%s) differ (got: %s)", test.description, test.expected, actual)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"time"
 )
 
 func ValidateThreshold(w, c int64) error {
@@ -13,3 +14,20 @@ func ValidateThreshold(w, c int64) error {
 
 	return nil
 }
+
+func ValidateInterval(i time.Duration) error {
+	if i <= 0 {
+		return fmt.Errorf("interval must be greater than 0 (interval:%s)", i)
+	}
+
+	return nil
+}
+
+// ValidateLimitThreshold ensures that the threshold is a percentage of a limit
+func ValidateLimitThreshold(t int64) error {
+	if t <= 0 || t > 100 {
+		return fmt.Errorf("limit threshold must be between 1 and 100 (limit-threshold:%d)", t)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestValidateThreshold(t *testing.T) {
@@ -29,3 +30,50 @@ func TestValidateThreshold(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateInterval(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		interval    time.Duration
+		expected    error
+	}{
+		{"interval:5s", 5 * time.Second, nil},
+		{"interval:0s", 0, fmt.Errorf("interval must be greater than 0 (interval:0s)")},
+		{"interval:-1s", -1 * time.Second, fmt.Errorf("interval must be greater than 0 (interval:-1s)")},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.description, func(t *testing.T) {
+			actual := ValidateInterval(test.interval)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected(%v) differ (got: %v)", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateLimitThreshold(t *testing.T) {
+
+	var tests = []struct {
+		description string
+		threshold   int64
+		expected    error
+	}{
+		{"threshold:90", 90, nil},
+		{"threshold:100", 100, nil},
+		{"threshold:0", 0, fmt.Errorf("limit threshold must be between 1 and 100 (limit-threshold:0)")},
+		{"threshold:101", 101, fmt.Errorf("limit threshold must be between 1 and 100 (limit-threshold:101)")},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.description, func(t *testing.T) {
+			actual := ValidateLimitThreshold(test.threshold)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected(%v) differ (got: %v)", test.expected, actual)
+			}
+		})
+	}
+}